@@ -6,6 +6,7 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/modelcontextprotocol/go-sdk v1.2.0
 	github.com/ncruces/go-sqlite3 v0.30.5
+	golang.org/x/text v0.33.0
 )
 
 require (
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"strings"
 	"testing"
@@ -12,6 +13,7 @@ import (
 	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
 	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/server"
 	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/storage"
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/tools"
 )
 
 // setupIntegration creates a real MCP server with in-memory transport and returns a connected client session.
@@ -98,6 +100,34 @@ func callToolExpectError(t *testing.T, session *mcp.ClientSession, name string,
 	return tc.Text
 }
 
+// callToolExpectCode calls a tool, expects an error result with the given
+// code in its structured content, and returns that content.
+func callToolExpectCode(t *testing.T, session *mcp.ClientSession, name string, args map[string]any, code string) tools.ToolError {
+	t.Helper()
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      name,
+		Arguments: args,
+	})
+	if err != nil {
+		t.Fatalf("CallTool(%s): protocol error: %v", name, err)
+	}
+	if !result.IsError {
+		t.Fatalf("CallTool(%s): expected error but got success", name)
+	}
+	data, err := json.Marshal(result.StructuredContent)
+	if err != nil {
+		t.Fatalf("CallTool(%s): marshal structured content: %v", name, err)
+	}
+	var te tools.ToolError
+	if err := json.Unmarshal(data, &te); err != nil {
+		t.Fatalf("CallTool(%s): parse structured content %s: %v", name, data, err)
+	}
+	if te.Code != code || te.Message == "" {
+		t.Fatalf("CallTool(%s): expected code %q, got %s", name, code, data)
+	}
+	return te
+}
+
 func TestIntegration_ListTools(t *testing.T) {
 	session, cleanup := setupIntegration(t)
 	defer cleanup()
@@ -111,8 +141,13 @@ func TestIntegration_ListTools(t *testing.T) {
 		"list_projects", "create_project", "switch_project", "get_current_project",
 		"archive_project", "delete_project", "restore_project",
 		"create_entities", "add_observations", "create_relations",
-		"search_nodes", "open_nodes", "read_graph",
-		"delete_entities", "delete_observations", "delete_relations",
+		"search_nodes", "semantic_search", "get_context", "open_nodes", "read_graph",
+		"list_entities", "list_relations", "describe_project",
+		"delete_entities", "delete_observations", "delete_relations", "apply_batch",
+		"set_properties", "get_properties", "extract_properties",
+		"traverse", "find_paths", "graph_stats",
+		"find_duplicates", "merge_entities", "validate_graph",
+		"get_ontology", "set_ontology",
 	}
 
 	toolNames := make(map[string]bool)
@@ -174,19 +209,34 @@ func TestIntegration_FullWorkflow(t *testing.T) {
 			},
 		},
 	})
-	var entities []models.Entity
-	if err := json.Unmarshal([]byte(text), &entities); err != nil {
+	var created models.BatchResult
+	if err := json.Unmarshal([]byte(text), &created); err != nil {
 		t.Fatalf("parse create_entities: %v", err)
 	}
-	if len(entities) != 2 {
-		t.Fatalf("expected 2 entities, got %d", len(entities))
+	if len(created.Results) != 2 || created.Counts["created"] != 2 {
+		t.Fatalf("expected 2 created entities, got %s", text)
 	}
-	if entities[0].Name != "Go" {
-		t.Errorf("entity[0].Name = %q, want %q", entities[0].Name, "Go")
+	if e := created.Results[0].Entity; e == nil || e.Name != "Go" {
+		t.Errorf("entity[0] = %+v, want Go", e)
+	} else if len(e.Observations) != 1 {
+		t.Errorf("expected 1 observation on Go, got %d", len(e.Observations))
 	}
-	if len(entities[0].Observations) != 1 {
-		t.Errorf("expected 1 observation on Go, got %d", len(entities[0].Observations))
+
+	// Creating Go again is a conflict: in best_effort mode the other item still applies
+	text = callTool(t, session, "create_entities", map[string]any{
+		"entities": []any{
+			map[string]any{"name": "Go", "entity_type": "technology"},
+			map[string]any{"name": "Scratch", "entity_type": "concept"},
+		},
+		"mode": "best_effort",
+	})
+	if err := json.Unmarshal([]byte(text), &created); err != nil {
+		t.Fatalf("parse create_entities best_effort: %v", err)
+	}
+	if !created.Applied || created.Results[0].Status != "conflict" || created.Results[1].Status != "created" {
+		t.Errorf("unexpected best_effort results: %s", text)
 	}
+	callTool(t, session, "delete_entities", map[string]any{"names": []any{"Scratch"}})
 
 	// Step 4: add_observations
 	text = callTool(t, session, "add_observations", map[string]any{
@@ -194,12 +244,49 @@ func TestIntegration_FullWorkflow(t *testing.T) {
 			map[string]any{
 				"entity_name": "Go",
 				"contents":    []any{"Great for CLI tools"},
+				"source":      "https://go.dev",
+				"author":      "client-42",
+				"confidence":  0.9,
+				"tags":        []any{"docs"},
 			},
 		},
 	})
 	if !strings.Contains(text, "Great for CLI tools") {
 		t.Error("add_observations should return the new observation")
 	}
+	if !strings.Contains(text, `"author": "client-42"`) || !strings.Contains(text, `"confidence": 0.9`) {
+		t.Errorf("add_observations should return the observation metadata, got %s", text)
+	}
+
+	// Step 4b: a retried add_observations with the same idempotency_key is not applied twice
+	retry := map[string]any{
+		"observations": []any{
+			map[string]any{"entity_name": "Go", "contents": []any{"Statically typed"}},
+		},
+		"idempotency_key": "retry-1",
+	}
+	first := callTool(t, session, "add_observations", retry)
+	if again := callTool(t, session, "add_observations", retry); again != first {
+		t.Errorf("retry should return the stored result, got %q", again)
+	}
+	retry["observations"] = []any{map[string]any{"entity_name": "Go", "contents": []any{"Other"}}}
+	errText := callToolExpectError(t, session, "add_observations", retry)
+	if !strings.Contains(errText, "different request") {
+		t.Errorf("unexpected idempotency conflict error: %q", errText)
+	}
+	callTool(t, session, "delete_observations", map[string]any{
+		"deletions": []any{map[string]any{"entity_name": "Go", "observations": []any{"Statically typed"}}},
+	})
+
+	// Step 4c: a write with a stale expected_version is rejected as a conflict
+	errText = callToolExpectError(t, session, "add_observations", map[string]any{
+		"observations": []any{
+			map[string]any{"entity_name": "Go", "contents": []any{"Stale"}, "expected_version": 1},
+		},
+	})
+	if !strings.Contains(errText, `"status": "conflict"`) || !strings.Contains(errText, "expected version 1") {
+		t.Errorf("unexpected version conflict: %q", errText)
+	}
 
 	// Step 5: create_relations
 	text = callTool(t, session, "create_relations", map[string]any{
@@ -211,11 +298,11 @@ func TestIntegration_FullWorkflow(t *testing.T) {
 			},
 		},
 	})
-	var rels []models.Relation
+	var rels models.BatchResult
 	if err := json.Unmarshal([]byte(text), &rels); err != nil {
 		t.Fatalf("parse create_relations: %v", err)
 	}
-	if len(rels) != 1 || rels[0].RelationType != "powers" {
+	if len(rels.Results) != 1 || rels.Results[0].Relation == nil || rels.Results[0].Relation.RelationType != "powers" {
 		t.Error("expected 1 relation with type 'powers'")
 	}
 
@@ -238,8 +325,10 @@ func TestIntegration_FullWorkflow(t *testing.T) {
 			if len(e.Observations) != 2 {
 				t.Errorf("Go should have 2 observations, got %d", len(e.Observations))
 			}
-			if len(e.Relations) != 1 {
-				t.Errorf("Go should have 1 relation, got %d", len(e.Relations))
+			if len(e.Outgoing) != 1 || len(e.Incoming) != 0 {
+				t.Errorf("Go should have 1 outgoing relation, got %d out / %d in", len(e.Outgoing), len(e.Incoming))
+			} else if r := e.Outgoing[0]; r.FromName != "Go" || r.ToName != "Memory Cloud" || r.ToType == "" {
+				t.Errorf("relation endpoints should be named, got %+v", r)
 			}
 		}
 	}
@@ -247,6 +336,37 @@ func TestIntegration_FullWorkflow(t *testing.T) {
 		t.Error("search did not return Go entity")
 	}
 
+	// Step 6a: search_nodes filters on observation metadata
+	text = callTool(t, session, "search_nodes", map[string]any{"tags": []any{"docs"}, "min_confidence": 0.5})
+	searchResults = nil
+	if err := json.Unmarshal([]byte(text), &searchResults); err != nil {
+		t.Fatalf("parse filtered search_nodes: %v", err)
+	}
+	if len(searchResults) != 1 || searchResults[0].Name != "Go" {
+		t.Errorf("expected only Go for tag docs, got %s", text)
+	}
+	text = callTool(t, session, "search_nodes", map[string]any{"query": "Go", "author": "someone-else"})
+	searchResults = nil
+	if err := json.Unmarshal([]byte(text), &searchResults); err != nil {
+		t.Fatalf("parse filtered search_nodes: %v", err)
+	}
+	if len(searchResults) != 0 {
+		t.Errorf("expected no results for another author, got %s", text)
+	}
+
+	// Step 6b: semantic_search ranks entities by vector similarity
+	text = callTool(t, session, "semantic_search", map[string]any{
+		"query":  "compiled language",
+		"hybrid": true,
+	})
+	var scored []models.ScoredEntity
+	if err := json.Unmarshal([]byte(text), &scored); err != nil {
+		t.Fatalf("parse semantic_search: %v", err)
+	}
+	if len(scored) == 0 || scored[0].Name != "Go" {
+		t.Errorf("semantic_search should rank Go first, got %+v", scored)
+	}
+
 	// Step 7: read_graph
 	text = callTool(t, session, "read_graph", nil)
 	var graph models.KnowledgeGraph
@@ -260,6 +380,62 @@ func TestIntegration_FullWorkflow(t *testing.T) {
 		t.Errorf("graph should have 1 relation, got %d", len(graph.Relations))
 	}
 
+	// Step 7a: read_graph pages with limit/cursor
+	text = callTool(t, session, "read_graph", map[string]any{"limit": 1, "include": []any{}})
+	var page models.KnowledgeGraph
+	if err := json.Unmarshal([]byte(text), &page); err != nil {
+		t.Fatalf("parse read_graph page: %v", err)
+	}
+	if len(page.Entities) != 1 || page.NextCursor == "" || page.TotalEntities != 2 {
+		t.Errorf("first page should hold 1 of 2 entities and a cursor, got %+v", page)
+	}
+	text = callTool(t, session, "read_graph", map[string]any{"limit": 1, "cursor": page.NextCursor})
+	page = models.KnowledgeGraph{}
+	json.Unmarshal([]byte(text), &page)
+	if len(page.Entities) != 1 || page.NextCursor != "" {
+		t.Errorf("second page should hold the last entity, got %+v", page)
+	}
+
+	// Step 7b: find_paths explains how two entities are connected
+	text = callTool(t, session, "find_paths", map[string]any{
+		"from": "Memory Cloud",
+		"to":   "Go",
+	})
+	var paths []models.Path
+	if err := json.Unmarshal([]byte(text), &paths); err != nil {
+		t.Fatalf("parse find_paths: %v", err)
+	}
+	if len(paths) != 1 || paths[0].Summary != "Memory Cloud ←powers— Go" {
+		t.Errorf("unexpected find_paths result: %+v", paths)
+	}
+
+	// Step 7c: list_entities and list_relations catalog the graph cheaply
+	text = callTool(t, session, "list_entities", map[string]any{"sort": "observations"})
+	var catalog models.EntityList
+	if err := json.Unmarshal([]byte(text), &catalog); err != nil {
+		t.Fatalf("parse list_entities: %v", err)
+	}
+	if len(catalog.Entities) != 2 || catalog.Entities[0].Name != "Go" || catalog.Entities[0].ObservationCount != 2 {
+		t.Errorf("list_entities should list Go first with 2 observations, got %+v", catalog.Entities)
+	}
+	text = callTool(t, session, "list_relations", map[string]any{"to": "Memory Cloud", "format": "markdown"})
+	if !strings.Contains(text, "- Go → powers → Memory Cloud") {
+		t.Errorf("unexpected list_relations markdown:\n%s", text)
+	}
+
+	// Step 7d: describe_project summarizes counts and types in use
+	text = callTool(t, session, "describe_project", nil)
+	var desc models.ProjectDescription
+	if err := json.Unmarshal([]byte(text), &desc); err != nil {
+		t.Fatalf("parse describe_project: %v", err)
+	}
+	if desc.Project.Name != "test-project" || desc.Counts.Entities != 2 || desc.Counts.Observations != 2 || desc.Counts.Relations != 1 {
+		t.Errorf("unexpected describe_project counts: %+v", desc)
+	}
+	if len(desc.RelationTypes) != 1 || desc.RelationTypes[0].Type != "powers" || desc.DBSizeBytes == 0 {
+		t.Errorf("unexpected describe_project types or size: %+v", desc)
+	}
+
 	// Step 8: open_nodes
 	text = callTool(t, session, "open_nodes", map[string]any{
 		"names": []any{"Go", "Memory Cloud"},
@@ -272,6 +448,118 @@ func TestIntegration_FullWorkflow(t *testing.T) {
 		t.Errorf("open_nodes should return 2 entities, got %d", len(openedNodes))
 	}
 
+	// Step 8b: markdown and projected JSON formats
+	text = callTool(t, session, "open_nodes", map[string]any{
+		"names":  []any{"Go"},
+		"format": "markdown",
+	})
+	var goVersion int64
+	for _, e := range openedNodes {
+		if e.Name == "Go" {
+			goVersion = e.Version
+		}
+	}
+	if !strings.HasPrefix(text, fmt.Sprintf("## Go (technology, version %d)\n- ", goVersion)) || !strings.Contains(text, "→ powers → Memory Cloud") {
+		t.Errorf("unexpected markdown for Go:\n%s", text)
+	}
+	text = callTool(t, session, "read_graph", map[string]any{
+		"format": "compact_json",
+		"omit":   []any{"meta"},
+	})
+	if strings.Contains(text, "\n") || strings.Contains(text, `"id"`) || strings.Contains(text, "created_at") {
+		t.Errorf("compact_json without meta should drop newlines, IDs and timestamps, got %s", text)
+	}
+	if !strings.Contains(text, `"from_name":"Go"`) {
+		t.Errorf("compact_json should keep relation names, got %s", text)
+	}
+	errText = callToolExpectError(t, session, "search_nodes", map[string]any{"query": "Go", "format": "yaml"})
+	if !strings.Contains(errText, "Unknown format") {
+		t.Errorf("expected unknown format error, got %q", errText)
+	}
+
+	// Step 8c: get_context packs the focus entity and its neighbors
+	text = callTool(t, session, "get_context", map[string]any{
+		"focus":      []any{"Memory Cloud"},
+		"max_tokens": 200,
+	})
+	if !strings.HasPrefix(text, "Context: 2 entities") || !strings.Contains(text, "## Go (technology)") ||
+		!strings.Contains(text, "← powers ← Go") {
+		t.Errorf("unexpected get_context pack:\n%s", text)
+	}
+
+	// Step 8d: apply_batch is all or nothing
+	errText = callToolExpectError(t, session, "apply_batch", map[string]any{
+		"operations": []any{
+			map[string]any{"op": "create_entity", "entity": "SQLite", "entity_type": "technology"},
+			map[string]any{"op": "create_relation", "from": "Memory Cloud", "to": "Nowhere", "relation_type": "uses"},
+		},
+	})
+	var batch models.BatchResult
+	if err := json.Unmarshal([]byte(errText), &batch); err != nil {
+		t.Fatalf("parse apply_batch error: %v", err)
+	}
+	if batch.Applied || batch.Results[0].Status != "created" || batch.Results[1].Status != "not_found" {
+		t.Errorf("unexpected apply_batch error: %q", errText)
+	}
+	text = callTool(t, session, "apply_batch", map[string]any{
+		"operations": []any{
+			map[string]any{"op": "create_entity", "entity": "SQLite", "entity_type": "technology"},
+			map[string]any{"op": "create_relation", "from": "Memory Cloud", "to": "SQLite", "relation_type": "uses"},
+			map[string]any{"op": "delete_relation", "from": "Memory Cloud", "to": "SQLite", "relation_type": "uses"},
+			map[string]any{"op": "delete_entity", "entity": "SQLite"},
+		},
+	})
+	if err := json.Unmarshal([]byte(text), &batch); err != nil {
+		t.Fatalf("parse apply_batch: %v", err)
+	}
+	if len(batch.Results) != 4 || batch.Results[1].Relation == nil || batch.Results[2].Deleted != 1 {
+		t.Errorf("unexpected apply_batch results: %s", text)
+	}
+
+	// Step 8e: typed properties, set directly or extracted from "Key: value" observations
+	callTool(t, session, "add_observations", map[string]any{
+		"observations": []any{
+			map[string]any{"entity_name": "Memory Cloud", "contents": []any{"Stack: Go + SQLite"}},
+		},
+	})
+	text = callTool(t, session, "extract_properties", map[string]any{"entity_names": []any{"Memory Cloud"}})
+	var candidates []models.PropertyCandidate
+	if err := json.Unmarshal([]byte(text), &candidates); err != nil {
+		t.Fatalf("parse extract_properties: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].Key != "stack" || candidates[0].Value != "Go + SQLite" {
+		t.Fatalf("expected the stack candidate, got %s", text)
+	}
+	callTool(t, session, "extract_properties", map[string]any{"entity_names": []any{"Memory Cloud"}, "apply": true})
+	callTool(t, session, "set_properties", map[string]any{
+		"properties": []any{
+			map[string]any{"entity_name": "Memory Cloud", "key": "started_on", "value": "2025-11", "type": "date"},
+			map[string]any{"entity_name": "Memory Cloud", "key": "services", "value": 3},
+		},
+	})
+	text = callTool(t, session, "search_nodes", map[string]any{
+		"properties": []any{
+			map[string]any{"key": "stack", "op": "contains", "value": "sqlite"},
+			map[string]any{"key": "services", "op": ">=", "value": 2},
+		},
+	})
+	searchResults = nil
+	if err := json.Unmarshal([]byte(text), &searchResults); err != nil {
+		t.Fatalf("parse property search_nodes: %v", err)
+	}
+	if len(searchResults) != 1 || searchResults[0].Properties["started_on"] != "2025-11-01" {
+		t.Errorf("expected Memory Cloud with its properties, got %s", text)
+	}
+	for _, o := range searchResults[0].Observations {
+		if strings.HasPrefix(o.Content, "Stack:") {
+			t.Errorf("the extracted observation should be deleted, got %q", o.Content)
+		}
+	}
+	text = callTool(t, session, "get_properties", map[string]any{"names": []any{"Memory Cloud"}, "keys": []any{"services"}})
+	if !strings.Contains(text, `"type": "number"`) || strings.Contains(text, "stack") {
+		t.Errorf("expected only the typed services property, got %s", text)
+	}
+
 	// Step 9: delete_observations
 	text = callTool(t, session, "delete_observations", map[string]any{
 		"deletions": []any{
@@ -281,8 +569,8 @@ func TestIntegration_FullWorkflow(t *testing.T) {
 			},
 		},
 	})
-	if !strings.Contains(text, "Deleted 1") {
-		t.Errorf("expected 'Deleted 1', got %q", text)
+	if !strings.Contains(text, `"deleted": 1`) {
+		t.Errorf("expected 1 deletion, got %q", text)
 	}
 
 	// Verify Go now has 1 observation
@@ -294,12 +582,23 @@ func TestIntegration_FullWorkflow(t *testing.T) {
 		t.Errorf("Go should have 1 observation after delete, got %d", len(openedNodes[0].Observations))
 	}
 
-	// Step 10: delete_entities
+	// Step 10: delete_entities, previewed with dry_run first
+	text = callTool(t, session, "delete_entities", map[string]any{
+		"names":   []any{"Go"},
+		"dry_run": true,
+	})
+	var changes models.ChangeReport
+	if err := json.Unmarshal([]byte(text), &changes); err != nil {
+		t.Fatalf("parse delete_entities dry run: %v", err)
+	}
+	if !changes.DryRun || changes.Counts["entity_deleted"] != 1 || changes.Counts["observation_deleted"] != 1 || changes.Counts["relation_deleted"] != 1 {
+		t.Errorf("unexpected dry-run report: %s", text)
+	}
 	text = callTool(t, session, "delete_entities", map[string]any{
 		"names": []any{"Go"},
 	})
-	if !strings.Contains(text, "Deleted 1") {
-		t.Errorf("expected 'Deleted 1', got %q", text)
+	if !strings.Contains(text, `"deleted": 1`) {
+		t.Errorf("expected 1 deletion, got %q", text)
 	}
 
 	// Verify Go is gone, relation is gone, Memory Cloud still exists
@@ -331,8 +630,27 @@ func TestIntegration_FullWorkflow(t *testing.T) {
 			map[string]any{"from": "Memory Cloud", "to": "SQLite", "relation_type": "uses"},
 		},
 	})
-	if !strings.Contains(text, "Deleted 1") {
-		t.Errorf("expected 'Deleted 1', got %q", text)
+	if !strings.Contains(text, `"deleted": 1`) {
+		t.Errorf("expected 1 deletion, got %q", text)
+	}
+
+	// Step 11b: a relation that ended is hidden unless historical facts are asked for
+	callTool(t, session, "create_relations", map[string]any{
+		"relations": []any{
+			map[string]any{"from": "Memory Cloud", "to": "SQLite", "relation_type": "used", "valid_until": "2025-01"},
+		},
+	})
+	text = callTool(t, session, "open_nodes", map[string]any{"names": []string{"SQLite"}})
+	if strings.Contains(text, `"used"`) {
+		t.Errorf("an ended relation should be hidden, got %q", text)
+	}
+	text = callTool(t, session, "open_nodes", map[string]any{"names": []string{"SQLite"}, "valid_at": "2024-06-01"})
+	if !strings.Contains(text, `"used"`) || strings.Contains(text, `"historical"`) {
+		t.Errorf("the relation should be current as of 2024, got %q", text)
+	}
+	text = callTool(t, session, "open_nodes", map[string]any{"names": []string{"SQLite"}, "include_historical": true})
+	if !strings.Contains(text, `"valid_until": "2025-01-01"`) || !strings.Contains(text, `"historical": true`) {
+		t.Errorf("include_historical should return the relation marked historical, got %q", text)
 	}
 
 	// Step 12: archive_project
@@ -422,6 +740,7 @@ func TestIntegration_ErrorCases(t *testing.T) {
 	if !strings.Contains(errText, "No active project") {
 		t.Errorf("expected 'No active project', got %q", errText)
 	}
+	callToolExpectCode(t, session, "read_graph", nil, tools.CodeNoActiveProject)
 
 	// Create a project for remaining tests
 	callTool(t, session, "create_project", map[string]any{
@@ -435,6 +754,13 @@ func TestIntegration_ErrorCases(t *testing.T) {
 	if !strings.Contains(errText, "Failed to create project") {
 		t.Errorf("expected 'Failed to create project' for duplicate, got %q", errText)
 	}
+	callToolExpectCode(t, session, "create_project", map[string]any{"name": "error-test"}, tools.CodeConflict)
+
+	// Error: project names must be slugs
+	errText = callToolExpectError(t, session, "create_project", map[string]any{"name": "../Error Test"})
+	if !strings.Contains(errText, "must be lowercase letters and digits") {
+		t.Errorf("expected a slug validation error, got %q", errText)
+	}
 
 	// Error: add observations to nonexistent entity
 	errText = callToolExpectError(t, session, "add_observations", map[string]any{
@@ -464,6 +790,37 @@ func TestIntegration_ErrorCases(t *testing.T) {
 		t.Errorf("expected 'not found', got %q", errText)
 	}
 
+	// open_nodes resolves case-insensitively and suggests names when nothing matches
+	text := callTool(t, session, "open_nodes", map[string]any{"names": []any{"a"}})
+	if !strings.Contains(text, `"name": "A"`) {
+		t.Errorf("open_nodes('a') should resolve to A, got %q", text)
+	}
+	errText = callToolExpectError(t, session, "open_nodes", map[string]any{"names": []any{"Zebra"}})
+	if !strings.Contains(errText, "not found") {
+		t.Errorf("expected 'not found' for open_nodes, got %q", errText)
+	}
+	callTool(t, session, "create_entities", map[string]any{
+		"entities": []any{
+			map[string]any{"name": "Redis", "entity_type": "technology"},
+		},
+	})
+	te := callToolExpectCode(t, session, "open_nodes", map[string]any{"names": []any{"Rediss"}}, tools.CodeNotFound)
+	if missing, ok := te.Details["not_found"].([]any); !ok || len(missing) != 1 {
+		t.Errorf("expected the unresolved name in details, got %+v", te.Details)
+	} else if entry := missing[0].(map[string]any); entry["name"] != "Rediss" || !strings.Contains(fmt.Sprint(entry["suggestions"]), "Redis") {
+		t.Errorf("expected Redis to be suggested, got %+v", entry)
+	}
+
+	// A rolled-back batch is coded by its first failed item
+	te = callToolExpectCode(t, session, "create_relations", map[string]any{
+		"relations": []any{
+			map[string]any{"from": "A", "to": "NonExistent", "relation_type": "links"},
+		},
+	}, tools.CodeNotFound)
+	if index, ok := te.Details["index"].(float64); !ok || index != 0 {
+		t.Errorf("expected the failed item index in details, got %+v", te.Details)
+	}
+
 	// Error: switch to nonexistent project
 	errText = callToolExpectError(t, session, "switch_project", map[string]any{
 		"name": "nonexistent-project",
@@ -480,6 +837,7 @@ func TestIntegration_ErrorCases(t *testing.T) {
 	if !strings.Contains(errText, "already archived") {
 		t.Errorf("expected 'already archived', got %q", errText)
 	}
+	callToolExpectCode(t, session, "switch_project", map[string]any{"name": "error-test"}, tools.CodeArchived)
 
 	// Error: switch to archived project
 	errText = callToolExpectError(t, session, "switch_project", map[string]any{
@@ -0,0 +1,76 @@
+// Package graph provides in-memory algorithms over a project's knowledge graph
+// (paths, centrality, components). Graphs are built from storage ReadTopology data
+// and are read-only snapshots.
+package graph
+
+import (
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+// Edge is a directed relation between two entities, by index into Graph.Edges.
+type Edge struct {
+	ID   string
+	From string
+	To   string
+	Type string
+}
+
+// Graph is an adjacency-list snapshot of active entities and relations.
+type Graph struct {
+	Names map[string]string // entity ID -> name
+	Types map[string]string // entity ID -> entity_type
+	IDs   []string          // entity IDs in load order
+	Edges []Edge
+	out   map[string][]int // entity ID -> indices of outgoing edges
+	in    map[string][]int // entity ID -> indices of incoming edges
+}
+
+// New builds a graph from a knowledge graph snapshot. Relations whose
+// endpoints are not among the entities are ignored.
+func New(kg *models.KnowledgeGraph) *Graph {
+	g := &Graph{
+		Names: make(map[string]string, len(kg.Entities)),
+		Types: make(map[string]string, len(kg.Entities)),
+		out:   make(map[string][]int),
+		in:    make(map[string][]int),
+	}
+	for _, e := range kg.Entities {
+		g.Names[e.ID] = e.Name
+		g.Types[e.ID] = e.EntityType
+		g.IDs = append(g.IDs, e.ID)
+	}
+	for _, r := range kg.Relations {
+		if _, ok := g.Names[r.FromEntity]; !ok {
+			continue
+		}
+		if _, ok := g.Names[r.ToEntity]; !ok {
+			continue
+		}
+		idx := len(g.Edges)
+		g.Edges = append(g.Edges, Edge{ID: r.ID, From: r.FromEntity, To: r.ToEntity, Type: r.RelationType})
+		g.out[r.FromEntity] = append(g.out[r.FromEntity], idx)
+		g.in[r.ToEntity] = append(g.in[r.ToEntity], idx)
+	}
+	return g
+}
+
+// step is one move along an edge: the edge index and the node it arrives at.
+type step struct {
+	edge int
+	node string
+}
+
+// neighbors lists the moves available from id. Incoming edges are included
+// (traversed backwards) unless directed is set.
+func (g *Graph) neighbors(id string, directed bool) []step {
+	var steps []step
+	for _, ei := range g.out[id] {
+		steps = append(steps, step{edge: ei, node: g.Edges[ei].To})
+	}
+	if !directed {
+		for _, ei := range g.in[id] {
+			steps = append(steps, step{edge: ei, node: g.Edges[ei].From})
+		}
+	}
+	return steps
+}
@@ -0,0 +1,188 @@
+package graph
+
+import (
+	"strings"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+// path is a walk from a source: nodes[0] is the source and edges[i] connects
+// nodes[i] to nodes[i+1].
+type path struct {
+	nodes []string
+	edges []int
+}
+
+func (p path) sameEdges(o path) bool {
+	if len(p.edges) != len(o.edges) {
+		return false
+	}
+	for i := range p.edges {
+		if p.edges[i] != o.edges[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// hasRoot reports whether p starts with the first i+1 nodes and i edges of root.
+func (p path) hasRoot(root path, i int) bool {
+	if len(p.nodes) <= i {
+		return false
+	}
+	for j := 0; j <= i; j++ {
+		if p.nodes[j] != root.nodes[j] {
+			return false
+		}
+	}
+	for j := 0; j < i; j++ {
+		if p.edges[j] != root.edges[j] {
+			return false
+		}
+	}
+	return true
+}
+
+// shortest runs a BFS from src to dst of at most maxLen edges, skipping the
+// banned edges and nodes. It returns false if no such path exists.
+func (g *Graph) shortest(src, dst string, maxLen int, directed bool, bannedEdges map[int]bool, bannedNodes map[string]bool) (path, bool) {
+	if src == dst {
+		return path{nodes: []string{src}}, true
+	}
+	type visit struct {
+		prev string
+		edge int
+	}
+	visited := map[string]visit{src: {edge: -1}}
+	frontier := []string{src}
+	for depth := 0; depth < maxLen && len(frontier) > 0; depth++ {
+		var next []string
+		for _, id := range frontier {
+			for _, s := range g.neighbors(id, directed) {
+				if bannedEdges[s.edge] || bannedNodes[s.node] {
+					continue
+				}
+				if _, seen := visited[s.node]; seen {
+					continue
+				}
+				visited[s.node] = visit{prev: id, edge: s.edge}
+				if s.node == dst {
+					// Walk back to the source
+					var p path
+					for n := dst; n != src; n = visited[n].prev {
+						p.nodes = append(p.nodes, n)
+						p.edges = append(p.edges, visited[n].edge)
+					}
+					p.nodes = append(p.nodes, src)
+					for i, j := 0, len(p.nodes)-1; i < j; i, j = i+1, j-1 {
+						p.nodes[i], p.nodes[j] = p.nodes[j], p.nodes[i]
+					}
+					for i, j := 0, len(p.edges)-1; i < j; i, j = i+1, j-1 {
+						p.edges[i], p.edges[j] = p.edges[j], p.edges[i]
+					}
+					return p, true
+				}
+				next = append(next, s.node)
+			}
+		}
+		frontier = next
+	}
+	return path{}, false
+}
+
+// KShortestPaths returns up to k loopless paths from src to dst with at most
+// maxLen hops, shortest first (Yen's algorithm over BFS). Relation direction
+// is ignored unless directed is set; hops walked against a relation are
+// marked as reversed.
+func (g *Graph) KShortestPaths(src, dst string, k, maxLen int, directed bool) []models.Path {
+	first, ok := g.shortest(src, dst, maxLen, directed, nil, nil)
+	if !ok {
+		return []models.Path{}
+	}
+	found := []path{first}
+	var candidates []path
+
+	for len(found) < k {
+		prev := found[len(found)-1]
+		for i := 0; i < len(prev.nodes)-1; i++ {
+			spur := prev.nodes[i]
+			root := path{nodes: prev.nodes[:i+1], edges: prev.edges[:i]}
+
+			bannedEdges := make(map[int]bool)
+			for _, p := range found {
+				if p.hasRoot(prev, i) && len(p.edges) > i {
+					bannedEdges[p.edges[i]] = true
+				}
+			}
+			bannedNodes := make(map[string]bool)
+			for _, n := range root.nodes[:i] {
+				bannedNodes[n] = true
+			}
+
+			spurPath, ok := g.shortest(spur, dst, maxLen-i, directed, bannedEdges, bannedNodes)
+			if !ok {
+				continue
+			}
+			total := path{
+				nodes: append(append([]string{}, root.nodes...), spurPath.nodes[1:]...),
+				edges: append(append([]int{}, root.edges...), spurPath.edges...),
+			}
+			duplicate := false
+			for _, c := range append(candidates, found...) {
+				if c.sameEdges(total) {
+					duplicate = true
+					break
+				}
+			}
+			if !duplicate {
+				candidates = append(candidates, total)
+			}
+		}
+		if len(candidates) == 0 {
+			break
+		}
+		// Take the shortest candidate; ties keep discovery order
+		best := 0
+		for i, c := range candidates {
+			if len(c.edges) < len(candidates[best].edges) {
+				best = i
+			}
+		}
+		found = append(found, candidates[best])
+		candidates = append(candidates[:best], candidates[best+1:]...)
+	}
+
+	out := make([]models.Path, len(found))
+	for i, p := range found {
+		out[i] = g.describe(p)
+	}
+	return out
+}
+
+// describe renders a path with entity names, one "A —relation→ B" line per
+// hop and a one-line summary in walk order.
+func (g *Graph) describe(p path) models.Path {
+	mp := models.Path{Length: len(p.edges), Hops: []models.PathHop{}}
+	var summary strings.Builder
+	summary.WriteString(g.Names[p.nodes[0]])
+	for i, ei := range p.edges {
+		e := g.Edges[ei]
+		reversed := e.From != p.nodes[i]
+		hop := models.PathHop{
+			From:         g.Names[e.From],
+			RelationType: e.Type,
+			To:           g.Names[e.To],
+			Reversed:     reversed,
+		}
+		hop.Text = hop.From + " —" + e.Type + "→ " + hop.To
+		mp.Hops = append(mp.Hops, hop)
+
+		if reversed {
+			summary.WriteString(" ←" + e.Type + "— " + g.Names[p.nodes[i+1]])
+		} else {
+			summary.WriteString(" —" + e.Type + "→ " + g.Names[p.nodes[i+1]])
+		}
+	}
+	mp.Summary = summary.String()
+	return mp
+}
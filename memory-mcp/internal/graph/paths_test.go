@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+// testGraph builds a knowledge graph from "from type to" triples; entity IDs
+// are the names themselves.
+func testGraph(triples ...[3]string) *Graph {
+	kg := &models.KnowledgeGraph{}
+	seen := make(map[string]bool)
+	addEntity := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			kg.Entities = append(kg.Entities, models.Entity{ID: name, Name: name, EntityType: "thing"})
+		}
+	}
+	for i, t := range triples {
+		addEntity(t[0])
+		addEntity(t[2])
+		kg.Relations = append(kg.Relations, models.Relation{
+			ID: string(rune('a' + i)), FromEntity: t[0], RelationType: t[1], ToEntity: t[2],
+		})
+	}
+	return New(kg)
+}
+
+func TestShortestPathUndirected(t *testing.T) {
+	g := testGraph(
+		[3]string{"João", "sponsors", "Migração"},
+		[3]string{"ADR: RabbitMQ", "affects", "Migração"},
+	)
+
+	paths := g.KShortestPaths("João", "ADR: RabbitMQ", 1, 4, false)
+	if len(paths) != 1 {
+		t.Fatalf("Expected 1 path, got %d", len(paths))
+	}
+	p := paths[0]
+	if p.Length != 2 {
+		t.Errorf("Length = %d, want 2", p.Length)
+	}
+	if p.Hops[0].Text != "João —sponsors→ Migração" {
+		t.Errorf("Hop 0 = %q", p.Hops[0].Text)
+	}
+	if !p.Hops[1].Reversed || p.Hops[1].Text != "ADR: RabbitMQ —affects→ Migração" {
+		t.Errorf("Hop 1 should be the reversed affects relation, got %+v", p.Hops[1])
+	}
+	if p.Summary != "João —sponsors→ Migração ←affects— ADR: RabbitMQ" {
+		t.Errorf("Summary = %q", p.Summary)
+	}
+
+	// Respecting direction there is no path
+	if paths := g.KShortestPaths("João", "ADR: RabbitMQ", 1, 4, true); len(paths) != 0 {
+		t.Errorf("Directed search should find no path, got %+v", paths)
+	}
+}
+
+func TestKShortestPaths(t *testing.T) {
+	g := testGraph(
+		[3]string{"A", "r", "B"},
+		[3]string{"B", "r", "D"},
+		[3]string{"A", "r", "C"},
+		[3]string{"C", "r", "E"},
+		[3]string{"E", "r", "D"},
+		[3]string{"A", "s", "B"},
+	)
+
+	paths := g.KShortestPaths("A", "D", 5, 6, true)
+	if len(paths) != 3 {
+		t.Fatalf("Expected 3 paths (two via parallel A→B edges, one via C/E), got %d", len(paths))
+	}
+	if paths[0].Length != 2 || paths[1].Length != 2 || paths[2].Length != 3 {
+		t.Errorf("Paths should be ordered by length, got %d, %d, %d", paths[0].Length, paths[1].Length, paths[2].Length)
+	}
+
+	// max depth cuts off the longer route
+	if paths := g.KShortestPaths("A", "D", 5, 2, true); len(paths) != 2 {
+		t.Errorf("Expected 2 paths within 2 hops, got %d", len(paths))
+	}
+	if paths := g.KShortestPaths("A", "D", 5, 1, true); len(paths) != 0 {
+		t.Errorf("Expected no path within 1 hop, got %d", len(paths))
+	}
+}
@@ -0,0 +1,199 @@
+package graph
+
+import (
+	"math"
+	"sort"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+const (
+	pageRankDamping    = 0.85
+	pageRankIterations = 100
+	pageRankTolerance  = 1e-9
+	// maxComponentMembers caps how many names are listed per component.
+	maxComponentMembers = 20
+)
+
+// StatsOptions controls Stats.
+type StatsOptions struct {
+	// EntityTypes restricts rankings, orphans, leaf/hub counts and component
+	// member lists to these types. Measures are always computed on the full
+	// graph, so filtering never changes an entity's degree or PageRank.
+	EntityTypes []string
+	// Top is the length of each ranking (default 10).
+	Top int
+	// HubMinDegree is the total degree from which an entity counts as a hub
+	// (default 5). Entities with total degree 1 are leaves.
+	HubMinDegree int
+}
+
+// Stats computes degree and PageRank rankings, weakly connected components,
+// orphans (no relations) and leaf/hub counts. With an entity type filter,
+// relation_count counts relations touching at least one included entity.
+func (g *Graph) Stats(opts StatsOptions) *models.GraphStats {
+	if opts.Top <= 0 {
+		opts.Top = 10
+	}
+	if opts.HubMinDegree <= 0 {
+		opts.HubMinDegree = 5
+	}
+	include := func(id string) bool {
+		if len(opts.EntityTypes) == 0 {
+			return true
+		}
+		for _, t := range opts.EntityTypes {
+			if g.Types[id] == t {
+				return true
+			}
+		}
+		return false
+	}
+
+	pr := g.PageRank()
+	stats := &models.GraphStats{
+		Components: []models.Component{},
+		Orphans:    []string{},
+	}
+	for _, e := range g.Edges {
+		if include(e.From) || include(e.To) {
+			stats.RelationCount++
+		}
+	}
+
+	var ranked []models.RankedEntity
+	for _, id := range g.IDs {
+		if !include(id) {
+			continue
+		}
+		stats.EntityCount++
+		re := models.RankedEntity{
+			Name:       g.Names[id],
+			EntityType: g.Types[id],
+			InDegree:   len(g.in[id]),
+			OutDegree:  len(g.out[id]),
+			PageRank:   math.Round(pr[id]*1e6) / 1e6,
+		}
+		re.Degree = re.InDegree + re.OutDegree
+		switch {
+		case re.Degree == 0:
+			stats.Orphans = append(stats.Orphans, re.Name)
+		case re.Degree == 1:
+			stats.LeafCount++
+		}
+		if re.Degree >= opts.HubMinDegree {
+			stats.HubCount++
+		}
+		ranked = append(ranked, re)
+	}
+	sort.Strings(stats.Orphans)
+
+	stats.TopByDegree = topBy(ranked, opts.Top, func(a, b models.RankedEntity) bool {
+		if a.Degree != b.Degree {
+			return a.Degree > b.Degree
+		}
+		return a.Name < b.Name
+	})
+	stats.TopByPageRank = topBy(ranked, opts.Top, func(a, b models.RankedEntity) bool {
+		if a.PageRank != b.PageRank {
+			return a.PageRank > b.PageRank
+		}
+		return a.Name < b.Name
+	})
+
+	for _, comp := range g.Components() {
+		c := models.Component{Size: len(comp), Entities: []string{}}
+		for _, id := range comp {
+			if include(id) && len(c.Entities) < maxComponentMembers {
+				c.Entities = append(c.Entities, g.Names[id])
+			}
+		}
+		if len(c.Entities) == 0 {
+			continue
+		}
+		sort.Strings(c.Entities)
+		stats.Components = append(stats.Components, c)
+	}
+	stats.ComponentCount = len(stats.Components)
+
+	return stats
+}
+
+func topBy(items []models.RankedEntity, n int, less func(a, b models.RankedEntity) bool) []models.RankedEntity {
+	sorted := append([]models.RankedEntity{}, items...)
+	sort.Slice(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// PageRank computes PageRank over directed relations by power iteration.
+// Rank from entities without outgoing relations is spread evenly.
+func (g *Graph) PageRank() map[string]float64 {
+	n := len(g.IDs)
+	rank := make(map[string]float64, n)
+	if n == 0 {
+		return rank
+	}
+	for _, id := range g.IDs {
+		rank[id] = 1 / float64(n)
+	}
+
+	for iter := 0; iter < pageRankIterations; iter++ {
+		var dangling float64
+		for _, id := range g.IDs {
+			if len(g.out[id]) == 0 {
+				dangling += rank[id]
+			}
+		}
+		base := (1-pageRankDamping)/float64(n) + pageRankDamping*dangling/float64(n)
+		next := make(map[string]float64, n)
+		for _, id := range g.IDs {
+			next[id] = base
+		}
+		for _, id := range g.IDs {
+			if out := g.out[id]; len(out) > 0 {
+				share := pageRankDamping * rank[id] / float64(len(out))
+				for _, ei := range out {
+					next[g.Edges[ei].To] += share
+				}
+			}
+		}
+
+		var delta float64
+		for _, id := range g.IDs {
+			delta += math.Abs(next[id] - rank[id])
+		}
+		rank = next
+		if delta < pageRankTolerance {
+			break
+		}
+	}
+	return rank
+}
+
+// Components returns the weakly connected components (relation direction
+// ignored), largest first. Each component lists entity IDs.
+func (g *Graph) Components() [][]string {
+	seen := make(map[string]bool, len(g.IDs))
+	var comps [][]string
+	for _, start := range g.IDs {
+		if seen[start] {
+			continue
+		}
+		seen[start] = true
+		comp := []string{start}
+		for i := 0; i < len(comp); i++ {
+			for _, s := range g.neighbors(comp[i], false) {
+				if !seen[s.node] {
+					seen[s.node] = true
+					comp = append(comp, s.node)
+				}
+			}
+		}
+		comps = append(comps, comp)
+	}
+	sort.SliceStable(comps, func(i, j int) bool { return len(comps[i]) > len(comps[j]) })
+	return comps
+}
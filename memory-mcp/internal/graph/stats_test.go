@@ -0,0 +1,65 @@
+package graph
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	g := testGraph(
+		[3]string{"A", "uses", "Hub"},
+		[3]string{"B", "uses", "Hub"},
+		[3]string{"C", "uses", "Hub"},
+		[3]string{"X", "uses", "Y"},
+	)
+	// An isolated entity
+	g.IDs = append(g.IDs, "Lonely")
+	g.Names["Lonely"] = "Lonely"
+	g.Types["Lonely"] = "concept"
+
+	stats := g.Stats(StatsOptions{Top: 3, HubMinDegree: 3})
+
+	if stats.EntityCount != 7 || stats.RelationCount != 4 {
+		t.Errorf("counts = %d entities / %d relations, want 7 / 4", stats.EntityCount, stats.RelationCount)
+	}
+	if stats.ComponentCount != 3 {
+		t.Errorf("ComponentCount = %d, want 3", stats.ComponentCount)
+	}
+	if stats.Components[0].Size != 4 {
+		t.Errorf("Largest component should have 4 entities, got %d", stats.Components[0].Size)
+	}
+	if len(stats.Orphans) != 1 || stats.Orphans[0] != "Lonely" {
+		t.Errorf("Orphans = %v, want [Lonely]", stats.Orphans)
+	}
+	if stats.HubCount != 1 || stats.LeafCount != 5 {
+		t.Errorf("hubs = %d, leaves = %d; want 1 and 5", stats.HubCount, stats.LeafCount)
+	}
+	if stats.TopByDegree[0].Name != "Hub" || stats.TopByDegree[0].InDegree != 3 {
+		t.Errorf("Hub should rank first by degree, got %+v", stats.TopByDegree[0])
+	}
+	if stats.TopByPageRank[0].Name != "Hub" {
+		t.Errorf("Hub should rank first by PageRank, got %+v", stats.TopByPageRank[0])
+	}
+
+	// Filtering by type restricts what is reported
+	stats = g.Stats(StatsOptions{EntityTypes: []string{"concept"}})
+	if stats.EntityCount != 1 || stats.ComponentCount != 1 || len(stats.TopByDegree) != 1 {
+		t.Errorf("Filtered stats should only report Lonely, got %+v", stats)
+	}
+}
+
+func TestPageRankSumsToOne(t *testing.T) {
+	g := testGraph(
+		[3]string{"A", "r", "B"},
+		[3]string{"B", "r", "C"},
+		[3]string{"C", "r", "A"},
+		[3]string{"C", "r", "D"},
+	)
+	var sum float64
+	for _, v := range g.PageRank() {
+		sum += v
+	}
+	if math.Abs(sum-1) > 1e-6 {
+		t.Errorf("PageRank should sum to 1, got %f", sum)
+	}
+}
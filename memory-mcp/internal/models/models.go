@@ -11,36 +11,366 @@ type Project struct {
 	UpdatedAt   string `json:"updated_at"`
 }
 
-// Entity represents a node in the knowledge graph.
+// Entity represents a node in the knowledge graph. Version counts the changes
+// to the entity, its observations, its relations and its properties.
+// Properties holds the values of its properties by key.
 type Entity struct {
-	ID           string        `json:"id"`
-	Name         string        `json:"name"`
-	EntityType   string        `json:"entity_type"`
-	Observations []Observation `json:"observations,omitempty"`
-	Relations    []Relation    `json:"relations,omitempty"`
-	CreatedAt    string        `json:"created_at"`
-	UpdatedAt    string        `json:"updated_at"`
+	ID           string         `json:"id"`
+	Name         string         `json:"name"`
+	EntityType   string         `json:"entity_type"`
+	Version      int64          `json:"version"`
+	Aliases      []string       `json:"aliases,omitempty"`
+	Properties   map[string]any `json:"properties,omitempty"`
+	Observations []Observation  `json:"observations,omitempty"`
+	Outgoing     []Relation     `json:"outgoing,omitempty"`
+	Incoming     []Relation     `json:"incoming,omitempty"`
+	CreatedAt    string         `json:"created_at"`
+	UpdatedAt    string         `json:"updated_at"`
 }
 
-// Observation represents a fact attached to an entity.
+// Observation represents a fact attached to an entity, with its provenance.
 type Observation struct {
-	ID        string `json:"id"`
-	EntityID  string `json:"entity_id"`
-	Content   string `json:"content"`
+	ID       string `json:"id"`
+	EntityID string `json:"entity_id"`
+	Content  string `json:"content"`
+	ObservationMeta
+	Validity
 	CreatedAt string `json:"created_at"`
 }
 
+// ObservationMeta records where an observation came from: a Source (URL or
+// document reference), the Author or client that recorded it, a Confidence
+// from 0 to 1 (nil when unknown) and free-form Tags. All are optional.
+type ObservationMeta struct {
+	Source     string   `json:"source,omitempty"`
+	Author     string   `json:"author,omitempty"`
+	Confidence *float64 `json:"confidence,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+}
+
+// Property is a typed attribute of an entity. Type is string, number, date,
+// bool or json; Value is the decoded value, with dates as strings
+// ("2026-02-01").
+type Property struct {
+	Key       string `json:"key"`
+	Type      string `json:"type"`
+	Value     any    `json:"value"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// EntityProperties lists the properties of one entity, ordered by key.
+type EntityProperties struct {
+	Entity     string     `json:"entity"`
+	Properties []Property `json:"properties"`
+}
+
+// PropertyCandidate is an observation written as "Key: value" that could be
+// stored as a property instead.
+type PropertyCandidate struct {
+	Entity      string `json:"entity"`
+	Observation string `json:"observation"`
+	Key         string `json:"key"`
+	Type        string `json:"type"`
+	Value       any    `json:"value"`
+}
+
 // Relation represents a directed edge between two entities.
 type Relation struct {
 	ID           string `json:"id"`
 	FromEntity   string `json:"from_entity"`
 	ToEntity     string `json:"to_entity"`
+	FromName     string `json:"from_name"`
+	FromType     string `json:"from_type"`
+	ToName       string `json:"to_name"`
+	ToType       string `json:"to_type"`
 	RelationType string `json:"relation_type"`
-	CreatedAt    string `json:"created_at"`
+	Validity
+	CreatedAt string `json:"created_at"`
+}
+
+// Validity is when an observation or relation holds in the world, as opposed
+// to when it was recorded. ValidFrom is inclusive and ValidUntil exclusive;
+// either may be empty for an open end. Historical marks a fact that is not
+// valid at the time a read asked about.
+type Validity struct {
+	ValidFrom  string `json:"valid_from,omitempty"`
+	ValidUntil string `json:"valid_until,omitempty"`
+	Historical bool   `json:"historical,omitempty"`
 }
 
-// KnowledgeGraph represents the full graph for a project.
+// KnowledgeGraph represents the full graph for a project, or one page of it.
+// NextCursor is set when more entities follow.
 type KnowledgeGraph struct {
-	Entities  []Entity  `json:"entities"`
-	Relations []Relation `json:"relations"`
+	Entities      []Entity   `json:"entities"`
+	Relations     []Relation `json:"relations"`
+	TotalEntities int        `json:"total_entities"`
+	NextCursor    string     `json:"next_cursor,omitempty"`
+}
+
+// EntitySummary is a catalog entry for an entity, without its observations
+// or relations.
+type EntitySummary struct {
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	EntityType       string `json:"entity_type"`
+	Version          int64  `json:"version"`
+	ObservationCount int    `json:"observation_count"`
+	UpdatedAt        string `json:"updated_at"`
+}
+
+// EntityList is one page of the entity catalog.
+type EntityList struct {
+	Entities      []EntitySummary `json:"entities"`
+	TotalEntities int             `json:"total_entities"`
+	NextCursor    string          `json:"next_cursor,omitempty"`
+}
+
+// RelationList is one page of the relation catalog.
+type RelationList struct {
+	Relations      []Relation `json:"relations"`
+	TotalRelations int        `json:"total_relations"`
+	NextCursor     string     `json:"next_cursor,omitempty"`
+}
+
+// TypeCount is how many active entities or relations use a type.
+type TypeCount struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+// RowCounts counts the entities, observations and relations of a project.
+type RowCounts struct {
+	Entities     int `json:"entities"`
+	Observations int `json:"observations"`
+	Relations    int `json:"relations"`
+}
+
+// ProjectDescription summarizes what a project holds, for agents that need
+// to stay consistent with the types it already uses. Deleted counts
+// soft-deleted rows; DBSizeBytes includes the write-ahead log.
+type ProjectDescription struct {
+	Project         Project         `json:"project"`
+	Counts          RowCounts       `json:"counts"`
+	Deleted         RowCounts       `json:"deleted"`
+	EntityTypes     []TypeCount     `json:"entity_types"`
+	RelationTypes   []TypeCount     `json:"relation_types"`
+	RecentlyUpdated []EntitySummary `json:"recently_updated"`
+	DBSizeBytes     int64           `json:"db_size_bytes"`
+}
+
+// ScoredEntity is an entity returned by a ranked search, with its relevance score.
+type ScoredEntity struct {
+	Entity
+	Score float64 `json:"score"`
+}
+
+// DuplicateCandidate is a pair of entities that may describe the same thing.
+type DuplicateCandidate struct {
+	EntityA          string  `json:"entity_a"`
+	EntityB          string  `json:"entity_b"`
+	EntityTypeA      string  `json:"entity_type_a"`
+	EntityTypeB      string  `json:"entity_type_b"`
+	Score            float64 `json:"score"`
+	NameScore        float64 `json:"name_score"`
+	ObservationScore float64 `json:"observation_score"`
+	NeighborScore    float64 `json:"neighbor_score"`
+}
+
+// MergeResult summarizes what merge_entities changed.
+type MergeResult struct {
+	Target                       string   `json:"target"`
+	Merged                       []string `json:"merged"`
+	ObservationsMoved            int64    `json:"observations_moved"`
+	DuplicateObservationsRemoved int64    `json:"duplicate_observations_removed"`
+	RelationsMoved               int64    `json:"relations_moved"`
+	PropertiesMoved              int64    `json:"properties_moved"`
+	DuplicateRelationsRemoved    int64    `json:"duplicate_relations_removed"`
+	Aliases                      []string `json:"aliases"`
+}
+
+// BatchOpResult is the outcome of one batch operation: its status, the reason
+// it failed, and on success the created or updated entity, observations,
+// relation or property, or the number of rows deleted. Version is the current version of
+// the entity the operation targets (the source, for relations), also on a
+// version conflict.
+type BatchOpResult struct {
+	Op           string        `json:"op"`
+	Status       string        `json:"status"`
+	Reason       string        `json:"reason,omitempty"`
+	Version      int64         `json:"version,omitempty"`
+	Entity       *Entity       `json:"entity,omitempty"`
+	Observations []Observation `json:"observations,omitempty"`
+	Relation     *Relation     `json:"relation,omitempty"`
+	Property     *Property     `json:"property,omitempty"`
+	Deleted      int64         `json:"deleted,omitempty"`
+}
+
+// BatchResult holds one result per batch operation, in order, their counts
+// by status, and lenient-mode ontology warnings. Applied is false when an
+// all_or_nothing batch was rolled back because an operation failed.
+type BatchResult struct {
+	Mode     string          `json:"mode"`
+	Applied  bool            `json:"applied"`
+	Results  []BatchOpResult `json:"results"`
+	Counts   map[string]int  `json:"counts"`
+	Warnings []string        `json:"warnings,omitempty"`
+}
+
+// Change is one entity, observation, relation or property a write would
+// create, update or soft-delete. Entity names the entity the row belongs to (the source, for
+// relations); Text describes the row after the write and Before describes an
+// updated row as it was.
+type Change struct {
+	Kind   string `json:"kind"`
+	Action string `json:"action"`
+	ID     string `json:"id"`
+	Entity string `json:"entity"`
+	Text   string `json:"text"`
+	Before string `json:"before,omitempty"`
+}
+
+// ChangeReport is the outcome of a dry run: every change the write would have
+// made, cascades included, and their counts keyed by kind and action (for
+// example "observation_deleted").
+type ChangeReport struct {
+	DryRun  bool           `json:"dry_run"`
+	Changes []Change       `json:"changes"`
+	Counts  map[string]int `json:"counts"`
+}
+
+// TraversedEntity is an entity reached by a graph traversal, with its hop
+// distance from the nearest start entity.
+type TraversedEntity struct {
+	Entity
+	Depth int `json:"depth"`
+}
+
+// Subgraph is the result of a traversal: the reached entities and the
+// relations among them, with endpoints resolved to names.
+type Subgraph struct {
+	Entities  []TraversedEntity `json:"entities"`
+	Relations []Relation        `json:"relations"`
+}
+
+// PathHop is one relation along a path. Reversed is set when the path walks
+// the relation against its direction.
+type PathHop struct {
+	From         string `json:"from"`
+	RelationType string `json:"relation_type"`
+	To           string `json:"to"`
+	Reversed     bool   `json:"reversed,omitempty"`
+	Text         string `json:"text"`
+}
+
+// Path is a chain of relations connecting two entities.
+type Path struct {
+	Length  int       `json:"length"`
+	Summary string    `json:"summary"`
+	Hops    []PathHop `json:"hops"`
+}
+
+// RankedEntity is an entity with its centrality measures.
+type RankedEntity struct {
+	Name       string  `json:"name"`
+	EntityType string  `json:"entity_type"`
+	Degree     int     `json:"degree"`
+	InDegree   int     `json:"in_degree"`
+	OutDegree  int     `json:"out_degree"`
+	PageRank   float64 `json:"pagerank"`
+}
+
+// Component is a weakly connected component of the graph.
+type Component struct {
+	Size     int      `json:"size"`
+	Entities []string `json:"entities"`
+}
+
+// GraphStats summarizes the structure of a project's knowledge graph.
+type GraphStats struct {
+	EntityCount    int            `json:"entity_count"`
+	RelationCount  int            `json:"relation_count"`
+	ComponentCount int            `json:"component_count"`
+	Components     []Component    `json:"components"`
+	TopByDegree    []RankedEntity `json:"top_by_degree"`
+	TopByPageRank  []RankedEntity `json:"top_by_pagerank"`
+	Orphans        []string       `json:"orphans"`
+	LeafCount      int            `json:"leaf_count"`
+	HubCount       int            `json:"hub_count"`
+}
+
+// Finding is a single rule violation reported by validate_graph.
+type Finding struct {
+	ID            string `json:"id"`
+	Rule          string `json:"rule"`
+	Severity      string `json:"severity"`
+	Message       string `json:"message"`
+	Entity        string `json:"entity,omitempty"`
+	RelationID    string `json:"relation_id,omitempty"`
+	ObservationID string `json:"observation_id,omitempty"`
+	Fixable       bool   `json:"fixable"`
+	Fixed         bool   `json:"fixed,omitempty"`
+}
+
+// ValidationReport is the result of validate_graph.
+type ValidationReport struct {
+	Findings []Finding      `json:"findings"`
+	Counts   map[string]int `json:"counts"`
+	Fixed    int            `json:"fixed"`
+}
+
+// Ontology lists the entity and relation types a project allows. Mode is
+// "off", "lenient" (violations are reported as warnings) or "strict"
+// (violating writes are rejected).
+type Ontology struct {
+	Mode          string            `json:"mode"`
+	EntityTypes   []EntityTypeDef   `json:"entity_types"`
+	RelationTypes []RelationTypeDef `json:"relation_types"`
+}
+
+// EntityTypeDef is an allowed entity type.
+type EntityTypeDef struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// RelationTypeDef is an allowed relation type with its domain (SourceTypes)
+// and range (TargetTypes); empty lists allow any entity type. Inverse names
+// the reading from the target's side, e.g. "used_by" for "uses".
+type RelationTypeDef struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	SourceTypes []string `json:"source_types,omitempty"`
+	TargetTypes []string `json:"target_types,omitempty"`
+	Inverse     string   `json:"inverse,omitempty"`
+}
+
+// ContextPack is the result of get_context: the entities most relevant to a
+// query or focus set, with key observations and 1-hop neighbors, trimmed to a
+// token budget.
+type ContextPack struct {
+	Query           string            `json:"query,omitempty"`
+	Entities        []ContextEntity   `json:"entities"`
+	Relations       []ContextRelation `json:"relations"`
+	EstimatedTokens int               `json:"estimated_tokens"`
+	MaxTokens       int               `json:"max_tokens"`
+	// Truncated is set when items were left out to respect the budget.
+	Truncated  bool     `json:"truncated"`
+	Unresolved []string `json:"unresolved,omitempty"`
+}
+
+// ContextEntity is an entity in a context pack. Role is "focus", "hit" (a
+// query match) or "neighbor" (1 hop from a focus entity or hit).
+type ContextEntity struct {
+	Name         string   `json:"name"`
+	EntityType   string   `json:"entity_type"`
+	Role         string   `json:"role"`
+	Score        float64  `json:"score,omitempty"`
+	Observations []string `json:"observations,omitempty"`
+}
+
+// ContextRelation is a relation between two entities of a context pack.
+type ContextRelation struct {
+	From         string `json:"from"`
+	RelationType string `json:"relation_type"`
+	To           string `json:"to"`
 }
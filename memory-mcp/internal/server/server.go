@@ -60,47 +60,136 @@ func New(meta *storage.MetaStore) *mcp.Server {
 	mcp.AddTool(srv, &mcp.Tool{
 		Name:        "create_entities",
 		Description: "Create one or more entities in the knowledge graph (requires active project)",
-	}, kt.CreateEntities)
+	}, tools.Idempotent(kt, "create_entities", kt.CreateEntities))
 
 	mcp.AddTool(srv, &mcp.Tool{
 		Name:        "add_observations",
 		Description: "Add observations to existing entities (requires active project)",
-	}, kt.AddObservations)
+	}, tools.Idempotent(kt, "add_observations", kt.AddObservations))
 
 	mcp.AddTool(srv, &mcp.Tool{
 		Name:        "create_relations",
 		Description: "Create directed relations between entities (requires active project)",
-	}, kt.CreateRelations)
+	}, tools.Idempotent(kt, "create_relations", kt.CreateRelations))
 
 	mcp.AddTool(srv, &mcp.Tool{
 		Name:        "search_nodes",
-		Description: "Search entities and observations using FTS5 full-text search (requires active project)",
+		Description: "Search entities and observations using FTS5 full-text search, optionally filtered on observation metadata and entity properties (requires active project)",
 	}, kt.SearchNodes)
 
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "semantic_search",
+		Description: "Rank entities by semantic similarity to a natural-language query, optionally fused with keyword search (requires active project)",
+	}, kt.SemanticSearch)
+
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "get_context",
+		Description: "Get a token-budgeted context pack for a query and/or focus entities: ranked matches, their key observations and 1-hop neighbors. Call this first when starting a conversation (requires active project)",
+	}, kt.GetContext)
+
 	mcp.AddTool(srv, &mcp.Tool{
 		Name:        "open_nodes",
-		Description: "Retrieve specific entities by exact name match (requires active project)",
+		Description: "Retrieve specific entities by name; unmatched names get \"did you mean\" suggestions (requires active project)",
 	}, kt.OpenNodes)
 
 	mcp.AddTool(srv, &mcp.Tool{
 		Name:        "read_graph",
-		Description: "Read the entire knowledge graph of the current project (requires active project)",
+		Description: "Read the knowledge graph of the current project one page at a time, ordered by name; pass next_cursor to continue. Filter by entity_types and choose what to include (requires active project)",
 	}, kt.ReadGraph)
 
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "list_entities",
+		Description: "List entity names, types, observation counts and update times without loading observations. Filter by entity_types and name prefix, sort by name, updated or observations, paginate with next_cursor (requires active project)",
+	}, kt.ListEntities)
+
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "list_relations",
+		Description: "List relations with endpoint names, filtered by relation type, source/target entity or source/target entity type, paginated with next_cursor (requires active project)",
+	}, kt.ListRelations)
+
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "describe_project",
+		Description: "Describe the current project before writing to it: entity, observation and relation counts, the entity and relation types in use with their counts, recently updated entities, database size and soft-deleted row counts (requires active project)",
+	}, kt.DescribeProject)
+
 	mcp.AddTool(srv, &mcp.Tool{
 		Name:        "delete_entities",
 		Description: "Soft-delete entities and cascade to their observations and relations (requires active project)",
-	}, kt.DeleteEntities)
+	}, tools.Idempotent(kt, "delete_entities", kt.DeleteEntities))
 
 	mcp.AddTool(srv, &mcp.Tool{
 		Name:        "delete_observations",
 		Description: "Soft-delete specific observations from entities (requires active project)",
-	}, kt.DeleteObservations)
+	}, tools.Idempotent(kt, "delete_observations", kt.DeleteObservations))
 
 	mcp.AddTool(srv, &mcp.Tool{
 		Name:        "delete_relations",
 		Description: "Soft-delete specific relations (requires active project)",
-	}, kt.DeleteRelations)
+	}, tools.Idempotent(kt, "delete_relations", kt.DeleteRelations))
+
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "apply_batch",
+		Description: "Apply an ordered list of create/update/delete operations on entities, observations, relations and properties in one transaction; later operations can refer to entities created earlier. Returns a status per operation; in all_or_nothing mode (default) nothing is applied if any fails, in best_effort mode the rest is (requires active project)",
+	}, tools.Idempotent(kt, "apply_batch", kt.ApplyBatch))
+
+	// Property tools
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "set_properties",
+		Description: "Set typed key-value properties on entities (string, number, date, bool or json); a null value removes the property (requires active project)",
+	}, tools.Idempotent(kt, "set_properties", kt.SetProperties))
+
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "get_properties",
+		Description: "Get the typed properties of entities, optionally only some keys (requires active project)",
+	}, kt.GetProperties)
+
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "extract_properties",
+		Description: "List observations written as \"Key: value\" (e.g. \"Stack: Java\") that could become properties; with apply, set those properties and delete the observations (requires active project)",
+	}, tools.Idempotent(kt, "extract_properties", kt.ExtractProperties))
+
+	// Graph exploration tools
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "traverse",
+		Description: "Walk the graph N hops from one or more entities (outgoing, incoming or both), filtered by relation and entity types; returns the subgraph with relation endpoints as names (requires active project)",
+	}, kt.Traverse)
+
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "find_paths",
+		Description: "Find the shortest path (or k shortest paths) connecting two entities, each hop shown as \"A —relation→ B\" (requires active project)",
+	}, kt.FindPaths)
+
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "graph_stats",
+		Description: "Graph analytics: degree and PageRank rankings, connected components, orphan entities and leaf/hub counts, filterable by entity type (requires active project)",
+	}, kt.GraphStats)
+
+	// Graph curation tools
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "find_duplicates",
+		Description: "Find likely duplicate entity pairs scored by name similarity, shared observations and shared neighbors (requires active project)",
+	}, kt.FindDuplicates)
+
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "merge_entities",
+		Description: "Merge source entities into a target: moves observations, relations and properties, removes duplicates, soft-deletes sources and keeps their names as aliases (requires active project)",
+	}, tools.Idempotent(kt, "merge_entities", kt.MergeEntities))
+
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "validate_graph",
+		Description: "Lint the graph against the memory protocol and project ontology (entity types, relation naming and domain/range, duplicates, self-loops, empty entities, non-atomic observations, dangling relations), optionally applying safe fixes (requires active project)",
+	}, tools.Idempotent(kt, "validate_graph", kt.ValidateGraph))
+
+	// Ontology tools
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "get_ontology",
+		Description: "Get the project ontology: allowed entity types, relation types with source/target types and inverse names, and the enforcement mode (requires active project)",
+	}, kt.GetOntology)
+
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "set_ontology",
+		Description: "Replace the project ontology. In lenient mode writes outside it succeed with warnings; in strict mode they are rejected (requires active project)",
+	}, tools.Idempotent(kt, "set_ontology", kt.SetOntology))
 
 	return srv
 }
@@ -31,7 +31,7 @@ func (s *Session) SwitchProject(meta *storage.MetaStore, name string) (*models.P
 		return nil, err
 	}
 	if proj.Status == "archived" {
-		return nil, fmt.Errorf("project %q is archived — restore it first", name)
+		return nil, storage.Errorf(storage.ErrArchived, "project %q is archived — restore it first", name)
 	}
 
 	// Close current project DB if open
@@ -45,6 +45,9 @@ func (s *Session) SwitchProject(meta *storage.MetaStore, name string) (*models.P
 	if err != nil {
 		return nil, fmt.Errorf("open project db: %w", err)
 	}
+	pdb.SetEmbedder(meta.Embedder())
+	pdb.SetIdempotencyWindow(meta.IdempotencyWindow())
+	pdb.SetLimits(meta.Limits())
 
 	s.currentProjectID = proj.ID
 	s.currentProjectName = proj.Name
@@ -0,0 +1,790 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+// Batch operation kinds.
+const (
+	OpCreateEntity      = "create_entity"
+	OpUpdateEntity      = "update_entity"
+	OpDeleteEntity      = "delete_entity"
+	OpAddObservation    = "add_observation"
+	OpUpdateObservation = "update_observation"
+	OpDeleteObservation = "delete_observation"
+	OpCreateRelation    = "create_relation"
+	OpUpdateRelation    = "update_relation"
+	OpDeleteRelation    = "delete_relation"
+	OpSetProperty       = "set_property"
+)
+
+// Batch modes. In all_or_nothing mode a batch is applied only if every
+// operation succeeds; in best_effort mode the operations that succeed are
+// applied and the others are reported.
+const (
+	ModeAllOrNothing = "all_or_nothing"
+	ModeBestEffort   = "best_effort"
+)
+
+// Per-operation statuses of a batch result.
+const (
+	StatusCreated  = "created"
+	StatusUpdated  = "updated"
+	StatusDeleted  = "deleted"
+	StatusNotFound = "not_found"
+	StatusConflict = "conflict"
+	StatusInvalid  = "invalid"
+)
+
+// BatchOp is one operation of ApplyBatch. Op selects the fields used:
+//
+//	create_entity       Entity, EntityType, Observations, Meta and Validity (optional)
+//	update_entity       Entity, NewName and/or NewType
+//	delete_entity       Entity
+//	add_observation     Entity, Observations, Meta and Validity (optional)
+//	update_observation  Entity, Observation (current content), NewContent and/or Validity
+//	delete_observation  Entity, Observations
+//	create_relation     From, RelationType, To, Validity (optional)
+//	update_relation     From, RelationType, To, Validity
+//	delete_relation     From, RelationType, To
+//	set_property        Entity, Key, Value (nil removes the property), PropertyType (optional)
+//
+// Names are resolved inside the batch transaction, so an operation can refer
+// to an entity created or renamed by an earlier one. Deletions only resolve
+// exact names and aliases. Meta and Validity are
+// recorded on every observation or relation the operation creates; on
+// updates, the Validity bounds that are set replace the stored ones. A non-zero
+// ExpectedVersion makes the operation fail with a *VersionConflictError
+// unless Entity (From, for relation operations) is at that version when the
+// operation runs; create_entity ignores it.
+type BatchOp struct {
+	Op              string
+	Entity          string
+	EntityType      string
+	NewName         string
+	NewType         string
+	Observations    []string
+	Meta            models.ObservationMeta
+	Observation     string
+	NewContent      string
+	From            string
+	To              string
+	RelationType    string
+	Validity        models.Validity
+	Key             string
+	PropertyType    string
+	Value           any
+	ExpectedVersion int64
+}
+
+// VersionConflictError is returned when a write expects an entity version
+// that is no longer current, because the entity changed in between.
+type VersionConflictError struct {
+	Entity          string `json:"entity"`
+	ExpectedVersion int64  `json:"expected_version"`
+	CurrentVersion  int64  `json:"current_version"`
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("entity %q changed: expected version %d, current version is %d", e.Entity, e.ExpectedVersion, e.CurrentVersion)
+}
+
+// BatchError reports the operation that made ApplyBatch roll back.
+type BatchError struct {
+	Index int
+	Op    string
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d (%s): %v", e.Index, e.Op, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// itemStatus returns the status of a failed operation, or "" when the error
+// is not caused by the operation's input or the current graph and must
+// abort the batch.
+func itemStatus(err error) string {
+	switch {
+	case errors.Is(err, ErrConflict):
+		return StatusConflict
+	case errors.Is(err, ErrNotFound):
+		return StatusNotFound
+	case errors.Is(err, ErrInvalid):
+		return StatusInvalid
+	}
+	return ""
+}
+
+// ApplyBatch runs ops in order in a single transaction and returns one result
+// per operation. If any operation fails nothing is applied, and the error is
+// a *BatchError naming the first failed operation (0-based). Ontology
+// violations fail the batch in strict mode and are returned as warnings in
+// lenient mode.
+func (p *ProjectStore) ApplyBatch(ops []BatchOp) (*models.BatchResult, error) {
+	result, errs, err := p.applyBatch(ops, ModeAllOrNothing)
+	if err != nil {
+		return nil, err
+	}
+	for i, err := range errs {
+		if err != nil {
+			return nil, &BatchError{Index: i, Op: ops[i].Op, Err: err}
+		}
+	}
+	return result, nil
+}
+
+// ApplyBatchItems runs every operation of ops in order in a single
+// transaction and reports a status per operation, with the reason for the
+// ones that failed. Each operation runs in its own savepoint, so a failed
+// one leaves no trace and later ones still run. In all_or_nothing mode (the
+// default) the batch is committed only if every operation succeeded; in
+// best_effort mode the successful operations are committed. Applied tells
+// whether anything was committed. The error is reserved for failures that
+// are not specific to an operation, such as database errors.
+func (p *ProjectStore) ApplyBatchItems(ops []BatchOp, mode string) (*models.BatchResult, error) {
+	result, _, err := p.applyBatch(ops, mode)
+	return result, err
+}
+
+// applyBatch implements ApplyBatch and ApplyBatchItems. It returns the error
+// of each failed operation alongside the result.
+func (p *ProjectStore) applyBatch(ops []BatchOp, mode string) (*models.BatchResult, []error, error) {
+	if err := p.limits.checkBatchSize(len(ops)); err != nil {
+		return nil, nil, err
+	}
+	switch mode {
+	case "":
+		mode = ModeAllOrNothing
+	case ModeAllOrNothing, ModeBestEffort:
+	default:
+		return nil, nil, invalidf("unknown mode %q: use %s or %s", mode, ModeAllOrNothing, ModeBestEffort)
+	}
+
+	tx, err := p.begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	ontology, err := loadOntologyChecker(tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	w := &batchWriter{tx: tx, ontology: ontology, limits: p.limits}
+
+	result := &models.BatchResult{
+		Mode:    mode,
+		Results: make([]models.BatchOpResult, 0, len(ops)),
+		Counts:  make(map[string]int),
+	}
+	errs := make([]error, len(ops))
+	failed := false
+	for i, op := range ops {
+		res, err := w.applyItem(op)
+		if err != nil {
+			status := itemStatus(err)
+			if status == "" {
+				return nil, nil, &BatchError{Index: i, Op: op.Op, Err: err}
+			}
+			res = &models.BatchOpResult{Op: op.Op, Status: status, Reason: err.Error()}
+			var vc *VersionConflictError
+			if errors.As(err, &vc) {
+				res.Version = vc.CurrentVersion
+			}
+			errs[i] = err
+			failed = true
+		}
+		result.Results = append(result.Results, *res)
+		result.Counts[res.Status]++
+	}
+	result.Warnings = w.warnings
+
+	if failed && mode == ModeAllOrNothing {
+		return result, errs, nil
+	}
+	if err := p.commit(tx); err != nil {
+		return nil, nil, fmt.Errorf("commit: %w", err)
+	}
+	result.Applied = true
+
+	p.indexEmbeddings(w.toEmbed)
+	return result, errs, nil
+}
+
+// batchWriter applies batch operations inside a transaction and collects the
+// vectors to index once it commits.
+type batchWriter struct {
+	tx       *sql.Tx
+	ontology *ontologyChecker
+	limits   Limits
+	warnings []string
+	toEmbed  []embedItem
+
+	// versionedID is the entity whose version the current operation
+	// reports: the one it created or that ExpectedVersion applies to.
+	versionedID string
+}
+
+// applyItem applies one operation inside a savepoint. A failed operation is
+// rolled back to the savepoint, along with the warnings and vectors it
+// queued.
+func (w *batchWriter) applyItem(op BatchOp) (*models.BatchOpResult, error) {
+	warnings, toEmbed := len(w.warnings), len(w.toEmbed)
+	if _, err := w.tx.Exec(`SAVEPOINT batch_op`); err != nil {
+		return nil, fmt.Errorf("savepoint: %w", err)
+	}
+	res, err := w.apply(op)
+	if err != nil {
+		if _, rbErr := w.tx.Exec(`ROLLBACK TO batch_op`); rbErr != nil {
+			return nil, fmt.Errorf("rollback to savepoint: %w", rbErr)
+		}
+		w.warnings, w.toEmbed = w.warnings[:warnings], w.toEmbed[:toEmbed]
+	}
+	if _, relErr := w.tx.Exec(`RELEASE batch_op`); relErr != nil {
+		return nil, fmt.Errorf("release savepoint: %w", relErr)
+	}
+	return res, err
+}
+
+func (w *batchWriter) apply(op BatchOp) (*models.BatchOpResult, error) {
+	if err := w.limits.checkOp(&op); err != nil {
+		return nil, err
+	}
+	res := &models.BatchOpResult{Op: op.Op}
+	w.versionedID = ""
+	var err error
+	switch op.Op {
+	case OpCreateEntity:
+		res.Status = StatusCreated
+		res.Entity, err = w.createEntity(op)
+	case OpUpdateEntity:
+		res.Status = StatusUpdated
+		res.Entity, err = w.updateEntity(op)
+	case OpDeleteEntity:
+		res.Status = StatusDeleted
+		res.Entity, err = w.deleteEntity(op)
+		res.Deleted = 1
+	case OpAddObservation:
+		res.Status = StatusCreated
+		res.Observations, err = w.addObservations(op)
+	case OpUpdateObservation:
+		res.Status = StatusUpdated
+		res.Observations, err = w.updateObservation(op)
+	case OpDeleteObservation:
+		res.Status = StatusDeleted
+		res.Deleted, err = w.deleteObservations(op)
+	case OpCreateRelation:
+		res.Status = StatusCreated
+		res.Relation, err = w.createRelation(op)
+	case OpUpdateRelation:
+		res.Status = StatusUpdated
+		res.Relation, err = w.updateRelation(op)
+	case OpDeleteRelation:
+		res.Status = StatusDeleted
+		res.Deleted, err = w.deleteRelation(op)
+	case OpSetProperty:
+		res.Status, res.Property, err = w.setProperty(op)
+		if res.Status == StatusDeleted {
+			res.Deleted = 1
+		}
+	case "":
+		err = invalidf("op is required")
+	default:
+		err = invalidf("unknown op %q", op.Op)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Report the version the entity the operation resolved is at now
+	if w.versionedID != "" {
+		if err := w.tx.QueryRow(`SELECT version FROM entities WHERE id = ?`, w.versionedID).Scan(&res.Version); err != nil {
+			return nil, fmt.Errorf("read version: %w", err)
+		}
+	}
+	return res, nil
+}
+
+// resolveVersioned resolves the entity of op and checks its ExpectedVersion.
+// Targets of deletions are resolved by exact name or alias only.
+func (w *batchWriter) resolveVersioned(field, name string, expected int64, exact bool) (string, error) {
+	id, err := w.resolve(field, name, exact)
+	if err != nil {
+		return "", err
+	}
+	w.versionedID = id
+	if err := checkVersion(w.tx, id, name, expected); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// checkVersion returns a *VersionConflictError naming name unless entity id
+// is at version expected. A zero expected version accepts any version.
+func checkVersion(q querier, id, name string, expected int64) error {
+	if expected == 0 {
+		return nil
+	}
+	var current int64
+	if err := q.QueryRow(`SELECT version FROM entities WHERE id = ?`, id).Scan(&current); err != nil {
+		return fmt.Errorf("read version: %w", err)
+	}
+	if current != expected {
+		return &VersionConflictError{Entity: name, ExpectedVersion: expected, CurrentVersion: current}
+	}
+	return nil
+}
+
+// checkOntology rejects violations in strict mode and keeps them as warnings
+// in lenient mode.
+func (w *batchWriter) checkOntology(violations []string) error {
+	if len(violations) == 0 || w.ontology.mode == OntologyOff {
+		return nil
+	}
+	if w.ontology.strict() {
+		return &OntologyViolationError{Violations: violations}
+	}
+	w.warnings = append(w.warnings, violations...)
+	return nil
+}
+
+// resolve resolves a required entity name inside the transaction, by exact
+// name or alias only if exact is set.
+func (w *batchWriter) resolve(field, name string, exact bool) (string, error) {
+	if name == "" {
+		return "", invalidf("%s is required", field)
+	}
+	if exact {
+		return resolveTarget(w.tx, name)
+	}
+	return resolveEntity(w.tx, name)
+}
+
+// checkNameFree returns a conflict if an active entity other than id is
+// named name.
+func (w *batchWriter) checkNameFree(name, id string) error {
+	var taken int
+	if err := w.tx.QueryRow(
+		`SELECT COUNT(*) FROM entities WHERE name = ? AND id != ? AND deleted_at IS NULL`, name, id,
+	).Scan(&taken); err != nil {
+		return fmt.Errorf("check name: %w", err)
+	}
+	if taken > 0 {
+		return conflictf("entity %q already exists", name)
+	}
+	return nil
+}
+
+// loadEntity reads an entity row, without observations or relations.
+func (w *batchWriter) loadEntity(id string) (*models.Entity, error) {
+	var e models.Entity
+	err := w.tx.QueryRow(
+		`SELECT id, name, entity_type, version, created_at, updated_at FROM entities WHERE id = ?`, id,
+	).Scan(&e.ID, &e.Name, &e.EntityType, &e.Version, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("load entity: %w", err)
+	}
+	return &e, nil
+}
+
+func (w *batchWriter) createEntity(op BatchOp) (*models.Entity, error) {
+	if op.Entity == "" || op.EntityType == "" {
+		return nil, invalidf("entity and entity_type are required")
+	}
+	if err := w.checkOntology(w.ontology.checkEntity(op.Entity, op.EntityType)); err != nil {
+		return nil, err
+	}
+	if err := w.checkNameFree(op.Entity, ""); err != nil {
+		return nil, err
+	}
+
+	id := uuid.New().String()
+	if _, err := w.tx.Exec(
+		`INSERT INTO entities (id, name, name_fold, entity_type) VALUES (?, ?, ?, ?)`,
+		id, op.Entity, foldText(op.Entity), op.EntityType,
+	); err != nil {
+		return nil, fmt.Errorf("insert entity %q: %w", op.Entity, err)
+	}
+	w.toEmbed = append(w.toEmbed, entityEmbedItem(id, op.Entity, op.EntityType))
+	w.versionedID = id
+
+	entity, err := w.loadEntity(id)
+	if err != nil {
+		return nil, err
+	}
+	if entity.Observations, err = w.insertObservations(id, op.Observations, op.Meta, op.Validity); err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// updateEntity renames and/or retypes an entity. The old name is kept as an
+// alias so that references to it keep resolving.
+func (w *batchWriter) updateEntity(op BatchOp) (*models.Entity, error) {
+	id, err := w.resolveVersioned("entity", op.Entity, op.ExpectedVersion, false)
+	if err != nil {
+		return nil, err
+	}
+	if op.NewName == "" && op.NewType == "" {
+		return nil, invalidf("new_name or new_type is required")
+	}
+	entity, err := w.loadEntity(id)
+	if err != nil {
+		return nil, err
+	}
+	name, entityType := entity.Name, entity.EntityType
+	if op.NewName != "" {
+		name = op.NewName
+	}
+	if op.NewType != "" {
+		entityType = op.NewType
+		if err := w.checkOntology(w.ontology.checkEntity(name, entityType)); err != nil {
+			return nil, err
+		}
+	}
+
+	if name != entity.Name {
+		if err := w.checkNameFree(name, id); err != nil {
+			return nil, err
+		}
+		if _, err := w.tx.Exec(
+			`INSERT OR IGNORE INTO entity_aliases (alias, entity_id) VALUES (?, ?)`, entity.Name, id,
+		); err != nil {
+			return nil, fmt.Errorf("record alias: %w", err)
+		}
+	}
+
+	if _, err := w.tx.Exec(
+		`UPDATE entities SET name = ?, name_fold = ?, entity_type = ?, updated_at = datetime('now') WHERE id = ?`,
+		name, foldText(name), entityType, id,
+	); err != nil {
+		return nil, fmt.Errorf("update entity: %w", err)
+	}
+	w.toEmbed = append(w.toEmbed, entityEmbedItem(id, name, entityType))
+	return w.loadEntity(id)
+}
+
+// deleteEntity soft-deletes an entity and cascades to its observations,
+// properties and relations.
+func (w *batchWriter) deleteEntity(op BatchOp) (*models.Entity, error) {
+	id, err := w.resolveVersioned("entity", op.Entity, op.ExpectedVersion, true)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.tx.Exec(
+		`UPDATE observations SET deleted_at = datetime('now') WHERE entity_id = ? AND deleted_at IS NULL`, id,
+	); err != nil {
+		return nil, fmt.Errorf("soft-delete observations: %w", err)
+	}
+	if _, err := w.tx.Exec(
+		`UPDATE entity_properties SET deleted_at = datetime('now') WHERE entity_id = ? AND deleted_at IS NULL`, id,
+	); err != nil {
+		return nil, fmt.Errorf("soft-delete properties: %w", err)
+	}
+	if _, err := w.tx.Exec(
+		`UPDATE relations SET deleted_at = datetime('now') WHERE (from_entity = ?1 OR to_entity = ?1) AND deleted_at IS NULL`, id,
+	); err != nil {
+		return nil, fmt.Errorf("soft-delete relations: %w", err)
+	}
+	if _, err := w.tx.Exec(
+		`UPDATE entities SET deleted_at = datetime('now'), updated_at = datetime('now') WHERE id = ?`, id,
+	); err != nil {
+		return nil, fmt.Errorf("soft-delete entity: %w", err)
+	}
+	return w.loadEntity(id)
+}
+
+// insertObservations adds observations with the same metadata and validity
+// to an entity.
+func (w *batchWriter) insertObservations(entityID string, contents []string, meta models.ObservationMeta, validity models.Validity) ([]models.Observation, error) {
+	var created []models.Observation
+	for _, content := range contents {
+		obs := models.Observation{ID: uuid.New().String(), EntityID: entityID, Content: content, ObservationMeta: meta, Validity: validity}
+		if _, err := w.tx.Exec(
+			`INSERT INTO observations (id, entity_id, content, source, author, confidence, tags, valid_from, valid_until)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))`,
+			obs.ID, entityID, content, meta.Source, meta.Author, meta.Confidence, jsonArray(meta.Tags),
+			validity.ValidFrom, validity.ValidUntil,
+		); err != nil {
+			return nil, fmt.Errorf("insert observation: %w", err)
+		}
+		w.tx.QueryRow(`SELECT created_at FROM observations WHERE id = ?`, obs.ID).Scan(&obs.CreatedAt)
+		w.toEmbed = append(w.toEmbed, embedItem{entityID: entityID, observationID: obs.ID, text: content})
+		created = append(created, obs)
+	}
+	return created, nil
+}
+
+func (w *batchWriter) addObservations(op BatchOp) ([]models.Observation, error) {
+	id, err := w.resolveVersioned("entity", op.Entity, op.ExpectedVersion, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(op.Observations) == 0 {
+		return nil, invalidf("observations are required")
+	}
+	return w.insertObservations(id, op.Observations, op.Meta, op.Validity)
+}
+
+// mergeValidity sets the bounds of update on v and checks that they are
+// still in order.
+func mergeValidity(v *models.Validity, update models.Validity) error {
+	if update.ValidFrom != "" {
+		v.ValidFrom = update.ValidFrom
+	}
+	if update.ValidUntil != "" {
+		v.ValidUntil = update.ValidUntil
+	}
+	return checkValidity(v)
+}
+
+// updateObservation replaces the content and/or validity bounds of the
+// oldest active observation of the entity whose content is op.Observation,
+// keeping its metadata.
+func (w *batchWriter) updateObservation(op BatchOp) ([]models.Observation, error) {
+	id, err := w.resolveVersioned("entity", op.Entity, op.ExpectedVersion, false)
+	if err != nil {
+		return nil, err
+	}
+	if op.Observation == "" {
+		return nil, invalidf("observation is required")
+	}
+	if op.NewContent == "" && op.Validity == (models.Validity{}) {
+		return nil, invalidf("new_content, valid_from or valid_until is required")
+	}
+
+	var obs models.Observation
+	err = scanObservation(w.tx.QueryRow(
+		`SELECT `+observationColumns+` FROM observations o
+		 WHERE o.entity_id = ? AND o.content = ? AND o.deleted_at IS NULL
+		 ORDER BY o.created_at, o.rowid LIMIT 1`,
+		id, op.Observation,
+	), &obs)
+	if err == sql.ErrNoRows {
+		return nil, notFoundf("observation %q not found on %q", op.Observation, op.Entity)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find observation: %w", err)
+	}
+	if err := mergeValidity(&obs.Validity, op.Validity); err != nil {
+		return nil, err
+	}
+	if op.NewContent != "" {
+		if _, err := w.tx.Exec(`UPDATE observations SET content = ? WHERE id = ?`, op.NewContent, obs.ID); err != nil {
+			return nil, fmt.Errorf("update observation: %w", err)
+		}
+		obs.Content = op.NewContent
+		w.toEmbed = append(w.toEmbed, embedItem{entityID: id, observationID: obs.ID, text: op.NewContent})
+	}
+	if op.Validity != (models.Validity{}) {
+		if _, err := w.tx.Exec(
+			`UPDATE observations SET valid_from = NULLIF(?, ''), valid_until = NULLIF(?, '') WHERE id = ?`,
+			obs.ValidFrom, obs.ValidUntil, obs.ID,
+		); err != nil {
+			return nil, fmt.Errorf("update observation validity: %w", err)
+		}
+	}
+	return []models.Observation{obs}, nil
+}
+
+func (w *batchWriter) deleteObservations(op BatchOp) (int64, error) {
+	id, err := w.resolveVersioned("entity", op.Entity, op.ExpectedVersion, true)
+	if err != nil {
+		return 0, err
+	}
+	if len(op.Observations) == 0 {
+		return 0, invalidf("observations are required")
+	}
+	var total int64
+	for _, content := range op.Observations {
+		res, err := w.tx.Exec(
+			`UPDATE observations SET deleted_at = datetime('now') WHERE entity_id = ? AND content = ? AND deleted_at IS NULL`,
+			id, content,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("soft-delete observation: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	if total == 0 {
+		return 0, notFoundf("no observation of %q matches", op.Entity)
+	}
+	return total, nil
+}
+
+// relationEnds resolves the endpoints of a relation operation, by exact name
+// or alias only if exact is set.
+func (w *batchWriter) relationEnds(op BatchOp, exact bool) (fromID, toID string, err error) {
+	if op.RelationType == "" {
+		return "", "", invalidf("relation_type is required")
+	}
+	if fromID, err = w.resolveVersioned("from", op.From, op.ExpectedVersion, exact); err != nil {
+		return "", "", fmt.Errorf("from entity: %w", err)
+	}
+	if toID, err = w.resolve("to", op.To, exact); err != nil {
+		return "", "", fmt.Errorf("to entity: %w", err)
+	}
+	return fromID, toID, nil
+}
+
+func (w *batchWriter) createRelation(op BatchOp) (*models.Relation, error) {
+	fromID, toID, err := w.relationEnds(op, false)
+	if err != nil {
+		return nil, err
+	}
+	rel := models.Relation{ID: uuid.New().String(), FromEntity: fromID, ToEntity: toID, RelationType: op.RelationType, Validity: op.Validity}
+	if rel.FromName, rel.FromType, err = entityNameType(w.tx, fromID); err != nil {
+		return nil, err
+	}
+	if rel.ToName, rel.ToType, err = entityNameType(w.tx, toID); err != nil {
+		return nil, err
+	}
+	if err := w.checkOntology(w.ontology.checkRelation(rel.FromName, rel.FromType, rel.RelationType, rel.ToName, rel.ToType)); err != nil {
+		return nil, err
+	}
+	// The same relation may be recorded again for a period that does not
+	// overlap the ones already recorded.
+	var exists int
+	if err := w.tx.QueryRow(
+		`SELECT COUNT(*) FROM relations
+		 WHERE from_entity = ? AND to_entity = ? AND relation_type = ? AND deleted_at IS NULL
+		   AND (valid_from IS NULL OR ?5 = '' OR valid_from < ?5)
+		   AND (valid_until IS NULL OR ?4 = '' OR ?4 < valid_until)`,
+		fromID, toID, rel.RelationType, rel.ValidFrom, rel.ValidUntil,
+	).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("check relation: %w", err)
+	}
+	if exists > 0 {
+		return nil, conflictf("relation %s —%s→ %s already exists", rel.FromName, rel.RelationType, rel.ToName)
+	}
+
+	if _, err := w.tx.Exec(
+		`INSERT INTO relations (id, from_entity, to_entity, relation_type, valid_from, valid_until)
+		 VALUES (?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))`,
+		rel.ID, fromID, toID, rel.RelationType, rel.ValidFrom, rel.ValidUntil,
+	); err != nil {
+		return nil, fmt.Errorf("insert relation: %w", err)
+	}
+	w.tx.QueryRow(`SELECT created_at FROM relations WHERE id = ?`, rel.ID).Scan(&rel.CreatedAt)
+	return &rel, nil
+}
+
+// updateRelation sets the validity bounds of the latest recorded active
+// relation From —RelationType→ To, for instance to end it with valid_until.
+func (w *batchWriter) updateRelation(op BatchOp) (*models.Relation, error) {
+	fromID, toID, err := w.relationEnds(op, false)
+	if err != nil {
+		return nil, err
+	}
+	if op.Validity == (models.Validity{}) {
+		return nil, invalidf("valid_from or valid_until is required")
+	}
+	var rel models.Relation
+	err = w.tx.QueryRow(
+		`SELECT `+relationColumns+`
+		 FROM relations r
+		 JOIN entities f ON f.id = r.from_entity
+		 JOIN entities t ON t.id = r.to_entity
+		 WHERE r.from_entity = ? AND r.to_entity = ? AND r.relation_type = ? AND r.deleted_at IS NULL
+		 ORDER BY r.created_at DESC, r.rowid DESC LIMIT 1`,
+		fromID, toID, op.RelationType,
+	).Scan(relationDest(&rel)...)
+	if err == sql.ErrNoRows {
+		return nil, notFoundf("relation %s —%s→ %s not found", op.From, op.RelationType, op.To)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find relation: %w", err)
+	}
+	if err := mergeValidity(&rel.Validity, op.Validity); err != nil {
+		return nil, err
+	}
+	if _, err := w.tx.Exec(
+		`UPDATE relations SET valid_from = NULLIF(?, ''), valid_until = NULLIF(?, '') WHERE id = ?`,
+		rel.ValidFrom, rel.ValidUntil, rel.ID,
+	); err != nil {
+		return nil, fmt.Errorf("update relation: %w", err)
+	}
+	return &rel, nil
+}
+
+func (w *batchWriter) deleteRelation(op BatchOp) (int64, error) {
+	fromID, toID, err := w.relationEnds(op, true)
+	if err != nil {
+		return 0, err
+	}
+	res, err := w.tx.Exec(
+		`UPDATE relations SET deleted_at = datetime('now')
+		 WHERE from_entity = ? AND to_entity = ? AND relation_type = ? AND deleted_at IS NULL`,
+		fromID, toID, op.RelationType,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("soft-delete relation: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return 0, notFoundf("relation %s —%s→ %s not found", op.From, op.RelationType, op.To)
+	}
+	return n, nil
+}
+
+// setProperty sets, or with a nil Value removes, a property of an entity and
+// returns the status of the change with the property as it now is.
+func (w *batchWriter) setProperty(op BatchOp) (string, *models.Property, error) {
+	id, err := w.resolveVersioned("entity", op.Entity, op.ExpectedVersion, false)
+	if err != nil {
+		return "", nil, err
+	}
+	var current string
+	err = w.tx.QueryRow(
+		`SELECT value_type || ':' || value FROM entity_properties WHERE entity_id = ? AND key = ? AND deleted_at IS NULL`, id, op.Key,
+	).Scan(&current)
+	exists := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		return "", nil, fmt.Errorf("find property: %w", err)
+	}
+
+	if op.Value == nil {
+		if !exists {
+			return "", nil, notFoundf("property %q not found on %q", op.Key, op.Entity)
+		}
+		if _, err := w.tx.Exec(
+			`UPDATE entity_properties SET deleted_at = datetime('now'), updated_at = datetime('now') WHERE entity_id = ? AND key = ?`, id, op.Key,
+		); err != nil {
+			return "", nil, fmt.Errorf("soft-delete property: %w", err)
+		}
+		return StatusDeleted, nil, nil
+	}
+
+	typ, text, err := w.limits.encodeProperty(op.PropertyType, op.Value)
+	if err != nil {
+		return "", nil, err
+	}
+	status := StatusCreated
+	if exists {
+		status = StatusUpdated
+	}
+	if current != typ+":"+text {
+		if _, err := w.tx.Exec(
+			`INSERT INTO entity_properties (id, entity_id, key, value_type, value) VALUES (?, ?, ?, ?, ?)
+			 ON CONFLICT (entity_id, key) DO UPDATE SET value_type = excluded.value_type, value = excluded.value,
+			     updated_at = datetime('now'), deleted_at = NULL`,
+			uuid.New().String(), id, op.Key, typ, text,
+		); err != nil {
+			return "", nil, fmt.Errorf("set property: %w", err)
+		}
+	}
+	prop := &models.Property{Key: op.Key, Type: typ, Value: decodeProperty(typ, text)}
+	w.tx.QueryRow(`SELECT updated_at FROM entity_properties WHERE entity_id = ? AND key = ?`, id, op.Key).Scan(&prop.UpdatedAt)
+	return status, prop, nil
+}
@@ -0,0 +1,337 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+func TestApplyBatch(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	result, err := ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Memory Cloud", EntityType: "project", Observations: []string{"MCP server"}},
+		{Op: OpCreateEntity, Entity: "Golang", EntityType: "technology"},
+		// Both entities only exist inside the batch transaction so far
+		{Op: OpCreateRelation, From: "Memory Cloud", To: "Golang", RelationType: "uses"},
+		{Op: OpAddObservation, Entity: "Golang", Observations: []string{"Compiled", "Garbage colected"}},
+		{Op: OpUpdateObservation, Entity: "Golang", Observation: "Garbage colected", NewContent: "Garbage collected"},
+		{Op: OpUpdateEntity, Entity: "Golang", NewName: "Go"},
+		{Op: OpDeleteObservation, Entity: "Go", Observations: []string{"Compiled"}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+	if len(result.Results) != 7 {
+		t.Fatalf("Expected 7 results, got %d", len(result.Results))
+	}
+	if r := result.Results[2].Relation; r == nil || r.FromName != "Memory Cloud" || r.ToName != "Golang" || r.CreatedAt == "" {
+		t.Errorf("Unexpected relation result %+v", r)
+	}
+	if e := result.Results[5].Entity; e == nil || e.Name != "Go" {
+		t.Errorf("Unexpected rename result %+v", e)
+	}
+	if result.Results[6].Deleted != 1 {
+		t.Errorf("Expected 1 deleted observation, got %d", result.Results[6].Deleted)
+	}
+
+	entities, _ := ps.GetEntities([]string{"Golang"})
+	if len(entities) != 1 || entities[0].Name != "Go" {
+		t.Fatalf("The old name should resolve to the renamed entity, got %+v", entities)
+	}
+	if obs := entities[0].Observations; len(obs) != 1 || obs[0].Content != "Garbage collected" {
+		t.Errorf("Go observations = %+v, want only the corrected one", obs)
+	}
+	if len(entities[0].Incoming) != 1 || entities[0].Incoming[0].FromName != "Memory Cloud" {
+		t.Errorf("Go should keep its incoming relation, got %+v", entities[0].Incoming)
+	}
+
+	result, err = ps.ApplyBatch([]BatchOp{
+		{Op: OpDeleteRelation, From: "Memory Cloud", To: "Go", RelationType: "uses"},
+		{Op: OpDeleteEntity, Entity: "Memory Cloud"},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch deletes: %v", err)
+	}
+	if result.Results[0].Deleted != 1 || result.Results[1].Deleted != 1 {
+		t.Errorf("Unexpected delete results %+v", result.Results)
+	}
+	if entities, _ := ps.GetEntities([]string{"Memory Cloud"}); len(entities) != 0 {
+		t.Error("Memory Cloud should be deleted")
+	}
+}
+
+func TestApplyBatchRollback(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	_, err := ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Memory Cloud", EntityType: "project"},
+		{Op: OpAddObservation, Entity: "Memory Cloud", Observations: []string{"MCP server"}},
+		{Op: OpCreateRelation, From: "Memory Cloud", To: "Nowhere", RelationType: "uses"},
+	})
+	var be *BatchError
+	if !errors.As(err, &be) || be.Index != 2 || be.Op != OpCreateRelation {
+		t.Fatalf("Expected a BatchError for operation 2, got %v", err)
+	}
+	var nf *EntityNotFoundError
+	if !errors.As(err, &nf) || nf.Name != "Nowhere" {
+		t.Errorf("BatchError should wrap the not-found error, got %v", err)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("BatchError should match ErrNotFound, got %v", err)
+	}
+	if entities, _ := ps.GetEntities([]string{"Memory Cloud"}); len(entities) != 0 {
+		t.Error("A failed batch must not leave earlier operations applied")
+	}
+
+	for _, op := range []BatchOp{
+		{Op: "upsert_entity", Entity: "X"},
+		{Op: OpCreateEntity, Entity: "X"},
+		{Op: OpUpdateEntity, Entity: "X"},
+	} {
+		if _, err := ps.ApplyBatch([]BatchOp{op}); err == nil {
+			t.Errorf("Expected an error for %+v", op)
+		}
+	}
+}
+
+func TestApplyBatchItems(t *testing.T) {
+	ps := setupProjectStore(t)
+	ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Go", EntityType: "technology", Observations: []string{"Compiled"}},
+		{Op: OpCreateEntity, Entity: "Memory Cloud", EntityType: "project"},
+		{Op: OpCreateRelation, From: "Memory Cloud", To: "Go", RelationType: "uses"},
+	})
+
+	ops := []BatchOp{
+		{Op: OpCreateEntity, Entity: "SQLite", EntityType: "technology"},
+		{Op: OpCreateEntity, Entity: "Go", EntityType: "technology"},
+		{Op: OpCreateRelation, From: "Memory Cloud", To: "Go", RelationType: "uses"},
+		{Op: OpDeleteRelation, From: "Go", To: "Memory Cloud", RelationType: "uses"},
+		{Op: OpDeleteObservation, Entity: "Go", Observations: []string{"Interpreted"}},
+		{Op: OpAddObservation, Entity: "Go"},
+		{Op: OpDeleteEntity, Entity: "Nowhere"},
+	}
+	want := []string{StatusCreated, StatusConflict, StatusConflict, StatusNotFound, StatusNotFound, StatusInvalid, StatusNotFound}
+
+	result, err := ps.ApplyBatchItems(ops, "")
+	if err != nil {
+		t.Fatalf("ApplyBatchItems: %v", err)
+	}
+	if result.Mode != ModeAllOrNothing || result.Applied {
+		t.Errorf("A failing all_or_nothing batch should not be applied, got mode %q applied %v", result.Mode, result.Applied)
+	}
+	for i, res := range result.Results {
+		if res.Status != want[i] {
+			t.Errorf("Operation %d: status %q, want %q (%s)", i, res.Status, want[i], res.Reason)
+		}
+		if res.Status != StatusCreated && res.Reason == "" {
+			t.Errorf("Operation %d: a failure needs a reason", i)
+		}
+	}
+	if entities, _ := ps.GetEntities([]string{"SQLite"}); len(entities) != 0 {
+		t.Error("SQLite should not be created by a rolled-back batch")
+	}
+
+	result, err = ps.ApplyBatchItems(ops, ModeBestEffort)
+	if err != nil {
+		t.Fatalf("ApplyBatchItems best_effort: %v", err)
+	}
+	if !result.Applied || result.Counts[StatusCreated] != 1 || result.Counts[StatusNotFound] != 3 {
+		t.Errorf("Unexpected best_effort result: applied %v, counts %v", result.Applied, result.Counts)
+	}
+	if entities, _ := ps.GetEntities([]string{"SQLite"}); len(entities) != 1 {
+		t.Error("best_effort should apply the successful operation")
+	}
+
+	if _, err := ps.ApplyBatchItems(ops, "sometimes"); err == nil {
+		t.Error("Expected an error for an unknown mode")
+	}
+}
+
+func TestApplyBatchDeleteNearMiss(t *testing.T) {
+	ps := setupProjectStore(t)
+	ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "ADR-001", EntityType: "decision", Observations: []string{"Use SQLite"}},
+		{Op: OpCreateEntity, Entity: "Memory Cloud", EntityType: "project"},
+		{Op: OpCreateRelation, From: "Memory Cloud", To: "ADR-001", RelationType: "follows"},
+	})
+
+	// Deletions only resolve exact names, even when a fold would match
+	result, err := ps.ApplyBatchItems([]BatchOp{
+		{Op: OpDeleteEntity, Entity: "ADR-007"},
+		{Op: OpDeleteEntity, Entity: "adr-001"},
+		{Op: OpDeleteObservation, Entity: "ADR-007", Observations: []string{"Use SQLite"}},
+		{Op: OpDeleteRelation, From: "Memory Cloud", To: "ADR-007", RelationType: "follows"},
+	}, ModeBestEffort)
+	if err != nil {
+		t.Fatalf("ApplyBatchItems: %v", err)
+	}
+	if result.Counts[StatusNotFound] != 4 {
+		t.Fatalf("Every near miss should be not found, got %+v", result.Results)
+	}
+	if !strings.Contains(result.Results[0].Reason, `did you mean "ADR-001"`) {
+		t.Errorf("A near miss should suggest ADR-001, got %q", result.Results[0].Reason)
+	}
+	entities, _ := ps.GetEntities([]string{"ADR-001"})
+	if len(entities) != 1 || len(entities[0].Observations) != 1 || len(entities[0].Incoming) != 1 {
+		t.Errorf("ADR-001 should be left untouched, got %+v", entities)
+	}
+}
+
+func TestEntityVersions(t *testing.T) {
+	ps := setupProjectStore(t)
+	result, err := ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Go", EntityType: "technology"},
+		{Op: OpCreateEntity, Entity: "Memory Cloud", EntityType: "project"},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+	if v := result.Results[0].Entity.Version; v != 1 {
+		t.Fatalf("A new entity should be at version 1, got %d", v)
+	}
+
+	// Observation and relation writes bump the entity's version
+	result, err = ps.ApplyBatch([]BatchOp{
+		{Op: OpAddObservation, Entity: "Go", Observations: []string{"Compiled"}, ExpectedVersion: 1},
+		{Op: OpCreateRelation, From: "Memory Cloud", To: "Go", RelationType: "uses"},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch with the current version: %v", err)
+	}
+	if result.Results[0].Version != 2 {
+		t.Errorf("Expected version 2 after adding an observation, got %d", result.Results[0].Version)
+	}
+	entities, _ := ps.GetEntities([]string{"Go"})
+	if entities[0].Version != 3 {
+		t.Errorf("Expected version 3 after the incoming relation, got %d", entities[0].Version)
+	}
+
+	// A stale version is a structured conflict and applies nothing
+	_, err = ps.ApplyBatch([]BatchOp{
+		{Op: OpDeleteObservation, Entity: "Go", Observations: []string{"Compiled"}, ExpectedVersion: 2},
+	})
+	var vc *VersionConflictError
+	if !errors.As(err, &vc) || vc.Entity != "Go" || vc.ExpectedVersion != 2 || vc.CurrentVersion != 3 {
+		t.Fatalf("Expected a version conflict, got %v", err)
+	}
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("A version conflict should match ErrConflict, got %v", err)
+	}
+	items, err := ps.ApplyBatchItems([]BatchOp{
+		{Op: OpDeleteEntity, Entity: "Go", ExpectedVersion: 2},
+	}, ModeBestEffort)
+	if err != nil {
+		t.Fatalf("ApplyBatchItems: %v", err)
+	}
+	if res := items.Results[0]; res.Status != StatusConflict || res.Version != 3 {
+		t.Errorf("Expected a conflict reporting version 3, got %+v", res)
+	}
+	if entities, _ := ps.GetEntities([]string{"Go"}); len(entities) != 1 || len(entities[0].Observations) != 1 {
+		t.Error("A conflicting write must not change the entity")
+	}
+
+	// A deleted entity reports its own version, not that of an entity its
+	// name now folds to
+	ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "GO", EntityType: "concept", Observations: []string{"Game", "Board"}},
+	})
+	items, err = ps.ApplyBatchItems([]BatchOp{{Op: OpDeleteEntity, Entity: "Go"}}, ModeBestEffort)
+	if err != nil {
+		t.Fatalf("ApplyBatchItems: %v", err)
+	}
+	if res := items.Results[0]; res.Status != StatusDeleted || res.Entity == nil || res.Version != res.Entity.Version || res.Version != 6 {
+		t.Errorf("Expected the deleted entity's version 6, got %+v", res)
+	}
+}
+
+func TestApplyBatchOntology(t *testing.T) {
+	ps := setupProjectStore(t)
+	if _, err := ps.SetOntology(testOntology(OntologyStrict)); err != nil {
+		t.Fatalf("SetOntology: %v", err)
+	}
+
+	_, err := ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Memory Cloud", EntityType: "project"},
+		{Op: OpCreateEntity, Entity: "Go", EntityType: "language"},
+	})
+	var ov *OntologyViolationError
+	if !errors.As(err, &ov) {
+		t.Fatalf("Strict mode should reject the unknown type, got %v", err)
+	}
+	if !errors.Is(err, ErrInvalid) {
+		t.Errorf("An ontology violation should match ErrInvalid, got %v", err)
+	}
+
+	ps.SetOntology(testOntology(OntologyLenient))
+	result, err := ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Memory Cloud", EntityType: "project"},
+		{Op: OpCreateEntity, Entity: "Go", EntityType: "language"},
+	})
+	if err != nil {
+		t.Fatalf("Lenient mode should apply the batch: %v", err)
+	}
+	if len(result.Warnings) != 1 {
+		t.Errorf("Expected 1 warning, got %v", result.Warnings)
+	}
+}
+
+func TestObservationMeta(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	confidence := 0.8
+	meta := models.ObservationMeta{
+		Source:     " https://wiki.example.com/go ",
+		Author:     "client-42",
+		Confidence: &confidence,
+		Tags:       []string{"meeting", " docs ", "meeting", ""},
+	}
+	result, err := ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Go", EntityType: "technology", Observations: []string{"Compiled"}, Meta: meta},
+		{Op: OpAddObservation, Entity: "Go", Observations: []string{"Garbage collected"}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+	got := result.Results[0].Entity.Observations[0].ObservationMeta
+	if got.Source != "https://wiki.example.com/go" || got.Author != "client-42" || *got.Confidence != 0.8 {
+		t.Errorf("Unexpected metadata %+v", got)
+	}
+	if len(got.Tags) != 2 || got.Tags[0] != "meeting" || got.Tags[1] != "docs" {
+		t.Errorf("Tags = %q, want trimmed and deduplicated", got.Tags)
+	}
+
+	entities, _ := ps.GetEntities([]string{"Go"})
+	obs := entities[0].Observations
+	if len(obs) != 2 || obs[0].Author != "client-42" || len(obs[0].Tags) != 2 {
+		t.Fatalf("Metadata should be stored, got %+v", obs)
+	}
+	if plain := obs[1].ObservationMeta; plain.Source != "" || plain.Confidence != nil || plain.Tags != nil {
+		t.Errorf("An observation without metadata should have none, got %+v", plain)
+	}
+
+	// Updating the content keeps the metadata
+	result, err = ps.ApplyBatch([]BatchOp{
+		{Op: OpUpdateObservation, Entity: "Go", Observation: "Compiled", NewContent: "Compiled to native code"},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch update: %v", err)
+	}
+	if o := result.Results[0].Observations[0]; o.Content != "Compiled to native code" || o.Author != "client-42" {
+		t.Errorf("Unexpected updated observation %+v", o)
+	}
+
+	invalid := 1.5
+	items, err := ps.ApplyBatchItems([]BatchOp{
+		{Op: OpAddObservation, Entity: "Go", Observations: []string{"Fast"}, Meta: models.ObservationMeta{Confidence: &invalid}},
+	}, ModeBestEffort)
+	if err != nil {
+		t.Fatalf("ApplyBatchItems: %v", err)
+	}
+	if res := items.Results[0]; res.Status != StatusInvalid || !strings.Contains(res.Reason, "confidence") {
+		t.Errorf("Expected an invalid confidence, got %+v", res)
+	}
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// benchEntities is the size of the seeded benchmark project.
+const benchEntities = 10000
+
+// setupBenchProject creates a project with benchEntities entities, three
+// observations each, and two outgoing relations per entity.
+func setupBenchProject(b *testing.B) *ProjectStore {
+	b.Helper()
+	dbPath := filepath.Join(b.TempDir(), "bench.db")
+	if err := initProjectDB(dbPath); err != nil {
+		b.Fatalf("initProjectDB: %v", err)
+	}
+	ps, err := OpenProject(dbPath)
+	if err != nil {
+		b.Fatalf("OpenProject: %v", err)
+	}
+	b.Cleanup(func() { ps.Close() })
+
+	const batch = 500
+	for start := 0; start < benchEntities; start += batch {
+		entities := make([]struct {
+			Name         string
+			EntityType   string
+			Observations []string
+		}, batch)
+		for i := range entities {
+			n := start + i
+			entities[i].Name = fmt.Sprintf("Entity %05d", n)
+			entities[i].EntityType = []string{"technology", "project", "person", "concept"}[n%4]
+			entities[i].Observations = []string{
+				fmt.Sprintf("Observation one about entity %d", n),
+				fmt.Sprintf("Observation two mentions topic%d", n%100),
+				"Shared observation text for every entity",
+			}
+		}
+		if _, err := ps.CreateEntities(entities); err != nil {
+			b.Fatalf("CreateEntities: %v", err)
+		}
+
+		relations := make([]struct {
+			From         string
+			To           string
+			RelationType string
+		}, 0, 2*batch)
+		for i := 0; i < batch; i++ {
+			n := start + i
+			for _, offset := range []int{1, 7} {
+				if to := n + offset; to < start+batch {
+					relations = append(relations, struct {
+						From         string
+						To           string
+						RelationType string
+					}{fmt.Sprintf("Entity %05d", n), fmt.Sprintf("Entity %05d", to), "uses"})
+				}
+			}
+		}
+		if _, err := ps.CreateRelations(relations); err != nil {
+			b.Fatalf("CreateRelations: %v", err)
+		}
+	}
+	return ps
+}
+
+func BenchmarkSearch(b *testing.B) {
+	ps := setupBenchProject(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// "topic42" matches 100 entities through their observations
+		entities, err := ps.Search("topic42")
+		if err != nil || len(entities) != benchEntities/100 {
+			b.Fatalf("Search: %d entities, %v", len(entities), err)
+		}
+	}
+}
+
+func BenchmarkGetEntities(b *testing.B) {
+	ps := setupBenchProject(b)
+	names := make([]string, 50)
+	for i := range names {
+		names[i] = fmt.Sprintf("Entity %05d", i*37)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entities, err := ps.GetEntities(names)
+		if err != nil || len(entities) != len(names) {
+			b.Fatalf("GetEntities: %d entities, %v", len(entities), err)
+		}
+	}
+}
+
+func BenchmarkReadGraphPage(b *testing.B) {
+	ps := setupBenchProject(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		page, err := ps.ReadGraphPage(ReadGraphOptions{Limit: 100, IncludeObservations: true, IncludeRelations: true})
+		if err != nil || len(page.Entities) != 100 {
+			b.Fatalf("ReadGraphPage: %d entities, %v", len(page.Entities), err)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+// Sort orders for ListEntities.
+const (
+	SortByName         = "name"
+	SortByUpdated      = "updated"
+	SortByObservations = "observations"
+)
+
+// ListEntitiesOptions controls ListEntities.
+type ListEntitiesOptions struct {
+	// EntityTypes restricts the catalog to these types.
+	EntityTypes []string
+	// NamePrefix keeps entities whose name starts with it (case-sensitive,
+	// so the name index can be used).
+	NamePrefix string
+	// Sort is SortByName (default), SortByUpdated (most recent first) or
+	// SortByObservations (most observations first); ties are ordered by name.
+	Sort string
+	// Limit caps the number of entities per page; 0 means no limit.
+	Limit int
+	// Cursor resumes after the last entity of a previous page.
+	Cursor string
+}
+
+// ListEntities returns one page of the entity catalog: names, types,
+// observation counts and update times, without loading observations.
+func (p *ProjectStore) ListEntities(opts ListEntitiesOptions) (*models.EntityList, error) {
+	where := []string{"deleted_at IS NULL"}
+	var args []any
+	if len(opts.EntityTypes) > 0 {
+		where = append(where, "entity_type IN ("+inPlaceholders(len(opts.EntityTypes))+")")
+		args = append(args, stringArgs(opts.EntityTypes)...)
+	}
+	if opts.NamePrefix != "" {
+		// A range rather than LIKE, which cannot use the binary name index
+		where = append(where, "name >= ? AND name < ?")
+		args = append(args, opts.NamePrefix, opts.NamePrefix+string(utf8.MaxRune))
+	}
+
+	list := &models.EntityList{Entities: []models.EntitySummary{}}
+	if err := p.db.QueryRow(
+		`SELECT COUNT(*) FROM entities WHERE `+strings.Join(where, " AND "), args...,
+	).Scan(&list.TotalEntities); err != nil {
+		return nil, fmt.Errorf("count entities: %w", err)
+	}
+
+	// key is the leading sort column for the non-name orders, descending
+	var key string
+	switch opts.Sort {
+	case "", SortByName:
+	case SortByUpdated:
+		key = "updated_at"
+	case SortByObservations:
+		key = "obs_count"
+	default:
+		return nil, invalidf("invalid sort %q (use name, updated or observations)", opts.Sort)
+	}
+
+	query := `SELECT id, name, entity_type, version, updated_at, obs_count FROM (
+	              SELECT e.id, e.name, e.entity_type, e.version, e.updated_at,
+	                     (SELECT COUNT(*) FROM observations o
+	                      WHERE o.entity_id = e.id AND o.deleted_at IS NULL) AS obs_count
+	              FROM entities e WHERE ` + strings.Join(where, " AND ") + `
+	          )`
+	if opts.Cursor != "" {
+		if key == "" {
+			keys, err := decodeCursor(opts.Cursor, 2)
+			if err != nil {
+				return nil, err
+			}
+			query += ` WHERE (name, id) > (?, ?)`
+			args = append(args, keys[0], keys[1])
+		} else {
+			keys, err := decodeCursor(opts.Cursor, 3)
+			if err != nil {
+				return nil, err
+			}
+			var last any = keys[0]
+			if key == "obs_count" {
+				n, err := strconv.Atoi(keys[0])
+				if err != nil {
+					return nil, invalidf("invalid cursor %q", opts.Cursor)
+				}
+				last = n
+			}
+			query += fmt.Sprintf(` WHERE (%[1]s < ? OR (%[1]s = ? AND (name, id) > (?, ?)))`, key)
+			args = append(args, last, last, keys[1], keys[2])
+		}
+	}
+	if key == "" {
+		query += ` ORDER BY name, id`
+	} else {
+		query += ` ORDER BY ` + key + ` DESC, name, id`
+	}
+	if opts.Limit > 0 {
+		// Fetch one extra row to know whether another page follows
+		query += ` LIMIT ?`
+		args = append(args, opts.Limit+1)
+	}
+
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list entities: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e models.EntitySummary
+		if err := rows.Scan(&e.ID, &e.Name, &e.EntityType, &e.Version, &e.UpdatedAt, &e.ObservationCount); err != nil {
+			return nil, fmt.Errorf("scan entity: %w", err)
+		}
+		list.Entities = append(list.Entities, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if opts.Limit > 0 && len(list.Entities) > opts.Limit {
+		list.Entities = list.Entities[:opts.Limit]
+		last := list.Entities[len(list.Entities)-1]
+		switch key {
+		case "":
+			list.NextCursor = encodeCursor(last.Name, last.ID)
+		case "updated_at":
+			list.NextCursor = encodeCursor(last.UpdatedAt, last.Name, last.ID)
+		default:
+			list.NextCursor = encodeCursor(strconv.Itoa(last.ObservationCount), last.Name, last.ID)
+		}
+	}
+	return list, nil
+}
+
+// ListRelationsOptions controls ListRelations. Every filter is optional.
+type ListRelationsOptions struct {
+	// RelationTypes restricts the catalog to these relation types.
+	RelationTypes []string
+	// From and To name the source and target entity; they are resolved
+	// like any other entity name.
+	From string
+	To   string
+	// FromTypes and ToTypes restrict the entity types of the endpoints.
+	FromTypes []string
+	ToTypes   []string
+	// Limit caps the number of relations per page; 0 means no limit.
+	Limit int
+	// Cursor resumes after the last relation of a previous page.
+	Cursor string
+}
+
+// ListRelations returns one page of active relations, ordered by relation
+// type, source name and target name, with endpoint names filled in.
+// Relations not valid as of the store's valid time are left out, or listed
+// marked historical on a view that includes them.
+func (p *ProjectStore) ListRelations(opts ListRelationsOptions) (*models.RelationList, error) {
+	valid := p.validity()
+	validCond, args := valid.where("r")
+	where := []string{"r.deleted_at IS NULL" + validCond}
+	for _, end := range []struct{ name, column string }{{opts.From, "r.from_entity"}, {opts.To, "r.to_entity"}} {
+		if end.name == "" {
+			continue
+		}
+		id, err := resolveEntity(p.db, end.name)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, end.column+" = ?")
+		args = append(args, id)
+	}
+	if len(opts.RelationTypes) > 0 {
+		where = append(where, "r.relation_type IN ("+inPlaceholders(len(opts.RelationTypes))+")")
+		args = append(args, stringArgs(opts.RelationTypes)...)
+	}
+	if len(opts.FromTypes) > 0 {
+		where = append(where, "f.entity_type IN ("+inPlaceholders(len(opts.FromTypes))+")")
+		args = append(args, stringArgs(opts.FromTypes)...)
+	}
+	if len(opts.ToTypes) > 0 {
+		where = append(where, "t.entity_type IN ("+inPlaceholders(len(opts.ToTypes))+")")
+		args = append(args, stringArgs(opts.ToTypes)...)
+	}
+	from := ` FROM relations r
+	          JOIN entities f ON f.id = r.from_entity
+	          JOIN entities t ON t.id = r.to_entity
+	          WHERE `
+
+	list := &models.RelationList{Relations: []models.Relation{}}
+	if err := p.db.QueryRow(
+		`SELECT COUNT(*)`+from+strings.Join(where, " AND "), args...,
+	).Scan(&list.TotalRelations); err != nil {
+		return nil, fmt.Errorf("count relations: %w", err)
+	}
+
+	if opts.Cursor != "" {
+		keys, err := decodeCursor(opts.Cursor, 4)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, "(r.relation_type, f.name, t.name, r.id) > (?, ?, ?, ?)")
+		args = append(args, stringArgs(keys)...)
+	}
+	query := `SELECT ` + relationColumns + from + strings.Join(where, " AND ") +
+		` ORDER BY r.relation_type, f.name, t.name, r.id`
+	if opts.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, opts.Limit+1)
+	}
+
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list relations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r models.Relation
+		if err := rows.Scan(relationDest(&r)...); err != nil {
+			return nil, fmt.Errorf("scan relation: %w", err)
+		}
+		valid.keep(&r.Validity)
+		list.Relations = append(list.Relations, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if opts.Limit > 0 && len(list.Relations) > opts.Limit {
+		list.Relations = list.Relations[:opts.Limit]
+		last := list.Relations[len(list.Relations)-1]
+		list.NextCursor = encodeCursor(last.RelationType, last.FromName, last.ToName, last.ID)
+	}
+	return list, nil
+}
+
+// Describe counts the active and soft-deleted rows of the project, lists
+// the entity and relation types in use (most used first) and the last
+// recent entities to be updated. Project and DBSizeBytes are left for
+// the caller, which knows the project from the meta store.
+func (p *ProjectStore) Describe(recent int) (*models.ProjectDescription, error) {
+	desc := &models.ProjectDescription{}
+	err := p.db.QueryRow(
+		`SELECT
+		     (SELECT COUNT(*) - COUNT(deleted_at) FROM entities),
+		     (SELECT COUNT(deleted_at) FROM entities),
+		     (SELECT COUNT(*) - COUNT(deleted_at) FROM observations),
+		     (SELECT COUNT(deleted_at) FROM observations),
+		     (SELECT COUNT(*) - COUNT(deleted_at) FROM relations),
+		     (SELECT COUNT(deleted_at) FROM relations)`,
+	).Scan(
+		&desc.Counts.Entities, &desc.Deleted.Entities,
+		&desc.Counts.Observations, &desc.Deleted.Observations,
+		&desc.Counts.Relations, &desc.Deleted.Relations,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("count rows: %w", err)
+	}
+
+	if desc.EntityTypes, err = p.typeCounts(`SELECT entity_type, COUNT(*) FROM entities
+		WHERE deleted_at IS NULL GROUP BY entity_type ORDER BY 2 DESC, 1`); err != nil {
+		return nil, err
+	}
+	if desc.RelationTypes, err = p.typeCounts(`SELECT relation_type, COUNT(*) FROM relations
+		WHERE deleted_at IS NULL GROUP BY relation_type ORDER BY 2 DESC, 1`); err != nil {
+		return nil, err
+	}
+
+	list, err := p.ListEntities(ListEntitiesOptions{Sort: SortByUpdated, Limit: recent})
+	if err != nil {
+		return nil, err
+	}
+	desc.RecentlyUpdated = list.Entities
+	return desc, nil
+}
+
+// typeCounts runs a query returning (type, count) rows.
+func (p *ProjectStore) typeCounts(query string) ([]models.TypeCount, error) {
+	rows, err := p.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("count types: %w", err)
+	}
+	defer rows.Close()
+	counts := []models.TypeCount{}
+	for rows.Next() {
+		var c models.TypeCount
+		if err := rows.Scan(&c.Type, &c.Count); err != nil {
+			return nil, fmt.Errorf("scan type count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
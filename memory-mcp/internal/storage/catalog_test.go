@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func setupCatalog(t *testing.T) *ProjectStore {
+	t.Helper()
+	ps := setupProjectStore(t)
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{
+		{Name: "Go", EntityType: "technology", Observations: []string{"g1", "g2", "g3"}},
+		{Name: "Gopls", EntityType: "technology", Observations: []string{"p1"}},
+		{Name: "Ana", EntityType: "person", Observations: []string{"a1", "a2"}},
+		{Name: "Memory Cloud", EntityType: "project"},
+		{Name: "SQLite", EntityType: "technology", Observations: []string{"s1"}},
+	})
+	ps.CreateRelations([]struct {
+		From         string
+		To           string
+		RelationType string
+	}{
+		{From: "Memory Cloud", To: "Go", RelationType: "uses"},
+		{From: "Memory Cloud", To: "SQLite", RelationType: "uses"},
+		{From: "Gopls", To: "Go", RelationType: "uses"},
+		{From: "Ana", To: "Memory Cloud", RelationType: "maintains"},
+	})
+	return ps
+}
+
+// listAllEntities walks every page of ListEntities.
+func listAllEntities(t *testing.T, ps *ProjectStore, opts ListEntitiesOptions) []string {
+	t.Helper()
+	var names []string
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("Pagination did not terminate")
+		}
+		list, err := ps.ListEntities(opts)
+		if err != nil {
+			t.Fatalf("ListEntities: %v", err)
+		}
+		for _, e := range list.Entities {
+			names = append(names, e.Name)
+		}
+		if list.NextCursor == "" {
+			return names
+		}
+		opts.Cursor = list.NextCursor
+	}
+}
+
+func TestListEntities(t *testing.T) {
+	ps := setupCatalog(t)
+
+	list, err := ps.ListEntities(ListEntitiesOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListEntities: %v", err)
+	}
+	if list.TotalEntities != 5 || len(list.Entities) != 2 || list.NextCursor == "" {
+		t.Fatalf("First page should hold 2 of 5 entities and a cursor, got %+v", list)
+	}
+	if e := list.Entities[0]; e.Name != "Ana" || e.EntityType != "person" || e.ObservationCount != 2 || e.UpdatedAt == "" {
+		t.Errorf("Unexpected first entry %+v", e)
+	}
+
+	if got := strings.Join(listAllEntities(t, ps, ListEntitiesOptions{Limit: 2}), ","); got != "Ana,Go,Gopls,Memory Cloud,SQLite" {
+		t.Errorf("Name order = %s", got)
+	}
+	byObs := listAllEntities(t, ps, ListEntitiesOptions{Sort: SortByObservations, Limit: 2})
+	if got := strings.Join(byObs, ","); got != "Go,Ana,Gopls,SQLite,Memory Cloud" {
+		t.Errorf("Observation order = %s", got)
+	}
+	if byUpdated := listAllEntities(t, ps, ListEntitiesOptions{Sort: SortByUpdated, Limit: 2}); len(byUpdated) != 5 {
+		t.Errorf("Updated order should list all 5 entities once, got %v", byUpdated)
+	}
+
+	prefixed := listAllEntities(t, ps, ListEntitiesOptions{NamePrefix: "Go", EntityTypes: []string{"technology"}})
+	if strings.Join(prefixed, ",") != "Go,Gopls" {
+		t.Errorf("Prefix Go = %v, want [Go Gopls]", prefixed)
+	}
+
+	if _, err := ps.ListEntities(ListEntitiesOptions{Sort: "size"}); err == nil {
+		t.Error("Expected an error for an unknown sort")
+	}
+}
+
+func TestListRelations(t *testing.T) {
+	ps := setupCatalog(t)
+
+	var lines []string
+	opts := ListRelationsOptions{Limit: 3}
+	for {
+		list, err := ps.ListRelations(opts)
+		if err != nil {
+			t.Fatalf("ListRelations: %v", err)
+		}
+		if list.TotalRelations != 4 {
+			t.Errorf("TotalRelations = %d, want 4", list.TotalRelations)
+		}
+		for _, r := range list.Relations {
+			lines = append(lines, r.FromName+" "+r.RelationType+" "+r.ToName)
+		}
+		if list.NextCursor == "" {
+			break
+		}
+		opts.Cursor = list.NextCursor
+	}
+	want := "Ana maintains Memory Cloud|Gopls uses Go|Memory Cloud uses Go|Memory Cloud uses SQLite"
+	if got := strings.Join(lines, "|"); got != want {
+		t.Errorf("Relations = %s, want %s", got, want)
+	}
+
+	list, err := ps.ListRelations(ListRelationsOptions{To: "go", RelationTypes: []string{"uses"}, FromTypes: []string{"project"}})
+	if err != nil {
+		t.Fatalf("ListRelations: %v", err)
+	}
+	if len(list.Relations) != 1 || list.Relations[0].FromName != "Memory Cloud" {
+		t.Errorf("Expected only Memory Cloud uses Go, got %+v", list.Relations)
+	}
+
+	list, _ = ps.ListRelations(ListRelationsOptions{From: "Memory Cloud", ToTypes: []string{"person"}})
+	if list.TotalRelations != 0 || len(list.Relations) != 0 {
+		t.Errorf("Memory Cloud has no relation to a person, got %+v", list.Relations)
+	}
+
+	if _, err := ps.ListRelations(ListRelationsOptions{From: "Nope"}); err == nil {
+		t.Error("Expected an error for an unknown entity")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	ps := setupCatalog(t)
+	// Cascades to one observation and one relation
+	ps.DeleteEntities([]string{"Gopls"})
+
+	desc, err := ps.Describe(2)
+	if err != nil {
+		t.Fatalf("Describe: %v", err)
+	}
+	if desc.Counts.Entities != 4 || desc.Counts.Observations != 6 || desc.Counts.Relations != 3 {
+		t.Errorf("Counts = %+v, want 4 entities, 6 observations, 3 relations", desc.Counts)
+	}
+	if desc.Deleted.Entities != 1 || desc.Deleted.Observations != 1 || desc.Deleted.Relations != 1 {
+		t.Errorf("Deleted = %+v, want 1 of each", desc.Deleted)
+	}
+	if len(desc.EntityTypes) != 3 || desc.EntityTypes[0].Type != "technology" || desc.EntityTypes[0].Count != 2 {
+		t.Errorf("EntityTypes = %+v, want technology (2) first", desc.EntityTypes)
+	}
+	if len(desc.RelationTypes) != 2 || desc.RelationTypes[0].Type != "uses" || desc.RelationTypes[0].Count != 2 {
+		t.Errorf("RelationTypes = %+v, want uses (2) first", desc.RelationTypes)
+	}
+	if len(desc.RecentlyUpdated) != 2 {
+		t.Errorf("Expected 2 recently updated entities, got %d", len(desc.RecentlyUpdated))
+	}
+}
@@ -0,0 +1,380 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+// Roles of the entities in a context pack.
+const (
+	RoleFocus    = "focus"
+	RoleHit      = "hit"
+	RoleNeighbor = "neighbor"
+)
+
+const (
+	// DefaultContextTokens is the budget used when none is given.
+	DefaultContextTokens = 2000
+	// charsPerToken is the average number of characters per token that
+	// EstimateTokens assumes.
+	charsPerToken = 4
+	// contextHits and contextNeighbors cap how many query hits and 1-hop
+	// neighbors are considered before the budget is applied.
+	contextHits      = 10
+	contextNeighbors = 30
+	// keyObservations is how many observations per entity are packed before
+	// relations; the rest only fill leftover budget.
+	keyObservations = 3
+)
+
+// EstimateTokens estimates how many LLM tokens text costs: one token per
+// four characters, rounded up. This is the usual average for BPE tokenizers
+// on English and Portuguese prose; it is deliberately simple, so callers
+// should leave some headroom.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// ContextOptions controls GetContext.
+type ContextOptions struct {
+	// Query is matched with hybrid semantic/FTS search.
+	Query string
+	// Focus names entities that must lead the pack.
+	Focus []string
+	// MaxTokens defaults to DefaultContextTokens.
+	MaxTokens int
+}
+
+// GetContext builds a context pack: focus entities and query hits, their
+// key observations, and their 1-hop neighbors, added greedily in priority
+// order while the estimated size fits MaxTokens. Each entity heading,
+// observation and relation costs EstimateTokens of the markdown that
+// RenderContextMarkdown writes for it; room for the summary line and the
+// not-found line is set aside first. The priorities are:
+//
+//  1. focus entities and hits, by rank
+//  2. their first keyObservations observations, those sharing words with the query first
+//  3. relations to each other and to neighbors, bringing in the neighbor
+//  4. their remaining observations
+//  5. neighbor observations, up to keyObservations each
+//
+// Items that do not fit are skipped and Truncated is set.
+func (p *ProjectStore) GetContext(opts ContextOptions) (*models.ContextPack, error) {
+	if strings.TrimSpace(opts.Query) == "" && len(opts.Focus) == 0 {
+		return nil, invalidf("a query or focus entities are required")
+	}
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = DefaultContextTokens
+	}
+	pack := &models.ContextPack{
+		Query:     opts.Query,
+		MaxTokens: opts.MaxTokens,
+		Entities:  []models.ContextEntity{},
+		Relations: []models.ContextRelation{},
+	}
+
+	// Seeds: focus entities first, then query hits
+	type seed struct {
+		id, role string
+		score    float64
+	}
+	var seeds []seed
+	isSeed := make(map[string]bool)
+	used := 0
+	for _, name := range opts.Focus {
+		id, err := resolveEntity(p.db, name)
+		if err != nil {
+			var nf *EntityNotFoundError
+			if errors.As(err, &nf) {
+				pack.Unresolved = append(pack.Unresolved, name)
+				continue
+			}
+			return nil, err
+		}
+		if !isSeed[id] {
+			isSeed[id] = true
+			seeds = append(seeds, seed{id: id, role: RoleFocus})
+		}
+	}
+	// The summary line is reserved at its longest
+	used += EstimateTokens(contextSummary(len(seeds)+contextHits+contextNeighbors, opts.MaxTokens, opts.MaxTokens, true))
+	if len(pack.Unresolved) > 0 {
+		used += EstimateTokens(contextNotFound(pack.Unresolved))
+	}
+	if strings.TrimSpace(opts.Query) != "" {
+		hits, err := p.SemanticSearch(opts.Query, contextHits, true)
+		if err != nil {
+			return nil, fmt.Errorf("search: %w", err)
+		}
+		for _, h := range hits {
+			if !isSeed[h.ID] {
+				isSeed[h.ID] = true
+				seeds = append(seeds, seed{id: h.ID, role: RoleHit, score: round3(h.Score)})
+			}
+		}
+	}
+	if len(seeds) == 0 {
+		pack.EstimatedTokens = used
+		return pack, nil
+	}
+
+	seedIDs := make([]string, len(seeds))
+	for i, s := range seeds {
+		seedIDs[i] = s.id
+	}
+	relsByEntity, err := p.loadRelations(seedIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Relations of the seeds in seed order, and neighbors ranked by how many
+	// seeds they connect to
+	var relations []models.Relation
+	relSeen := make(map[string]bool)
+	links := make(map[string]int)
+	names := make(map[string]string)
+	for _, id := range seedIDs {
+		for _, r := range relsByEntity[id] {
+			if !relSeen[r.ID] {
+				relSeen[r.ID] = true
+				relations = append(relations, r)
+			}
+			other, otherName := r.ToEntity, r.ToName
+			if other == id {
+				other, otherName = r.FromEntity, r.FromName
+			}
+			if !isSeed[other] {
+				links[other]++
+				names[other] = otherName
+			}
+		}
+	}
+	neighborIDs := make([]string, 0, len(links))
+	for id := range links {
+		neighborIDs = append(neighborIDs, id)
+	}
+	sort.Slice(neighborIDs, func(i, j int) bool {
+		a, b := neighborIDs[i], neighborIDs[j]
+		if links[a] != links[b] {
+			return links[a] > links[b]
+		}
+		return names[a] < names[b]
+	})
+	if len(neighborIDs) > contextNeighbors {
+		neighborIDs = neighborIDs[:contextNeighbors]
+	}
+	isNeighbor := make(map[string]bool, len(neighborIDs))
+	for _, id := range neighborIDs {
+		isNeighbor[id] = true
+	}
+
+	loaded, err := p.getEntitiesByID(append(append([]string{}, seedIDs...), neighborIDs...))
+	if err != nil {
+		return nil, err
+	}
+	entities := make(map[string]models.Entity, len(loaded))
+	for _, e := range loaded {
+		entities[e.ID] = e
+	}
+
+	// Greedy packing
+	fits := func(text string) bool {
+		cost := EstimateTokens(text)
+		if used+cost > opts.MaxTokens {
+			pack.Truncated = true
+			return false
+		}
+		used += cost
+		return true
+	}
+	position := make(map[string]int)
+	addEntity := func(id, role string, score float64) {
+		e := entities[id]
+		position[id] = len(pack.Entities)
+		pack.Entities = append(pack.Entities, models.ContextEntity{
+			Name: e.Name, EntityType: e.EntityType, Role: role, Score: score,
+		})
+	}
+	heading := func(id string) string {
+		e := entities[id]
+		return contextHeading(e.Name, e.EntityType)
+	}
+	// An entity not yet packed is about to join as a neighbor
+	roleOf := func(id string) string {
+		if pos, ok := position[id]; ok {
+			return pack.Entities[pos].Role
+		}
+		return RoleNeighbor
+	}
+	observations := make(map[string][]string)
+	for id, e := range entities {
+		observations[id] = rankObservations(e.Observations, opts.Query)
+	}
+	packObservations := func(id string, from, to int) {
+		pos, ok := position[id]
+		if !ok {
+			return
+		}
+		obs := observations[id]
+		for i := from; i < to && i < len(obs); i++ {
+			if fits(contextObservation(obs[i])) {
+				pack.Entities[pos].Observations = append(pack.Entities[pos].Observations, obs[i])
+			}
+		}
+	}
+
+	for _, s := range seeds {
+		if _, ok := entities[s.id]; ok && fits(heading(s.id)) {
+			addEntity(s.id, s.role, s.score)
+		}
+	}
+	for _, s := range seeds {
+		packObservations(s.id, 0, keyObservations)
+	}
+	for _, r := range relations {
+		_, fromIn := position[r.FromEntity]
+		_, toIn := position[r.ToEntity]
+		if !fromIn && !toIn {
+			continue
+		}
+		rel := models.ContextRelation{From: r.FromName, RelationType: r.RelationType, To: r.ToName}
+		line := contextRelation(rel, listedUnderTarget(roleOf(r.FromEntity), roleOf(r.ToEntity)))
+		missing := r.FromEntity
+		if fromIn {
+			missing = r.ToEntity
+		}
+		if _, in := position[missing]; !in {
+			// Bring in the neighbor with its heading, or skip the relation
+			if !isNeighbor[missing] {
+				continue
+			}
+			if _, ok := entities[missing]; !ok || !fits(heading(missing)+line) {
+				continue
+			}
+			addEntity(missing, RoleNeighbor, 0)
+		} else if !fits(line) {
+			continue
+		}
+		pack.Relations = append(pack.Relations, rel)
+	}
+	for _, s := range seeds {
+		packObservations(s.id, keyObservations, len(observations[s.id]))
+	}
+	for _, id := range neighborIDs {
+		packObservations(id, 0, keyObservations)
+	}
+
+	pack.EstimatedTokens = used
+	return pack, nil
+}
+
+// RenderContextMarkdown renders a context pack as a summary line followed by
+// each entity as a heading with its observations and relations. Each
+// relation is listed once, under its source unless only the target was a
+// match. GetContext budgets packs by exactly these lines.
+func RenderContextMarkdown(pack *models.ContextPack) string {
+	var b strings.Builder
+	b.WriteString(contextSummary(len(pack.Entities), pack.EstimatedTokens, pack.MaxTokens, pack.Truncated))
+
+	roles := make(map[string]string, len(pack.Entities))
+	for _, e := range pack.Entities {
+		roles[e.Name] = e.Role
+	}
+	outgoing := make(map[string][]models.ContextRelation)
+	incoming := make(map[string][]models.ContextRelation)
+	for _, r := range pack.Relations {
+		if listedUnderTarget(roles[r.From], roles[r.To]) {
+			incoming[r.To] = append(incoming[r.To], r)
+		} else {
+			outgoing[r.From] = append(outgoing[r.From], r)
+		}
+	}
+
+	for _, e := range pack.Entities {
+		b.WriteString(contextHeading(e.Name, e.EntityType))
+		for _, o := range e.Observations {
+			b.WriteString(contextObservation(o))
+		}
+		for _, r := range outgoing[e.Name] {
+			b.WriteString(contextRelation(r, false))
+		}
+		for _, r := range incoming[e.Name] {
+			b.WriteString(contextRelation(r, true))
+		}
+	}
+	if len(pack.Unresolved) > 0 {
+		b.WriteString(contextNotFound(pack.Unresolved))
+	}
+	return b.String()
+}
+
+// The lines of a rendered context pack, newlines included.
+
+func contextSummary(entities, tokens, maxTokens int, truncated bool) string {
+	line := fmt.Sprintf("Context: %d entities, ~%d of %d tokens", entities, tokens, maxTokens)
+	if truncated {
+		line += " (trimmed to fit)"
+	}
+	return line + "\n"
+}
+
+func contextHeading(name, entityType string) string {
+	return "\n## " + name + " (" + entityType + ")\n"
+}
+
+func contextObservation(content string) string {
+	return "- " + content + "\n"
+}
+
+// contextRelation renders r under its source, or under its target when
+// underTarget is set.
+func contextRelation(r models.ContextRelation, underTarget bool) string {
+	if underTarget {
+		return "← " + r.RelationType + " ← " + r.From + "\n"
+	}
+	return "→ " + r.RelationType + " → " + r.To + "\n"
+}
+
+func contextNotFound(names []string) string {
+	return "\nNot found: " + strings.Join(names, ", ") + "\n"
+}
+
+// listedUnderTarget reports whether a relation between entities with these
+// roles is listed under its target: when it leads from a neighbor to a
+// focus entity or hit.
+func listedUnderTarget(fromRole, toRole string) bool {
+	return fromRole == RoleNeighbor && toRole != RoleNeighbor
+}
+
+// rankObservations orders observation texts by how many words they share
+// with the query, keeping the stored order among ties.
+func rankObservations(obs []models.Observation, query string) []string {
+	terms := make(map[string]bool)
+	for _, t := range tokenize(query) {
+		terms[t] = true
+	}
+	type scored struct {
+		text  string
+		score int
+	}
+	ranked := make([]scored, len(obs))
+	for i, o := range obs {
+		ranked[i].text = o.Content
+		for _, t := range tokenize(o.Content) {
+			if terms[t] {
+				ranked[i].score++
+			}
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+	texts := make([]string, len(ranked))
+	for i, r := range ranked {
+		texts[i] = r.text
+	}
+	return texts
+}
@@ -0,0 +1,120 @@
+package storage
+
+import "testing"
+
+func setupContextGraph(t *testing.T) *ProjectStore {
+	t.Helper()
+	ps := setupProjectStore(t)
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{
+		{Name: "Memory Cloud", EntityType: "project", Observations: []string{"Servidor MCP de memória", "Roda em uma VPS", "Um banco SQLite por projeto", "Autenticação via OAuth"}},
+		{Name: "Go", EntityType: "technology", Observations: []string{"Linguagem compilada", "Criada no Google"}},
+		{Name: "Ana", EntityType: "person", Observations: []string{"Mantenedora principal"}},
+		{Name: "Caddy", EntityType: "technology", Observations: []string{"Proxy reverso"}},
+	})
+	ps.CreateRelations([]struct {
+		From         string
+		To           string
+		RelationType string
+	}{
+		{From: "Memory Cloud", To: "Go", RelationType: "built_with"},
+		{From: "Ana", To: "Memory Cloud", RelationType: "maintains"},
+		{From: "Caddy", To: "Go", RelationType: "built_with"},
+	})
+	return ps
+}
+
+func TestGetContextFocus(t *testing.T) {
+	ps := setupContextGraph(t)
+
+	pack, err := ps.GetContext(ContextOptions{Focus: []string{"memory cloud", "Nope"}, MaxTokens: 1000})
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+	if len(pack.Entities) != 3 || pack.Entities[0].Name != "Memory Cloud" || pack.Entities[0].Role != RoleFocus {
+		t.Fatalf("Expected Memory Cloud then its 2 neighbors, got %+v", pack.Entities)
+	}
+	for _, e := range pack.Entities[1:] {
+		if e.Role != RoleNeighbor || e.Name == "Caddy" {
+			t.Errorf("Unexpected neighbor %+v (Caddy is 2 hops away)", e)
+		}
+	}
+	if len(pack.Relations) != 2 || pack.Truncated {
+		t.Errorf("Expected 2 relations and no truncation, got %+v (truncated %v)", pack.Relations, pack.Truncated)
+	}
+	if len(pack.Entities[0].Observations) != 4 {
+		t.Errorf("Focus entity should keep all observations with room to spare, got %v", pack.Entities[0].Observations)
+	}
+	if len(pack.Unresolved) != 1 || pack.Unresolved[0] != "Nope" {
+		t.Errorf("Unresolved = %v, want [Nope]", pack.Unresolved)
+	}
+	if pack.EstimatedTokens <= 0 || pack.EstimatedTokens > pack.MaxTokens {
+		t.Errorf("EstimatedTokens = %d, want within (0, %d]", pack.EstimatedTokens, pack.MaxTokens)
+	}
+}
+
+func TestGetContextBudget(t *testing.T) {
+	ps := setupContextGraph(t)
+
+	// Room for the heading, the key observations and little else
+	pack, err := ps.GetContext(ContextOptions{Focus: []string{"Memory Cloud"}, MaxTokens: 30})
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+	if !pack.Truncated || pack.EstimatedTokens > 30 {
+		t.Errorf("Pack should be trimmed to 30 tokens, got %d (truncated %v)", pack.EstimatedTokens, pack.Truncated)
+	}
+	if len(pack.Entities) == 0 || pack.Entities[0].Name != "Memory Cloud" {
+		t.Fatalf("The focus entity should survive trimming, got %+v", pack.Entities)
+	}
+	if n := len(pack.Entities[0].Observations); n == 0 || n > keyObservations {
+		t.Errorf("Expected between 1 and %d key observations, got %d", keyObservations, n)
+	}
+}
+
+func TestGetContextQuery(t *testing.T) {
+	ps := setupContextGraph(t)
+
+	pack, err := ps.GetContext(ContextOptions{Query: "proxy reverso"})
+	if err != nil {
+		t.Fatalf("GetContext: %v", err)
+	}
+	if len(pack.Entities) == 0 || pack.Entities[0].Name != "Caddy" || pack.Entities[0].Role != RoleHit {
+		t.Fatalf("Caddy should be the top hit, got %+v", pack.Entities)
+	}
+
+	if _, err := ps.GetContext(ContextOptions{}); err == nil {
+		t.Error("Expected an error without query or focus")
+	}
+}
+
+func TestGetContextRenderedBudget(t *testing.T) {
+	ps := setupContextGraph(t)
+
+	for _, maxTokens := range []int{25, 40, 60, 100, 1000} {
+		for _, opts := range []ContextOptions{
+			{Focus: []string{"Memory Cloud", "Nope"}, MaxTokens: maxTokens},
+			{Query: "go proxy", Focus: []string{"Ana"}, MaxTokens: maxTokens},
+		} {
+			pack, err := ps.GetContext(opts)
+			if err != nil {
+				t.Fatalf("GetContext: %v", err)
+			}
+			md := RenderContextMarkdown(pack)
+			if got := EstimateTokens(md); got > maxTokens || got > pack.EstimatedTokens {
+				t.Errorf("Rendered pack costs %d tokens, over max_tokens %d or the estimate %d:\n%s", got, maxTokens, pack.EstimatedTokens, md)
+			}
+		}
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	for text, want := range map[string]int{"": 0, "abcd": 1, "abcde": 2, "ação": 1} {
+		if got := EstimateTokens(text); got != want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+const (
+	// Weights of the duplicate score components; they sum to 1.
+	dupNameWeight        = 0.6
+	dupObservationWeight = 0.2
+	dupNeighborWeight    = 0.2
+	// dupCommonTokenLimit skips name tokens shared by more entities than this
+	// when generating candidate pairs ("adr", "bug", ...), so candidate
+	// generation stays close to linear on large projects.
+	dupCommonTokenLimit = 50
+)
+
+// dupEntity is the per-entity data needed to score duplicate pairs.
+type dupEntity struct {
+	id           string
+	name         string
+	entityType   string
+	tokens       map[string]bool
+	observations map[string]bool
+	neighbors    map[string]bool
+}
+
+// FindDuplicates scores pairs of active entities that may describe the same
+// thing, by name similarity, shared observations and shared neighbors.
+// Only pairs sharing a name token, an observation or a neighbor are scored.
+// entityType restricts both sides of a pair to that type when non-empty.
+// Pairs scoring below minScore are dropped; at most limit pairs are returned.
+func (p *ProjectStore) FindDuplicates(entityType string, minScore float64, limit int) ([]models.DuplicateCandidate, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	query := `SELECT id, name, entity_type FROM entities WHERE deleted_at IS NULL`
+	var args []any
+	if entityType != "" {
+		query += ` AND entity_type = ?`
+		args = append(args, entityType)
+	}
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query entities: %w", err)
+	}
+	byID := make(map[string]*dupEntity)
+	var ents []*dupEntity
+	for rows.Next() {
+		e := &dupEntity{tokens: map[string]bool{}, observations: map[string]bool{}, neighbors: map[string]bool{}}
+		if err := rows.Scan(&e.id, &e.name, &e.entityType); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan entity: %w", err)
+		}
+		for _, tok := range tokenize(e.name) {
+			e.tokens[tok] = true
+		}
+		byID[e.id] = e
+		ents = append(ents, e)
+	}
+	rows.Close()
+
+	obsRows, err := p.db.Query(`SELECT entity_id, content FROM observations WHERE deleted_at IS NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("query observations: %w", err)
+	}
+	for obsRows.Next() {
+		var entityID, content string
+		if err := obsRows.Scan(&entityID, &content); err != nil {
+			obsRows.Close()
+			return nil, fmt.Errorf("scan observation: %w", err)
+		}
+		if e := byID[entityID]; e != nil {
+			e.observations[foldText(content)] = true
+		}
+	}
+	obsRows.Close()
+
+	relRows, err := p.db.Query(`SELECT from_entity, to_entity FROM relations WHERE deleted_at IS NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("query relations: %w", err)
+	}
+	for relRows.Next() {
+		var from, to string
+		if err := relRows.Scan(&from, &to); err != nil {
+			relRows.Close()
+			return nil, fmt.Errorf("scan relation: %w", err)
+		}
+		if e := byID[from]; e != nil {
+			e.neighbors[to] = true
+		}
+		if e := byID[to]; e != nil {
+			e.neighbors[from] = true
+		}
+	}
+	relRows.Close()
+
+	// Candidate generation via inverted indexes on name tokens, observations
+	// and neighbors
+	type pairKey struct{ a, b string }
+	pairs := make(map[pairKey]bool)
+	addGroup := func(group []*dupEntity) {
+		if len(group) > dupCommonTokenLimit {
+			return
+		}
+		for i := 0; i < len(group); i++ {
+			for j := i + 1; j < len(group); j++ {
+				a, b := group[i].id, group[j].id
+				if a > b {
+					a, b = b, a
+				}
+				pairs[pairKey{a, b}] = true
+			}
+		}
+	}
+	tokenIndex := make(map[string][]*dupEntity)
+	obsIndex := make(map[string][]*dupEntity)
+	neighborIndex := make(map[string][]*dupEntity)
+	for _, e := range ents {
+		for tok := range e.tokens {
+			if len([]rune(tok)) > 2 {
+				tokenIndex[tok] = append(tokenIndex[tok], e)
+			}
+		}
+		for o := range e.observations {
+			obsIndex[o] = append(obsIndex[o], e)
+		}
+		for n := range e.neighbors {
+			neighborIndex[n] = append(neighborIndex[n], e)
+		}
+	}
+	for _, idx := range []map[string][]*dupEntity{tokenIndex, obsIndex, neighborIndex} {
+		for _, group := range idx {
+			addGroup(group)
+		}
+	}
+
+	var candidates []models.DuplicateCandidate
+	for pk := range pairs {
+		a, b := byID[pk.a], byID[pk.b]
+		nameScore := max(nameSimilarity(a.name, b.name), 0.9*containment(a.tokens, b.tokens))
+		obsScore := jaccard(a.observations, b.observations)
+		// The pair's own mutual relation is not evidence of duplication
+		neighborScore := jaccardExcluding(a.neighbors, b.neighbors, a.id, b.id)
+		score := dupNameWeight*nameScore + dupObservationWeight*obsScore + dupNeighborWeight*neighborScore
+		if score < minScore {
+			continue
+		}
+		first, second := a, b
+		if first.name > second.name {
+			first, second = second, first
+		}
+		candidates = append(candidates, models.DuplicateCandidate{
+			EntityA:          first.name,
+			EntityB:          second.name,
+			EntityTypeA:      first.entityType,
+			EntityTypeB:      second.entityType,
+			Score:            round3(score),
+			NameScore:        round3(nameScore),
+			ObservationScore: round3(obsScore),
+			NeighborScore:    round3(neighborScore),
+		})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		if candidates[i].EntityA != candidates[j].EntityA {
+			return candidates[i].EntityA < candidates[j].EntityA
+		}
+		return candidates[i].EntityB < candidates[j].EntityB
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
+
+// MergeEntities folds the source entities into target: observations,
+// relations and the properties the target does not have are moved onto the
+// target, duplicate observations (same content) and relations (same
+// endpoints and type, overlapping validity) are soft-deleted, relations
+// between the merged entities are dropped, the sources are soft-deleted and
+// their names are recorded as aliases of the target. Sources are resolved by
+// exact name or alias only (see resolveTarget). Everything runs in one
+// transaction.
+//
+// expectedVersions maps target or source names, as given, to the version
+// the client last read; the merge fails with a *VersionConflictError if one
+// of them is at another version.
+func (p *ProjectStore) MergeEntities(target string, sources []string, expectedVersions map[string]int64) (*models.MergeResult, error) {
+	tx, err := p.begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	targetID, err := resolveEntity(tx, target)
+	if err != nil {
+		return nil, fmt.Errorf("target: %w", err)
+	}
+	if err := checkVersion(tx, targetID, target, expectedVersions[target]); err != nil {
+		return nil, err
+	}
+	var targetName string
+	if err := tx.QueryRow(`SELECT name FROM entities WHERE id = ?`, targetID).Scan(&targetName); err != nil {
+		return nil, fmt.Errorf("load target: %w", err)
+	}
+
+	result := &models.MergeResult{Target: targetName, Merged: []string{}}
+	seen := map[string]bool{targetID: true}
+
+	for _, source := range sources {
+		sourceID, err := resolveTarget(tx, source)
+		if err != nil {
+			return nil, fmt.Errorf("source: %w", err)
+		}
+		if err := checkVersion(tx, sourceID, source, expectedVersions[source]); err != nil {
+			return nil, err
+		}
+		if seen[sourceID] {
+			continue
+		}
+		seen[sourceID] = true
+
+		var sourceName string
+		if err := tx.QueryRow(`SELECT name FROM entities WHERE id = ?`, sourceID).Scan(&sourceName); err != nil {
+			return nil, fmt.Errorf("load source: %w", err)
+		}
+
+		res, err := tx.Exec(
+			`UPDATE observations SET entity_id = ? WHERE entity_id = ? AND deleted_at IS NULL`,
+			targetID, sourceID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("move observations: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		result.ObservationsMoved += n
+
+		// Relations between source and target would become self-loops
+		_, err = tx.Exec(
+			`UPDATE relations SET deleted_at = datetime('now')
+			 WHERE ((from_entity = ?1 AND to_entity = ?2) OR (from_entity = ?2 AND to_entity = ?1))
+			   AND deleted_at IS NULL`,
+			sourceID, targetID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("drop relations between merged entities: %w", err)
+		}
+
+		var moved int64
+		for _, col := range []string{"from_entity", "to_entity"} {
+			res, err := tx.Exec(
+				fmt.Sprintf(`UPDATE relations SET %s = ? WHERE %s = ? AND deleted_at IS NULL`, col, col),
+				targetID, sourceID,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("move relations: %w", err)
+			}
+			n, _ := res.RowsAffected()
+			moved += n
+		}
+		result.RelationsMoved += moved
+
+		// The target keeps its own value of a property both have. A deleted
+		// property of the target gives way to the source's live one.
+		if _, err := tx.Exec(
+			`DELETE FROM entity_properties
+			 WHERE entity_id = ?1 AND deleted_at IS NOT NULL
+			   AND key IN (SELECT key FROM entity_properties WHERE entity_id = ?2 AND deleted_at IS NULL)`,
+			targetID, sourceID,
+		); err != nil {
+			return nil, fmt.Errorf("drop deleted properties: %w", err)
+		}
+		res, err = tx.Exec(
+			`UPDATE entity_properties SET entity_id = ?1
+			 WHERE entity_id = ?2 AND deleted_at IS NULL
+			   AND key NOT IN (SELECT key FROM entity_properties WHERE entity_id = ?1 AND deleted_at IS NULL)`,
+			targetID, sourceID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("move properties: %w", err)
+		}
+		n, _ = res.RowsAffected()
+		result.PropertiesMoved += n
+
+		// Keep vectors of moved observations; the source's own vector goes away
+		if _, err := tx.Exec(`UPDATE embeddings SET entity_id = ? WHERE entity_id = ? AND observation_id != ''`, targetID, sourceID); err != nil {
+			return nil, fmt.Errorf("move embeddings: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM embeddings WHERE entity_id = ?`, sourceID); err != nil {
+			return nil, fmt.Errorf("delete source embedding: %w", err)
+		}
+
+		if _, err := tx.Exec(`UPDATE OR IGNORE entity_aliases SET entity_id = ? WHERE entity_id = ?`, targetID, sourceID); err != nil {
+			return nil, fmt.Errorf("move aliases: %w", err)
+		}
+		if sourceName != targetName {
+			if _, err := tx.Exec(`INSERT OR IGNORE INTO entity_aliases (alias, entity_id) VALUES (?, ?)`, sourceName, targetID); err != nil {
+				return nil, fmt.Errorf("record alias: %w", err)
+			}
+		}
+
+		if _, err := tx.Exec(
+			`UPDATE entities SET deleted_at = datetime('now'), updated_at = datetime('now') WHERE id = ?`, sourceID,
+		); err != nil {
+			return nil, fmt.Errorf("soft-delete source: %w", err)
+		}
+		result.Merged = append(result.Merged, sourceName)
+	}
+
+	// Deduplicate: keep the oldest observation per content and the oldest
+	// relation per (from, to, type) and overlapping validity period
+	res, err := tx.Exec(
+		`UPDATE observations SET deleted_at = datetime('now')
+		 WHERE entity_id = ?1 AND deleted_at IS NULL AND id IN (
+		     SELECT id FROM (
+		         SELECT id, ROW_NUMBER() OVER (PARTITION BY content ORDER BY created_at, rowid) AS rn
+		         FROM observations WHERE entity_id = ?1 AND deleted_at IS NULL
+		     ) WHERE rn > 1
+		 )`,
+		targetID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("deduplicate observations: %w", err)
+	}
+	result.DuplicateObservationsRemoved, _ = res.RowsAffected()
+
+	res, err = tx.Exec(
+		`UPDATE relations SET deleted_at = datetime('now')
+		 WHERE deleted_at IS NULL AND id IN (
+		     SELECT r.id FROM relations r
+		     WHERE (r.from_entity = ?1 OR r.to_entity = ?1) AND r.deleted_at IS NULL AND `+overlapsEarlierRelation+`
+		 )`,
+		targetID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("deduplicate relations: %w", err)
+	}
+	result.DuplicateRelationsRemoved, _ = res.RowsAffected()
+
+	if _, err := tx.Exec(`UPDATE entities SET updated_at = datetime('now') WHERE id = ?`, targetID); err != nil {
+		return nil, fmt.Errorf("touch target: %w", err)
+	}
+
+	aliasRows, err := tx.Query(`SELECT alias FROM entity_aliases WHERE entity_id = ? ORDER BY alias`, targetID)
+	if err != nil {
+		return nil, fmt.Errorf("query aliases: %w", err)
+	}
+	result.Aliases = []string{}
+	for aliasRows.Next() {
+		var a string
+		aliasRows.Scan(&a)
+		result.Aliases = append(result.Aliases, a)
+	}
+	aliasRows.Close()
+
+	if err := p.commit(tx); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return result, nil
+}
+
+// --- Set similarity helpers ---
+
+func jaccard(a, b map[string]bool) float64 {
+	return jaccardExcluding(a, b)
+}
+
+// jaccardExcluding is the Jaccard index of a and b ignoring the given keys.
+func jaccardExcluding(a, b map[string]bool, exclude ...string) float64 {
+	skip := make(map[string]bool, len(exclude))
+	for _, x := range exclude {
+		skip[x] = true
+	}
+	var union, shared int
+	for k := range a {
+		if skip[k] {
+			continue
+		}
+		union++
+		if b[k] {
+			shared++
+		}
+	}
+	for k := range b {
+		if !skip[k] && !a[k] {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// containment is the share of the smaller set contained in the larger one.
+func containment(a, b map[string]bool) float64 {
+	small, large := a, b
+	if len(small) > len(large) {
+		small, large = large, small
+	}
+	if len(small) == 0 {
+		return 0
+	}
+	shared := 0
+	for k := range small {
+		if large[k] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(small))
+}
+
+func round3(x float64) float64 {
+	return float64(int64(x*1000+0.5)) / 1000
+}
@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{
+		{Name: "Caddy", EntityType: "infrastructure", Observations: []string{"Reverse proxy"}},
+		{Name: "Caddy Server", EntityType: "infrastructure", Observations: []string{"Reverse proxy", "TLS automático"}},
+		{Name: "PostgreSQL", EntityType: "technology"},
+		{Name: "VPS Hetzner", EntityType: "infrastructure"},
+	})
+	ps.CreateRelations([]struct {
+		From         string
+		To           string
+		RelationType string
+	}{
+		{From: "VPS Hetzner", To: "Caddy", RelationType: "hosts"},
+		{From: "VPS Hetzner", To: "Caddy Server", RelationType: "hosts"},
+	})
+
+	candidates, err := ps.FindDuplicates("", 0.5, 10)
+	if err != nil {
+		t.Fatalf("FindDuplicates: %v", err)
+	}
+	if len(candidates) != 1 {
+		t.Fatalf("Expected 1 candidate pair, got %+v", candidates)
+	}
+	c := candidates[0]
+	if c.EntityA != "Caddy" || c.EntityB != "Caddy Server" {
+		t.Errorf("Unexpected pair %q / %q", c.EntityA, c.EntityB)
+	}
+	if c.ObservationScore != 0.5 || c.NeighborScore != 1 {
+		t.Errorf("observation_score = %v, neighbor_score = %v; want 0.5 and 1", c.ObservationScore, c.NeighborScore)
+	}
+
+	// Type filter excludes the pair
+	candidates, _ = ps.FindDuplicates("technology", 0.5, 10)
+	if len(candidates) != 0 {
+		t.Errorf("Expected no technology duplicates, got %+v", candidates)
+	}
+}
+
+func TestMergeEntities(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{
+		{Name: "ADR: RabbitMQ", EntityType: "decision", Observations: []string{"Escolhido em 2026-01"}},
+		{Name: "ADR: RabbitMQ como message broker", EntityType: "decision", Observations: []string{"Escolhido em 2026-01", "Kafka rejeitado"}},
+		{Name: "João Silva", EntityType: "person"},
+		{Name: "Migração", EntityType: "project"},
+	})
+	ps.CreateRelations([]struct {
+		From         string
+		To           string
+		RelationType string
+	}{
+		{From: "João Silva", To: "ADR: RabbitMQ", RelationType: "approved"},
+		{From: "João Silva", To: "ADR: RabbitMQ como message broker", RelationType: "approved"},
+		{From: "ADR: RabbitMQ como message broker", To: "Migração", RelationType: "affects"},
+		{From: "ADR: RabbitMQ como message broker", To: "ADR: RabbitMQ", RelationType: "replaces"},
+	})
+	ps.ApplyBatch([]BatchOp{
+		{Op: OpSetProperty, Entity: "ADR: RabbitMQ", Key: "status", Value: "accepted"},
+		{Op: OpSetProperty, Entity: "ADR: RabbitMQ como message broker", Key: "status", Value: "proposed"},
+		{Op: OpSetProperty, Entity: "ADR: RabbitMQ como message broker", Key: "owner", Value: "João Silva"},
+	})
+
+	result, err := ps.MergeEntities("ADR: RabbitMQ", []string{"ADR: RabbitMQ como message broker"}, nil)
+	if err != nil {
+		t.Fatalf("MergeEntities: %v", err)
+	}
+	if result.ObservationsMoved != 2 || result.DuplicateObservationsRemoved != 1 {
+		t.Errorf("moved %d / removed %d observations, want 2 / 1", result.ObservationsMoved, result.DuplicateObservationsRemoved)
+	}
+	if result.RelationsMoved != 2 || result.DuplicateRelationsRemoved != 1 {
+		t.Errorf("moved %d / removed %d relations, want 2 / 1", result.RelationsMoved, result.DuplicateRelationsRemoved)
+	}
+
+	entities, _ := ps.GetEntities([]string{"ADR: RabbitMQ"})
+	if len(entities) != 1 {
+		t.Fatal("Target should still exist")
+	}
+	target := entities[0]
+	if len(target.Observations) != 2 {
+		t.Errorf("Expected 2 unique observations on target, got %d", len(target.Observations))
+	}
+	// approved (deduplicated) + affects; the replaces self-loop is dropped
+	if len(target.Outgoing) != 1 || len(target.Incoming) != 1 {
+		t.Errorf("Expected 1 outgoing and 1 incoming relation on target, got %+v / %+v", target.Outgoing, target.Incoming)
+	}
+	if result.PropertiesMoved != 1 || target.Properties["status"] != "accepted" || target.Properties["owner"] != "João Silva" {
+		t.Errorf("Expected the owner property moved and the target's status kept, got %d %v", result.PropertiesMoved, target.Properties)
+	}
+	if len(target.Aliases) != 1 || target.Aliases[0] != "ADR: RabbitMQ como message broker" {
+		t.Errorf("Expected source name as alias, got %v", target.Aliases)
+	}
+
+	// The old name now resolves to the target
+	obs, err := ps.AddObservations("ADR: RabbitMQ como message broker", []string{"Revisado em 2026-03"})
+	if err != nil {
+		t.Fatalf("AddObservations via alias: %v", err)
+	}
+	if obs[0].EntityID != target.ID {
+		t.Error("Alias should resolve to the merge target")
+	}
+
+	graph, _ := ps.ReadGraph()
+	if len(graph.Entities) != 3 {
+		t.Errorf("Source should be soft-deleted, graph has %d entities", len(graph.Entities))
+	}
+}
+
+func TestMergeEntitiesRelationPeriods(t *testing.T) {
+	ps := setupProjectStore(t)
+	_, err := ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Acme", EntityType: "organization"},
+		{Op: OpCreateEntity, Entity: "Acme Corp", EntityType: "organization"},
+		{Op: OpCreateEntity, Entity: "Memory Cloud", EntityType: "project"},
+		{Op: OpCreateRelation, From: "Acme", To: "Memory Cloud", RelationType: "sponsors",
+			Validity: models.Validity{ValidFrom: "2024-01-01", ValidUntil: "2025-01-01"}},
+		{Op: OpCreateRelation, From: "Acme Corp", To: "Memory Cloud", RelationType: "sponsors",
+			Validity: models.Validity{ValidFrom: "2025-01-01"}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+
+	result, err := ps.MergeEntities("Acme", []string{"Acme Corp"}, nil)
+	if err != nil {
+		t.Fatalf("MergeEntities: %v", err)
+	}
+	if result.RelationsMoved != 1 || result.DuplicateRelationsRemoved != 0 {
+		t.Errorf("moved %d / removed %d relations, want 1 / 0", result.RelationsMoved, result.DuplicateRelationsRemoved)
+	}
+	history, _ := ps.At("", true)
+	entities, _ := history.GetEntities([]string{"Acme"})
+	if len(entities) != 1 || len(entities[0].Outgoing) != 2 {
+		t.Errorf("Both sponsorship periods should be kept, got %+v", entities)
+	}
+}
+
+func TestMergeEntitiesExpectedVersion(t *testing.T) {
+	ps := setupProjectStore(t)
+	ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Caddy", EntityType: "infrastructure"},
+		{Op: OpCreateEntity, Entity: "Caddy Server", EntityType: "infrastructure", Observations: []string{"Reverse proxy"}},
+	})
+
+	// Caddy Server is at version 2 after its observation
+	_, err := ps.MergeEntities("Caddy", []string{"Caddy Server"}, map[string]int64{"Caddy": 1, "Caddy Server": 1})
+	var vc *VersionConflictError
+	if !errors.As(err, &vc) || vc.Entity != "Caddy Server" || vc.CurrentVersion != 2 {
+		t.Fatalf("Expected a version conflict on Caddy Server, got %v", err)
+	}
+	if entities, _ := ps.GetEntities([]string{"Caddy Server"}); len(entities) != 1 {
+		t.Fatal("A conflicting merge must not apply")
+	}
+	if _, err := ps.MergeEntities("Caddy", []string{"Caddy Server"}, map[string]int64{"Caddy": 1, "Caddy Server": 2}); err != nil {
+		t.Errorf("MergeEntities with current versions: %v", err)
+	}
+}
+
+func TestMergeEntitiesDeletedTargetProperty(t *testing.T) {
+	ps := setupProjectStore(t)
+	_, err := ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Caddy", EntityType: "infrastructure"},
+		{Op: OpCreateEntity, Entity: "Caddy Server", EntityType: "infrastructure"},
+		{Op: OpSetProperty, Entity: "Caddy", Key: "port", Value: 80},
+		{Op: OpSetProperty, Entity: "Caddy", Key: "port"},
+		{Op: OpSetProperty, Entity: "Caddy Server", Key: "port", Value: 443},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+
+	result, err := ps.MergeEntities("Caddy", []string{"Caddy Server"}, nil)
+	if err != nil {
+		t.Fatalf("MergeEntities: %v", err)
+	}
+	entities, _ := ps.GetEntities([]string{"Caddy"})
+	if result.PropertiesMoved != 1 || len(entities) != 1 || entities[0].Properties["port"] != float64(443) {
+		t.Errorf("Expected the source's port moved over the deleted one, got %d %+v", result.PropertiesMoved, entities)
+	}
+}
+
+func TestMergeEntitiesUnknownSource(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{{Name: "Caddy", EntityType: "infrastructure"}})
+
+	if _, err := ps.MergeEntities("Caddy", []string{"Nginx"}, nil); err == nil {
+		t.Error("Expected error for unknown source")
+	}
+
+	// A near miss is not merged
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{{Name: "Caddy Server", EntityType: "infrastructure"}})
+	_, err := ps.MergeEntities("Caddy", []string{"Caddy Servers"}, nil)
+	var nf *EntityNotFoundError
+	if !errors.As(err, &nf) || len(nf.Suggestions) == 0 || nf.Suggestions[0] != "Caddy Server" {
+		t.Errorf("Expected not-found suggesting Caddy Server, got %v", err)
+	}
+	if entities, _ := ps.GetEntities([]string{"Caddy Server"}); len(entities) != 1 {
+		t.Error("Caddy Server should not be merged")
+	}
+}
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+// Change kinds and actions reported by a dry run.
+const (
+	ChangeEntity      = "entity"
+	ChangeObservation = "observation"
+	ChangeRelation    = "relation"
+	ChangeProperty    = "property"
+
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+// dryRunLog creates a temporary log table and triggers that record every
+// row inserted or updated in entities, observations, relations and entity
+// properties, with a description of updated rows as they were. Entity
+// version bumps alone are not logged. Temporary objects belong to the
+// transaction's connection and vanish with the rollback.
+const dryRunLog = `
+CREATE TEMP TABLE dry_run_log (
+    seq     INTEGER PRIMARY KEY,
+    kind    TEXT NOT NULL,
+    row_id  TEXT NOT NULL,
+    action  TEXT NOT NULL,
+    before  TEXT NOT NULL DEFAULT ''
+);
+CREATE TEMP TRIGGER dry_run_entities_ai AFTER INSERT ON main.entities BEGIN
+    INSERT INTO dry_run_log (kind, row_id, action) VALUES ('entity', new.id, 'created');
+END;
+CREATE TEMP TRIGGER dry_run_entities_au AFTER UPDATE OF name, entity_type, deleted_at ON main.entities BEGIN
+    INSERT INTO dry_run_log (kind, row_id, action, before) VALUES ('entity', new.id,
+        CASE WHEN old.deleted_at IS NULL AND new.deleted_at IS NOT NULL THEN 'deleted' ELSE 'updated' END,
+        old.name || ' (' || old.entity_type || ')');
+END;
+CREATE TEMP TRIGGER dry_run_observations_ai AFTER INSERT ON main.observations BEGIN
+    INSERT INTO dry_run_log (kind, row_id, action) VALUES ('observation', new.id, 'created');
+END;
+CREATE TEMP TRIGGER dry_run_observations_au AFTER UPDATE ON main.observations BEGIN
+    INSERT INTO dry_run_log (kind, row_id, action, before) VALUES ('observation', new.id,
+        CASE WHEN old.deleted_at IS NULL AND new.deleted_at IS NOT NULL THEN 'deleted' ELSE 'updated' END,
+        (SELECT name FROM main.entities WHERE id = old.entity_id) || ': ' || old.content);
+END;
+CREATE TEMP TRIGGER dry_run_relations_ai AFTER INSERT ON main.relations BEGIN
+    INSERT INTO dry_run_log (kind, row_id, action) VALUES ('relation', new.id, 'created');
+END;
+CREATE TEMP TRIGGER dry_run_relations_au AFTER UPDATE ON main.relations BEGIN
+    INSERT INTO dry_run_log (kind, row_id, action, before) VALUES ('relation', new.id,
+        CASE WHEN old.deleted_at IS NULL AND new.deleted_at IS NOT NULL THEN 'deleted' ELSE 'updated' END,
+        (SELECT name FROM main.entities WHERE id = old.from_entity) || ' —' || old.relation_type || '→ ' ||
+        (SELECT name FROM main.entities WHERE id = old.to_entity));
+END;
+CREATE TEMP TRIGGER dry_run_properties_ai AFTER INSERT ON main.entity_properties BEGIN
+    INSERT INTO dry_run_log (kind, row_id, action) VALUES ('property', new.id, 'created');
+END;
+CREATE TEMP TRIGGER dry_run_properties_au AFTER UPDATE ON main.entity_properties BEGIN
+    INSERT INTO dry_run_log (kind, row_id, action, before) VALUES ('property', new.id,
+        CASE WHEN old.deleted_at IS NULL AND new.deleted_at IS NOT NULL THEN 'deleted'
+             WHEN old.deleted_at IS NOT NULL AND new.deleted_at IS NULL THEN 'created'
+             ELSE 'updated' END,
+        (SELECT name FROM main.entities WHERE id = old.entity_id) || ': ' || old.key || ' = ' || old.value);
+END;
+`
+
+// DryRun returns a view of the store whose writes run in full but are rolled
+// back instead of committed, and are not indexed for semantic search. After
+// a write, Changes reports every entity, observation, relation and property
+// it would have created, updated or soft-deleted, cascades included. Changes
+// of several writes accumulate, though each write starts from the committed
+// state. Reads go to the underlying store.
+func (p *ProjectStore) DryRun() *ProjectStore {
+	return &ProjectStore{
+		db:         p.db,
+		embedder:   p.embedder,
+		indexer:    p.indexer,
+		limits:     p.limits,
+		base:       p,
+		changes:    &models.ChangeReport{DryRun: true, Changes: []models.Change{}, Counts: map[string]int{}},
+		validAt:    p.validAt,
+		historical: p.historical,
+	}
+}
+
+// Changes returns the changes recorded by a dry-run view, or nil for a
+// regular store.
+func (p *ProjectStore) Changes() *models.ChangeReport {
+	return p.changes
+}
+
+// begin starts a write transaction. In a dry run it also installs the change
+// log.
+func (p *ProjectStore) begin() (*sql.Tx, error) {
+	tx, err := p.db.Begin()
+	if err != nil || p.changes == nil {
+		return tx, err
+	}
+	if _, err := tx.Exec(dryRunLog); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("install dry-run log: %w", err)
+	}
+	return tx, nil
+}
+
+// commit commits a write transaction. In a dry run it records the logged
+// changes instead and leaves the transaction to the caller's deferred
+// rollback.
+func (p *ProjectStore) commit(tx *sql.Tx) error {
+	if p.changes == nil {
+		return tx.Commit()
+	}
+	return p.collectChanges(tx)
+}
+
+// collectChanges folds the change log into one change per row: a row created
+// and then deleted is dropped, a row created and then updated is reported as
+// created, and an updated row keeps its description from before the first
+// update.
+func (p *ProjectStore) collectChanges(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT kind, row_id, action, before FROM dry_run_log ORDER BY seq`)
+	if err != nil {
+		return fmt.Errorf("read dry-run log: %w", err)
+	}
+	type key struct{ kind, id string }
+	var order []key
+	changes := make(map[key]*models.Change)
+	for rows.Next() {
+		var c models.Change
+		if err := rows.Scan(&c.Kind, &c.ID, &c.Action, &c.Before); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan dry-run log: %w", err)
+		}
+		k := key{c.Kind, c.ID}
+		prev, ok := changes[k]
+		switch {
+		case !ok:
+			order = append(order, k)
+			changes[k] = &c
+		case prev.Action == ChangeCreated && c.Action == ChangeDeleted:
+			delete(changes, k)
+		case prev.Action != ChangeCreated && c.Action == ChangeDeleted:
+			prev.Action = ChangeDeleted
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, k := range order {
+		c, ok := changes[k]
+		if !ok {
+			continue
+		}
+		if err := describeChange(tx, c); err != nil {
+			return err
+		}
+		if c.Action != ChangeUpdated {
+			c.Before = ""
+		}
+		p.changes.Changes = append(p.changes.Changes, *c)
+		p.changes.Counts[c.Kind+"_"+c.Action]++
+	}
+	return nil
+}
+
+// describeChange fills in the entity name and a description of the row as
+// the write leaves it.
+func describeChange(tx *sql.Tx, c *models.Change) error {
+	var err error
+	switch c.Kind {
+	case ChangeEntity:
+		var entityType string
+		err = tx.QueryRow(`SELECT name, entity_type FROM entities WHERE id = ?`, c.ID).Scan(&c.Entity, &entityType)
+		c.Text = c.Entity + " (" + entityType + ")"
+	case ChangeObservation:
+		err = tx.QueryRow(
+			`SELECT e.name, o.content FROM observations o JOIN entities e ON e.id = o.entity_id WHERE o.id = ?`, c.ID,
+		).Scan(&c.Entity, &c.Text)
+	case ChangeRelation:
+		var relationType, to string
+		err = tx.QueryRow(
+			`SELECT f.name, r.relation_type, t.name FROM relations r
+			 JOIN entities f ON f.id = r.from_entity
+			 JOIN entities t ON t.id = r.to_entity
+			 WHERE r.id = ?`, c.ID,
+		).Scan(&c.Entity, &relationType, &to)
+		c.Text = strings.Join([]string{c.Entity, " —", relationType, "→ ", to}, "")
+	case ChangeProperty:
+		var key, value string
+		err = tx.QueryRow(
+			`SELECT e.name, p.key, p.value FROM entity_properties p JOIN entities e ON e.id = p.entity_id WHERE p.id = ?`, c.ID,
+		).Scan(&c.Entity, &key, &value)
+		c.Text = key + " = " + value
+	}
+	if err != nil {
+		return fmt.Errorf("describe %s %s: %w", c.Kind, c.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+// changeTexts maps "kind action" to the texts of the matching changes.
+func changeTexts(report *models.ChangeReport) map[string][]string {
+	texts := make(map[string][]string)
+	for _, c := range report.Changes {
+		key := c.Kind + " " + c.Action
+		texts[key] = append(texts[key], c.Text)
+	}
+	return texts
+}
+
+func TestDryRunDeleteEntities(t *testing.T) {
+	ps := setupCatalog(t)
+
+	dry := ps.DryRun()
+	count, err := dry.DeleteEntities([]string{"Go"})
+	if err != nil {
+		t.Fatalf("DeleteEntities: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 entity reported, got %d", count)
+	}
+
+	report := dry.Changes()
+	if !report.DryRun {
+		t.Error("Report should be marked as a dry run")
+	}
+	texts := changeTexts(report)
+	if got := texts["entity deleted"]; len(got) != 1 || got[0] != "Go (technology)" {
+		t.Errorf("Deleted entities = %v", got)
+	}
+	if got := texts["observation deleted"]; len(got) != 3 {
+		t.Errorf("Expected the 3 observations of Go to cascade, got %v", got)
+	}
+	if got := texts["relation deleted"]; len(got) != 2 {
+		t.Errorf("Expected both relations to Go to cascade, got %v", got)
+	}
+	if report.Counts["observation_deleted"] != 3 || report.Counts["relation_deleted"] != 2 {
+		t.Errorf("Unexpected counts %v", report.Counts)
+	}
+
+	entities, err := ps.GetEntities([]string{"Go"})
+	if err != nil || len(entities) != 1 {
+		t.Fatalf("Go should survive the dry run: %v %v", entities, err)
+	}
+	if len(entities[0].Observations) != 3 || len(entities[0].Incoming) != 2 {
+		t.Errorf("Go lost data in a dry run: %+v", entities[0])
+	}
+
+	// The real store still writes normally after a dry run
+	if count, err := ps.DeleteEntities([]string{"Go"}); err != nil || count != 1 {
+		t.Fatalf("DeleteEntities after dry run: %d %v", count, err)
+	}
+	if ps.Changes() != nil {
+		t.Error("A regular store should not record changes")
+	}
+}
+
+func TestDryRunBatch(t *testing.T) {
+	ps := setupCatalog(t)
+
+	dry := ps.DryRun()
+	result, err := dry.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Caddy", EntityType: "technology", Observations: []string{"Reverse proxy"}},
+		{Op: OpCreateRelation, From: "Memory Cloud", To: "Caddy", RelationType: "uses"},
+		{Op: OpUpdateEntity, Entity: "Gopls", NewType: "tool"},
+		// Created and deleted in the same write: no net change
+		{Op: OpAddObservation, Entity: "Ana", Observations: []string{"temporary"}},
+		{Op: OpDeleteObservation, Entity: "Ana", Observations: []string{"temporary"}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+	if len(result.Results) != 5 {
+		t.Fatalf("Expected 5 results, got %d", len(result.Results))
+	}
+
+	texts := changeTexts(dry.Changes())
+	if got := texts["entity created"]; len(got) != 1 || got[0] != "Caddy (technology)" {
+		t.Errorf("Created entities = %v", got)
+	}
+	if got := texts["observation created"]; len(got) != 1 || got[0] != "Reverse proxy" {
+		t.Errorf("Created observations = %v", got)
+	}
+	if got := texts["relation created"]; len(got) != 1 || got[0] != "Memory Cloud —uses→ Caddy" {
+		t.Errorf("Created relations = %v", got)
+	}
+	var updated *models.Change
+	for i, c := range dry.Changes().Changes {
+		if c.Kind == ChangeEntity && c.Action == ChangeUpdated {
+			updated = &dry.Changes().Changes[i]
+		}
+	}
+	if updated == nil || updated.Text != "Gopls (tool)" || updated.Before != "Gopls (technology)" {
+		t.Errorf("Unexpected update %+v", updated)
+	}
+
+	if entities, _ := ps.GetEntities([]string{"Caddy"}); len(entities) != 0 {
+		t.Error("Caddy should not exist after a dry run")
+	}
+	if entities, _ := ps.GetEntities([]string{"Gopls"}); len(entities) != 1 || entities[0].EntityType != "technology" {
+		t.Errorf("Gopls should keep its type, got %+v", entities)
+	}
+}
+
+func TestDryRunMerge(t *testing.T) {
+	ps := setupCatalog(t)
+
+	dry := ps.DryRun()
+	if _, err := dry.MergeEntities("Go", []string{"Gopls"}, nil); err != nil {
+		t.Fatalf("MergeEntities: %v", err)
+	}
+	report := dry.Changes()
+	if report.Counts["entity_deleted"] != 1 {
+		t.Errorf("Expected Gopls to be reported deleted, got %v", report.Counts)
+	}
+	if len(report.Changes) < 2 {
+		t.Errorf("Expected the moved observation and relation to be reported, got %+v", report.Changes)
+	}
+
+	entities, _ := ps.GetEntities([]string{"Gopls"})
+	if len(entities) != 1 || entities[0].Name != "Gopls" {
+		t.Errorf("Gopls should survive the dry run, got %+v", entities)
+	}
+}
+
+func TestDryRunProperties(t *testing.T) {
+	ps := setupProjectStore(t)
+	ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Acme", EntityType: "company"},
+		{Op: OpSetProperty, Entity: "Acme", Key: "sector", Value: "retail"},
+		{Op: OpSetProperty, Entity: "Acme", Key: "employees", Value: 120},
+	})
+
+	dry := ps.DryRun()
+	if _, err := dry.ApplyBatch([]BatchOp{
+		{Op: OpSetProperty, Entity: "Acme", Key: "sector", Value: "logistics"},
+		{Op: OpSetProperty, Entity: "Acme", Key: "public", Value: false},
+		{Op: OpDeleteEntity, Entity: "Acme"},
+	}); err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+	texts := changeTexts(dry.Changes())
+	if got := texts["property created"]; len(got) != 0 {
+		t.Errorf("A property created and deleted should not be reported, got %v", got)
+	}
+	if got := texts["property deleted"]; len(got) != 2 {
+		t.Errorf("Expected both stored properties to cascade, got %v", got)
+	}
+
+	dry = ps.DryRun()
+	dry.ApplyBatch([]BatchOp{{Op: OpSetProperty, Entity: "Acme", Key: "sector", Value: "logistics"}})
+	report := dry.Changes()
+	if len(report.Changes) != 1 || report.Changes[0].Text != "sector = logistics" || report.Changes[0].Before != "Acme: sector = retail" {
+		t.Errorf("Unexpected property change %+v", report.Changes)
+	}
+	if entities, _ := ps.GetEntities([]string{"Acme"}); entities[0].Properties["sector"] != "retail" {
+		t.Errorf("The dry run should not change the property, got %v", entities[0].Properties)
+	}
+}
@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Embedder turns text into dense vectors for semantic search.
+type Embedder interface {
+	// Model identifies the vector space. Vectors produced by different models
+	// are never compared with each other.
+	Model() string
+	// Embed returns one vector per input text, in the same order.
+	Embed(texts []string) ([][]float32, error)
+}
+
+// HashEmbedder is the built-in offline embedder. It hashes word tokens and
+// character trigrams (after lowercasing and accent folding) into a fixed-size
+// vector, so it needs no model files or network access. It captures lexical
+// and morphological overlap rather than true meaning; use an HTTP embedder
+// for conceptual matches.
+type HashEmbedder struct {
+	Dims int
+}
+
+// NewHashEmbedder returns a HashEmbedder with the default dimensionality.
+func NewHashEmbedder() *HashEmbedder {
+	return &HashEmbedder{Dims: 256}
+}
+
+// Model implements Embedder.
+func (h *HashEmbedder) Model() string {
+	return fmt.Sprintf("builtin-hash-%d", h.Dims)
+}
+
+// Embed implements Embedder.
+func (h *HashEmbedder) Embed(texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i] = h.embedOne(text)
+	}
+	return out, nil
+}
+
+func (h *HashEmbedder) embedOne(text string) []float32 {
+	vec := make([]float32, h.Dims)
+	for _, tok := range tokenize(text) {
+		h.add(vec, "w:"+tok, 1.0)
+		padded := []rune(" " + tok + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			h.add(vec, "t:"+string(padded[i:i+3]), 0.5)
+		}
+	}
+	normalize(vec)
+	return vec
+}
+
+func (h *HashEmbedder) add(vec []float32, feature string, weight float32) {
+	hf := fnv.New64a()
+	hf.Write([]byte(feature))
+	sum := hf.Sum64()
+	idx := int(sum % uint64(h.Dims))
+	if sum&(1<<63) != 0 {
+		weight = -weight
+	}
+	vec[idx] += weight
+}
+
+// HTTPEmbedder calls a local Ollama-compatible embedding endpoint
+// (POST {URL}/api/embed with {"model", "input"}).
+type HTTPEmbedder struct {
+	URL       string
+	ModelName string
+	Client    *http.Client
+}
+
+// NewHTTPEmbedder returns an HTTPEmbedder for the given base URL and model.
+func NewHTTPEmbedder(url, model string) *HTTPEmbedder {
+	return &HTTPEmbedder{
+		URL:       strings.TrimRight(url, "/"),
+		ModelName: model,
+		Client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Model implements Embedder.
+func (e *HTTPEmbedder) Model() string {
+	return "http:" + e.ModelName
+}
+
+// Embed implements Embedder.
+func (e *HTTPEmbedder) Embed(texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	body, err := json.Marshal(map[string]any{"model": e.ModelName, "input": texts})
+	if err != nil {
+		return nil, fmt.Errorf("marshal embed request: %w", err)
+	}
+	resp, err := e.Client.Post(e.URL+"/api/embed", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("embed request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embed request: unexpected status %s", resp.Status)
+	}
+
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode embed response: %w", err)
+	}
+	if len(result.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("embed response: got %d vectors for %d inputs", len(result.Embeddings), len(texts))
+	}
+	for _, v := range result.Embeddings {
+		normalize(v)
+	}
+	return result.Embeddings, nil
+}
+
+// --- Vector helpers ---
+
+// cosine returns the cosine similarity of two vectors. Vectors stored by this
+// package are already L2-normalized, so this reduces to a dot product.
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
+
+func normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	n := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= n
+	}
+}
+
+func encodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v
+}
+
+// tokenize lowercases, folds accents and splits text into alphanumeric words.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(foldText(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
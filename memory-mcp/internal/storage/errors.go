@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error kinds. Errors caused by the request or the stored data rather than by
+// the database match one of them with errors.Is, so callers can tell a
+// missing entity or a rejected write from an internal failure.
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("conflict")
+	ErrInvalid  = errors.New("invalid")
+	ErrArchived = errors.New("archived")
+)
+
+// kindError is an error of one of the kinds above with its own message.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+// Errorf formats an error of the given kind, one of ErrNotFound,
+// ErrConflict, ErrInvalid or ErrArchived. The message is not prefixed with
+// the kind.
+func Errorf(kind error, format string, args ...any) error {
+	return &kindError{kind: kind, err: fmt.Errorf(format, args...)}
+}
+
+func invalidf(format string, args ...any) error {
+	return Errorf(ErrInvalid, format, args...)
+}
+
+func notFoundf(format string, args ...any) error {
+	return Errorf(ErrNotFound, format, args...)
+}
+
+func conflictf(format string, args ...any) error {
+	return Errorf(ErrConflict, format, args...)
+}
+
+func (e *EntityNotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+func (e *IdempotencyConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+func (e *IdempotencyInProgressError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+func (e *OntologyViolationError) Is(target error) bool {
+	return target == ErrInvalid
+}
@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+// MaxTraverseDepth caps how many hops a traversal may walk.
+const MaxTraverseDepth = 6
+
+// TraverseOptions controls a graph traversal.
+type TraverseOptions struct {
+	// Direction is "outgoing", "incoming" or "both" (the default).
+	Direction string
+	// MaxDepth is the number of hops from the start entities (default 2).
+	MaxDepth int
+	// RelationTypes, when set, restricts which relation types are followed.
+	RelationTypes []string
+	// EntityTypes, when set, restricts which entity types can be reached.
+	// Start entities are always included; the walk does not pass through
+	// entities of other types.
+	EntityTypes []string
+	// IncludeObservations loads observations for every reached entity.
+	IncludeObservations bool
+}
+
+// Traverse walks the graph from the named start entities up to MaxDepth hops
+// using a recursive CTE over relations valid as of the store's valid time,
+// and returns the reached entities with their distance plus the relations
+// among them, endpoints resolved to names.
+func (p *ProjectStore) Traverse(startNames []string, opts TraverseOptions) (*models.Subgraph, error) {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = 2
+	}
+	if opts.MaxDepth > MaxTraverseDepth {
+		opts.MaxDepth = MaxTraverseDepth
+	}
+
+	var startIDs []string
+	for _, name := range startNames {
+		id, err := resolveEntity(p.db, name)
+		if err != nil {
+			return nil, err
+		}
+		startIDs = append(startIDs, id)
+	}
+	if len(startIDs) == 0 {
+		return &models.Subgraph{Entities: []models.TraversedEntity{}, Relations: []models.Relation{}}, nil
+	}
+
+	relFilter, entityFilter := "", ""
+	if len(opts.RelationTypes) > 0 {
+		relFilter = fmt.Sprintf(" AND r.relation_type IN (%s)", inPlaceholders(len(opts.RelationTypes)))
+	}
+	// Only follow relations that relationsAmong returns
+	validFilter, validArgs := p.validity().where("r")
+	relFilter += validFilter
+	if len(opts.EntityTypes) > 0 {
+		entityFilter = fmt.Sprintf(" AND e.entity_type IN (%s)", inPlaceholders(len(opts.EntityTypes)))
+	}
+
+	args := stringArgs(startIDs)
+	var steps []string
+	step := func(joinCol, nextCol string) {
+		steps = append(steps, fmt.Sprintf(
+			`SELECT r.%[2]s, w.depth + 1 FROM walk w
+			 JOIN relations r ON r.%[1]s = w.entity_id AND r.deleted_at IS NULL%[3]s
+			 JOIN entities e ON e.id = r.%[2]s AND e.deleted_at IS NULL%[4]s
+			 WHERE w.depth < ?`,
+			joinCol, nextCol, relFilter, entityFilter,
+		))
+		args = append(args, stringArgs(opts.RelationTypes)...)
+		args = append(args, validArgs...)
+		args = append(args, stringArgs(opts.EntityTypes)...)
+		args = append(args, opts.MaxDepth)
+	}
+	switch opts.Direction {
+	case "outgoing":
+		step("from_entity", "to_entity")
+	case "incoming":
+		step("to_entity", "from_entity")
+	case "", "both":
+		step("from_entity", "to_entity")
+		step("to_entity", "from_entity")
+	default:
+		return nil, invalidf("invalid direction %q (use outgoing, incoming or both)", opts.Direction)
+	}
+
+	query := fmt.Sprintf(
+		`WITH RECURSIVE walk(entity_id, depth) AS (
+		     SELECT id, 0 FROM entities WHERE id IN (%s) AND deleted_at IS NULL
+		     UNION
+		     %s
+		 )
+		 SELECT w.entity_id, MIN(w.depth), e.name, e.entity_type, e.version, e.created_at, e.updated_at
+		 FROM walk w JOIN entities e ON e.id = w.entity_id
+		 GROUP BY w.entity_id
+		 ORDER BY MIN(w.depth), e.name`,
+		inPlaceholders(len(startIDs)), strings.Join(steps, "\n\t\t     UNION\n\t\t     "),
+	)
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("traverse: %w", err)
+	}
+	defer rows.Close()
+
+	graph := &models.Subgraph{Entities: []models.TraversedEntity{}, Relations: []models.Relation{}}
+	var ids []string
+	for rows.Next() {
+		var te models.TraversedEntity
+		if err := rows.Scan(&te.ID, &te.Depth, &te.Name, &te.EntityType, &te.Version, &te.CreatedAt, &te.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan traversed entity: %w", err)
+		}
+		graph.Entities = append(graph.Entities, te)
+		ids = append(ids, te.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if opts.IncludeObservations {
+		obs, err := p.loadObservations(ids)
+		if err != nil {
+			return nil, err
+		}
+		for i := range graph.Entities {
+			graph.Entities[i].Observations = obs[graph.Entities[i].ID]
+		}
+	}
+
+	rels, err := p.relationsAmong(ids, opts.RelationTypes)
+	if err != nil {
+		return nil, err
+	}
+	graph.Relations = rels
+	return graph, nil
+}
+
+// relationsAmong returns active relations whose endpoints are both in ids,
+// optionally restricted to relation types, with endpoint names filled in.
+// Relations not valid as of the store's valid time are left out.
+func (p *ProjectStore) relationsAmong(ids []string, relationTypes []string) ([]models.Relation, error) {
+	rels := []models.Relation{}
+	if len(ids) == 0 {
+		return rels, nil
+	}
+	valid := p.validity()
+	in := inPlaceholders(len(ids))
+	query := fmt.Sprintf(
+		`SELECT `+relationColumns+`
+		 FROM relations r
+		 JOIN entities f ON f.id = r.from_entity
+		 JOIN entities t ON t.id = r.to_entity
+		 WHERE r.deleted_at IS NULL AND r.from_entity IN (%s) AND r.to_entity IN (%s)`,
+		in, in,
+	)
+	args := append(stringArgs(ids), stringArgs(ids)...)
+	if len(relationTypes) > 0 {
+		query += fmt.Sprintf(" AND r.relation_type IN (%s)", inPlaceholders(len(relationTypes)))
+		args = append(args, stringArgs(relationTypes)...)
+	}
+
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query relations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.Relation
+		if err := rows.Scan(relationDest(&r)...); err != nil {
+			return nil, fmt.Errorf("scan relation: %w", err)
+		}
+		if !valid.keep(&r.Validity) {
+			continue
+		}
+		rels = append(rels, r)
+	}
+	sort.Slice(rels, func(i, j int) bool {
+		if rels[i].FromName != rels[j].FromName {
+			return rels[i].FromName < rels[j].FromName
+		}
+		if rels[i].RelationType != rels[j].RelationType {
+			return rels[i].RelationType < rels[j].RelationType
+		}
+		return rels[i].ToName < rels[j].ToName
+	})
+	return rels, rows.Err()
+}
+
+// ReadTopology returns what graph algorithms need and nothing more: every
+// active entity with only its ID, name and type, ordered by name, and the
+// active relations between them valid as of the store's valid time, with
+// only their IDs, endpoints and types. Observations, properties and
+// timestamps are not loaded.
+func (p *ProjectStore) ReadTopology() (*models.KnowledgeGraph, error) {
+	kg := &models.KnowledgeGraph{Entities: []models.Entity{}, Relations: []models.Relation{}}
+	rows, err := p.db.Query(`SELECT id, name, entity_type FROM entities WHERE deleted_at IS NULL ORDER BY name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("query entities: %w", err)
+	}
+	for rows.Next() {
+		var e models.Entity
+		if err := rows.Scan(&e.ID, &e.Name, &e.EntityType); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan entity: %w", err)
+		}
+		kg.Entities = append(kg.Entities, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	kg.TotalEntities = len(kg.Entities)
+
+	valid, args := p.validity().where("r")
+	rows, err = p.db.Query(
+		`SELECT r.id, r.from_entity, r.to_entity, r.relation_type FROM relations r
+		 JOIN entities f ON f.id = r.from_entity AND f.deleted_at IS NULL
+		 JOIN entities t ON t.id = r.to_entity AND t.deleted_at IS NULL
+		 WHERE r.deleted_at IS NULL`+valid+` ORDER BY r.created_at, r.rowid`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query relations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r models.Relation
+		if err := rows.Scan(&r.ID, &r.FromEntity, &r.ToEntity, &r.RelationType); err != nil {
+			return nil, fmt.Errorf("scan relation: %w", err)
+		}
+		kg.Relations = append(kg.Relations, r)
+	}
+	return kg, rows.Err()
+}
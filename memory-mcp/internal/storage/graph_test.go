@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+// setupChainGraph builds: João -sponsors-> Migração -uses-> RabbitMQ -runs_on-> VPS,
+// plus ACME -employs-> João.
+func setupChainGraph(t *testing.T) *ProjectStore {
+	t.Helper()
+	ps := setupProjectStore(t)
+
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{
+		{Name: "João Silva", EntityType: "person", Observations: []string{"CTO da ACME"}},
+		{Name: "ACME", EntityType: "organization"},
+		{Name: "Migração", EntityType: "project"},
+		{Name: "RabbitMQ", EntityType: "technology"},
+		{Name: "VPS", EntityType: "infrastructure"},
+	})
+	ps.CreateRelations([]struct {
+		From         string
+		To           string
+		RelationType string
+	}{
+		{From: "João Silva", To: "Migração", RelationType: "sponsors"},
+		{From: "Migração", To: "RabbitMQ", RelationType: "uses"},
+		{From: "RabbitMQ", To: "VPS", RelationType: "runs_on"},
+		{From: "ACME", To: "João Silva", RelationType: "employs"},
+	})
+	return ps
+}
+
+func TestTraverseOutgoing(t *testing.T) {
+	ps := setupChainGraph(t)
+
+	graph, err := ps.Traverse([]string{"João Silva"}, TraverseOptions{Direction: "outgoing", MaxDepth: 2})
+	if err != nil {
+		t.Fatalf("Traverse: %v", err)
+	}
+
+	depths := make(map[string]int)
+	for _, e := range graph.Entities {
+		depths[e.Name] = e.Depth
+	}
+	want := map[string]int{"João Silva": 0, "Migração": 1, "RabbitMQ": 2}
+	if len(depths) != len(want) {
+		t.Fatalf("Reached %v, want %v", depths, want)
+	}
+	for name, d := range want {
+		if depths[name] != d {
+			t.Errorf("depth(%s) = %d, want %d", name, depths[name], d)
+		}
+	}
+
+	if len(graph.Relations) != 2 {
+		t.Fatalf("Expected 2 relations, got %d", len(graph.Relations))
+	}
+	for _, r := range graph.Relations {
+		if r.FromName == "" || r.ToName == "" {
+			t.Errorf("Relation endpoints should be resolved to names: %+v", r)
+		}
+	}
+}
+
+func TestTraverseBothAndFilters(t *testing.T) {
+	ps := setupChainGraph(t)
+
+	graph, err := ps.Traverse([]string{"Migração"}, TraverseOptions{MaxDepth: 1})
+	if err != nil {
+		t.Fatalf("Traverse: %v", err)
+	}
+	if len(graph.Entities) != 3 {
+		t.Errorf("Both directions at depth 1 should reach 3 entities, got %d", len(graph.Entities))
+	}
+
+	graph, err = ps.Traverse([]string{"Migração"}, TraverseOptions{Direction: "incoming", MaxDepth: 3})
+	if err != nil {
+		t.Fatalf("Traverse incoming: %v", err)
+	}
+	if len(graph.Entities) != 3 {
+		t.Errorf("Incoming should reach Migração, João Silva and ACME, got %+v", graph.Entities)
+	}
+
+	graph, err = ps.Traverse([]string{"João Silva"}, TraverseOptions{MaxDepth: 5, RelationTypes: []string{"sponsors", "uses"}})
+	if err != nil {
+		t.Fatalf("Traverse relation filter: %v", err)
+	}
+	if len(graph.Entities) != 3 {
+		t.Errorf("Relation filter should stop before VPS and ACME, got %+v", graph.Entities)
+	}
+
+	graph, err = ps.Traverse([]string{"João Silva"}, TraverseOptions{MaxDepth: 5, EntityTypes: []string{"project", "technology"}, IncludeObservations: true})
+	if err != nil {
+		t.Fatalf("Traverse entity filter: %v", err)
+	}
+	if len(graph.Entities) != 3 {
+		t.Errorf("Entity filter should reach João Silva, Migração and RabbitMQ, got %+v", graph.Entities)
+	}
+	if len(graph.Entities[0].Observations) != 1 {
+		t.Error("Start entity observations should be included")
+	}
+
+	if _, err := ps.Traverse([]string{"João Silva"}, TraverseOptions{Direction: "sideways"}); err == nil {
+		t.Error("Expected error for invalid direction")
+	}
+}
+
+func TestReadTopology(t *testing.T) {
+	ps := setupChainGraph(t)
+	ps.ApplyBatch([]BatchOp{
+		{Op: OpSetProperty, Entity: "VPS", Key: "provider", Value: "Hetzner"},
+		{Op: OpUpdateRelation, From: "Migração", RelationType: "uses", To: "RabbitMQ", Validity: models.Validity{ValidUntil: "2024-01-01"}},
+	})
+
+	kg, err := ps.ReadTopology()
+	if err != nil {
+		t.Fatalf("ReadTopology: %v", err)
+	}
+	if len(kg.Entities) != 5 || kg.Entities[0].Name != "ACME" || kg.Entities[0].EntityType != "organization" {
+		t.Fatalf("Expected the 5 entities by name, got %+v", kg.Entities)
+	}
+	for _, e := range kg.Entities {
+		if e.Observations != nil || e.Properties != nil {
+			t.Errorf("Topology should not load observations or properties, got %+v", e)
+		}
+	}
+	// The ended uses relation is left out
+	if len(kg.Relations) != 3 {
+		t.Errorf("Expected 3 valid relations, got %+v", kg.Relations)
+	}
+	for _, r := range kg.Relations {
+		if r.RelationType == "uses" || r.FromEntity == "" || r.ToEntity == "" {
+			t.Errorf("Unexpected relation %+v", r)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DefaultIdempotencyWindow is how long results stored under an idempotency
+// key are replayed unless configured otherwise.
+const DefaultIdempotencyWindow = 24 * time.Hour
+
+// idempotencyLease is how long a claimed key stays in progress without a
+// result. A retry after that reclaims it, so a request cut short by a crash
+// or restart does not block its key for the whole window.
+const idempotencyLease = time.Minute
+
+// IdempotencyConflictError is returned when an idempotency key comes back
+// with a different request than the one it was first used for.
+type IdempotencyConflictError struct {
+	Key string
+}
+
+func (e *IdempotencyConflictError) Error() string {
+	return fmt.Sprintf("idempotency key %q was already used for a different request", e.Key)
+}
+
+// IdempotencyInProgressError is returned when an idempotency key comes back
+// while the request that claimed it is still running.
+type IdempotencyInProgressError struct {
+	Key string
+}
+
+func (e *IdempotencyInProgressError) Error() string {
+	return fmt.Sprintf("a request with idempotency key %q is still running; retry later", e.Key)
+}
+
+// SetIdempotencyWindow sets how long results stored under an idempotency key
+// are replayed. Zero disables idempotency keys.
+func (p *ProjectStore) SetIdempotencyWindow(d time.Duration) {
+	p.idempotencyWindow = d
+}
+
+// ClaimIdempotencyKey claims key for the request identified by requestHash
+// before it runs, so that concurrent retries do not both write. It returns
+// the result stored under key within the idempotency window, if any, and
+// otherwise claims the key and returns claimed. A key claimed for a
+// different request is an *IdempotencyConflictError; a key whose request is
+// still running is an *IdempotencyInProgressError, until its claim is older
+// than idempotencyLease and the same request may claim it again. Expired
+// keys are purged on the way. When idempotency keys are disabled every call is claimed.
+func (p *ProjectStore) ClaimIdempotencyKey(key, requestHash string) (result string, claimed bool, err error) {
+	if p.idempotencyWindow <= 0 {
+		return "", true, nil
+	}
+	if _, err := p.db.Exec(
+		`DELETE FROM idempotency_keys WHERE created_at < datetime('now', ?)`,
+		fmt.Sprintf("-%d seconds", int64(p.idempotencyWindow/time.Second)),
+	); err != nil {
+		return "", false, fmt.Errorf("purge idempotency keys: %w", err)
+	}
+
+	res, err := p.db.Exec(
+		`INSERT INTO idempotency_keys (key, request_hash, result, claimed_at) VALUES (?, ?, '', datetime('now'))
+		 ON CONFLICT (key) DO UPDATE SET claimed_at = excluded.claimed_at
+		 WHERE result = '' AND request_hash = excluded.request_hash AND claimed_at < datetime('now', ?)`,
+		key, requestHash, fmt.Sprintf("-%d seconds", int64(idempotencyLease/time.Second)),
+	)
+	if err != nil {
+		return "", false, fmt.Errorf("claim idempotency key: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		return "", true, nil
+	}
+
+	var storedHash string
+	err = p.db.QueryRow(
+		`SELECT request_hash, result FROM idempotency_keys WHERE key = ?`, key,
+	).Scan(&storedHash, &result)
+	if err == sql.ErrNoRows {
+		// Released in the meantime by a failed request
+		return p.ClaimIdempotencyKey(key, requestHash)
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("lookup idempotency key: %w", err)
+	}
+	if storedHash != requestHash {
+		return "", false, &IdempotencyConflictError{Key: key}
+	}
+	if result == "" {
+		return "", false, &IdempotencyInProgressError{Key: key}
+	}
+	return result, false, nil
+}
+
+// CompleteIdempotencyKey stores the result of the request that claimed key.
+func (p *ProjectStore) CompleteIdempotencyKey(key, result string) error {
+	if p.idempotencyWindow <= 0 {
+		return nil
+	}
+	if _, err := p.db.Exec(`UPDATE idempotency_keys SET result = ? WHERE key = ?`, result, key); err != nil {
+		return fmt.Errorf("save idempotency key: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey gives up the claim on key of a request that failed,
+// so that it can be retried with the same key.
+func (p *ProjectStore) ReleaseIdempotencyKey(key string) error {
+	if p.idempotencyWindow <= 0 {
+		return nil
+	}
+	if _, err := p.db.Exec(`DELETE FROM idempotency_keys WHERE key = ? AND result = ''`, key); err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
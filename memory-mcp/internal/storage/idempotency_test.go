@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	if _, claimed, err := ps.ClaimIdempotencyKey("k1", "h1"); err != nil || !claimed {
+		t.Fatalf("Unknown key: claimed %v, err %v", claimed, err)
+	}
+
+	// A retry while the first request runs neither claims nor replays
+	var inProgress *IdempotencyInProgressError
+	if _, claimed, err := ps.ClaimIdempotencyKey("k1", "h1"); !errors.As(err, &inProgress) || claimed {
+		t.Errorf("Expected the key in progress, got claimed %v, err %v", claimed, err)
+	}
+	if _, _, err := ps.ClaimIdempotencyKey("k1", "h1"); !errors.Is(err, ErrConflict) {
+		t.Errorf("A key in progress should match ErrConflict, got %v", err)
+	}
+
+	// A claim left without a result past its lease is taken over by the same
+	// request only
+	ps.db.Exec(`UPDATE idempotency_keys SET claimed_at = datetime('now', '-2 minutes') WHERE key = 'k1'`)
+	if _, _, err := ps.ClaimIdempotencyKey("k1", "h2"); !errors.Is(err, ErrConflict) {
+		t.Errorf("A stale claim should not pass to a different request, got %v", err)
+	}
+	if _, claimed, err := ps.ClaimIdempotencyKey("k1", "h1"); err != nil || !claimed {
+		t.Errorf("Stale claim: claimed %v, err %v", claimed, err)
+	}
+	if _, claimed, _ := ps.ClaimIdempotencyKey("k1", "h1"); claimed {
+		t.Error("A reclaimed key should be in progress again")
+	}
+
+	if err := ps.CompleteIdempotencyKey("k1", `{"content":[]}`); err != nil {
+		t.Fatalf("CompleteIdempotencyKey: %v", err)
+	}
+	result, claimed, err := ps.ClaimIdempotencyKey("k1", "h1")
+	if err != nil || claimed || result != `{"content":[]}` {
+		t.Errorf("Stored key: result %q, claimed %v, err %v", result, claimed, err)
+	}
+
+	var conflict *IdempotencyConflictError
+	if _, _, err := ps.ClaimIdempotencyKey("k1", "h2"); !errors.As(err, &conflict) || conflict.Key != "k1" {
+		t.Errorf("Expected a conflict for a different request, got %v", err)
+	}
+
+	// A released key can be claimed again; a completed one is kept
+	ps.ClaimIdempotencyKey("k2", "h1")
+	ps.ReleaseIdempotencyKey("k2")
+	if _, claimed, err := ps.ClaimIdempotencyKey("k2", "h1"); err != nil || !claimed {
+		t.Errorf("Released key: claimed %v, err %v", claimed, err)
+	}
+	ps.ReleaseIdempotencyKey("k1")
+	if _, claimed, _ := ps.ClaimIdempotencyKey("k1", "h1"); claimed {
+		t.Error("Releasing a completed key should keep its result")
+	}
+
+	// Keys older than the window are purged
+	ps.SetIdempotencyWindow(time.Hour)
+	if _, err := ps.db.Exec(`UPDATE idempotency_keys SET created_at = datetime('now', '-2 hours')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, claimed, err := ps.ClaimIdempotencyKey("k1", "h2"); err != nil || !claimed {
+		t.Errorf("Expired key: claimed %v, err %v", claimed, err)
+	}
+
+	// A zero window disables keys
+	ps.SetIdempotencyWindow(0)
+	ps.ClaimIdempotencyKey("k3", "h1")
+	ps.CompleteIdempotencyKey("k3", `{"content":[]}`)
+	ps.SetIdempotencyWindow(time.Hour)
+	if _, claimed, _ := ps.ClaimIdempotencyKey("k3", "h1"); !claimed {
+		t.Error("No key should be stored while idempotency keys are disabled")
+	}
+}
@@ -0,0 +1,311 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+// The bulk loaders below pass ID lists as one JSON array parameter, read with
+// `IN (SELECT value FROM json_each(?))`. The statement text does not depend
+// on the number of IDs, so each query is prepared once per store and reused,
+// and a whole result set costs one round trip instead of one per entity.
+
+// prepared returns a cached prepared statement for query. Dry-run views share
+// the cache of the store they were made from.
+func (p *ProjectStore) prepared(query string) (*sql.Stmt, error) {
+	if p.base != nil {
+		return p.base.prepared(query)
+	}
+	p.stmtMu.Lock()
+	defer p.stmtMu.Unlock()
+	if stmt, ok := p.stmts[query]; ok {
+		return stmt, nil
+	}
+	stmt, err := p.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	if p.stmts == nil {
+		p.stmts = make(map[string]*sql.Stmt)
+	}
+	p.stmts[query] = stmt
+	return stmt, nil
+}
+
+// queryPrepared runs a cached statement.
+func (p *ProjectStore) queryPrepared(query string, args ...any) (*sql.Rows, error) {
+	stmt, err := p.prepared(query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.Query(args...)
+}
+
+// jsonArray encodes values as a JSON array parameter for json_each.
+func jsonArray(values []string) string {
+	if len(values) == 0 {
+		return "[]"
+	}
+	b, _ := json.Marshal(values)
+	return string(b)
+}
+
+// relationColumns selects a relation with its endpoint names and types; the
+// query must join the source entity as f and the target as t. Scan it with
+// relationDest.
+const relationColumns = `r.id, r.from_entity, f.name, f.entity_type, r.to_entity, t.name, t.entity_type, r.relation_type, r.created_at,
+	COALESCE(r.valid_from, ''), COALESCE(r.valid_until, '')`
+
+// relationDest returns the scan destinations matching relationColumns.
+func relationDest(r *models.Relation) []any {
+	return []any{&r.ID, &r.FromEntity, &r.FromName, &r.FromType, &r.ToEntity, &r.ToName, &r.ToType, &r.RelationType, &r.CreatedAt,
+		&r.ValidFrom, &r.ValidUntil}
+}
+
+// entityNameType looks up the name and type of an entity by ID.
+func entityNameType(q querier, id string) (name, entityType string, err error) {
+	err = q.QueryRow(`SELECT name, entity_type FROM entities WHERE id = ?`, id).Scan(&name, &entityType)
+	if err != nil {
+		return "", "", fmt.Errorf("query entity: %w", err)
+	}
+	return name, entityType, nil
+}
+
+const entitiesByIDQuery = `SELECT id, name, entity_type, version, created_at, updated_at FROM entities
+	WHERE id IN (SELECT value FROM json_each(?)) AND deleted_at IS NULL`
+
+// getEntitiesByID loads active entities with their properties, observations,
+// outgoing and incoming relations and aliases, preserving the order of ids. Missing
+// or deleted IDs are skipped.
+func (p *ProjectStore) getEntitiesByID(ids []string) ([]models.Entity, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	rows, err := p.queryPrepared(entitiesByIDQuery, jsonArray(ids))
+	if err != nil {
+		return nil, fmt.Errorf("query entities: %w", err)
+	}
+	byID := make(map[string]models.Entity, len(ids))
+	for rows.Next() {
+		var e models.Entity
+		if err := rows.Scan(&e.ID, &e.Name, &e.EntityType, &e.Version, &e.CreatedAt, &e.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan entity: %w", err)
+		}
+		byID[e.ID] = e
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	found := make([]string, 0, len(byID))
+	for _, id := range ids {
+		if _, ok := byID[id]; ok {
+			found = append(found, id)
+		}
+	}
+	obs, err := p.loadObservations(found)
+	if err != nil {
+		return nil, err
+	}
+	rels, err := p.loadRelations(found)
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := p.loadAliases(found)
+	if err != nil {
+		return nil, err
+	}
+	props, err := p.loadProperties(found)
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make([]models.Entity, 0, len(found))
+	for _, id := range found {
+		e := byID[id]
+		e.Observations = obs[id]
+		for _, r := range rels[id] {
+			if r.FromEntity == id {
+				e.Outgoing = append(e.Outgoing, r)
+			} else {
+				e.Incoming = append(e.Incoming, r)
+			}
+		}
+		e.Aliases = aliases[id]
+		e.Properties = propertyValues(props[id])
+		entities = append(entities, e)
+	}
+	return entities, nil
+}
+
+// observationColumns selects an observation with its metadata; the query
+// must name the observations table o. Scan it with scanObservation.
+const observationColumns = `o.id, o.entity_id, o.content, o.source, o.author, o.confidence, o.tags, o.created_at,
+	COALESCE(o.valid_from, ''), COALESCE(o.valid_until, '')`
+
+// scanObservation scans a row of observationColumns.
+func scanObservation(row interface{ Scan(...any) error }, o *models.Observation) error {
+	var confidence sql.NullFloat64
+	var tags string
+	if err := row.Scan(&o.ID, &o.EntityID, &o.Content, &o.Source, &o.Author, &confidence, &tags, &o.CreatedAt,
+		&o.ValidFrom, &o.ValidUntil); err != nil {
+		return err
+	}
+	if confidence.Valid {
+		o.Confidence = &confidence.Float64
+	}
+	o.Tags = nil
+	if tags != "" && tags != "[]" {
+		if err := json.Unmarshal([]byte(tags), &o.Tags); err != nil {
+			return fmt.Errorf("decode tags of observation %s: %w", o.ID, err)
+		}
+	}
+	return nil
+}
+
+const observationsQuery = `SELECT ` + observationColumns + ` FROM observations o
+	WHERE o.entity_id IN (SELECT value FROM json_each(?)) AND o.deleted_at IS NULL
+	ORDER BY o.created_at, o.rowid`
+
+// loadObservations loads the active observations of many entities, keyed by
+// entity ID, leaving out those not valid as of the store's valid time.
+func (p *ProjectStore) loadObservations(ids []string) (map[string][]models.Observation, error) {
+	obs := make(map[string][]models.Observation, len(ids))
+	if len(ids) == 0 {
+		return obs, nil
+	}
+	valid := p.validity()
+	rows, err := p.queryPrepared(observationsQuery, jsonArray(ids))
+	if err != nil {
+		return nil, fmt.Errorf("query observations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var o models.Observation
+		if err := scanObservation(rows, &o); err != nil {
+			return nil, fmt.Errorf("scan observation: %w", err)
+		}
+		if !valid.keep(&o.Validity) {
+			continue
+		}
+		obs[o.EntityID] = append(obs[o.EntityID], o)
+	}
+	return obs, rows.Err()
+}
+
+// relationsQuery is a UNION rather than an OR so that both lookups use the
+// from/to indexes; UNION also lists a self-loop once.
+const relationsQuery = `SELECT ` + relationColumns + `, r.rowid
+	FROM relations r
+	JOIN entities f ON f.id = r.from_entity
+	JOIN entities t ON t.id = r.to_entity
+	WHERE r.from_entity IN (SELECT value FROM json_each(?1)) AND r.deleted_at IS NULL
+	UNION
+	SELECT ` + relationColumns + `, r.rowid
+	FROM relations r
+	JOIN entities f ON f.id = r.from_entity
+	JOIN entities t ON t.id = r.to_entity
+	WHERE r.to_entity IN (SELECT value FROM json_each(?1)) AND r.deleted_at IS NULL
+	ORDER BY 9, 12`
+
+// loadRelations loads the active relations of many entities, keyed by entity
+// ID, leaving out those not valid as of the store's valid time. A relation is
+// listed under both of its endpoints.
+func (p *ProjectStore) loadRelations(ids []string) (map[string][]models.Relation, error) {
+	rels := make(map[string][]models.Relation, len(ids))
+	if len(ids) == 0 {
+		return rels, nil
+	}
+	valid := p.validity()
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	rows, err := p.queryPrepared(relationsQuery, jsonArray(ids))
+	if err != nil {
+		return nil, fmt.Errorf("query relations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r models.Relation
+		var rowid int64
+		if err := rows.Scan(append(relationDest(&r), &rowid)...); err != nil {
+			return nil, fmt.Errorf("scan relation: %w", err)
+		}
+		if !valid.keep(&r.Validity) {
+			continue
+		}
+		if wanted[r.FromEntity] {
+			rels[r.FromEntity] = append(rels[r.FromEntity], r)
+		}
+		if wanted[r.ToEntity] && r.ToEntity != r.FromEntity {
+			rels[r.ToEntity] = append(rels[r.ToEntity], r)
+		}
+	}
+	return rels, rows.Err()
+}
+
+const relationsFromQuery = `SELECT ` + relationColumns + `
+	FROM relations r
+	JOIN entities f ON f.id = r.from_entity
+	JOIN entities t ON t.id = r.to_entity AND t.deleted_at IS NULL
+	WHERE r.from_entity IN (SELECT value FROM json_each(?1)) AND r.deleted_at IS NULL
+	  AND (?2 = '[]' OR t.entity_type IN (SELECT value FROM json_each(?2)))
+	ORDER BY r.created_at, r.rowid`
+
+// loadRelationsFrom loads the active relations whose source is one of ids
+// and whose target is active and, if entityTypes is set, of one of those
+// types. Relations not valid as of the store's valid time are left out.
+func (p *ProjectStore) loadRelationsFrom(ids []string, entityTypes []string) ([]models.Relation, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	valid := p.validity()
+	rows, err := p.queryPrepared(relationsFromQuery, jsonArray(ids), jsonArray(entityTypes))
+	if err != nil {
+		return nil, fmt.Errorf("query relations: %w", err)
+	}
+	defer rows.Close()
+
+	var rels []models.Relation
+	for rows.Next() {
+		var r models.Relation
+		if err := rows.Scan(relationDest(&r)...); err != nil {
+			return nil, fmt.Errorf("scan relation: %w", err)
+		}
+		if !valid.keep(&r.Validity) {
+			continue
+		}
+		rels = append(rels, r)
+	}
+	return rels, rows.Err()
+}
+
+const aliasesQuery = `SELECT entity_id, alias FROM entity_aliases
+	WHERE entity_id IN (SELECT value FROM json_each(?)) ORDER BY alias`
+
+// loadAliases loads the alternative names of many entities, keyed by entity ID.
+func (p *ProjectStore) loadAliases(ids []string) (map[string][]string, error) {
+	aliases := make(map[string][]string, len(ids))
+	if len(ids) == 0 {
+		return aliases, nil
+	}
+	rows, err := p.queryPrepared(aliasesQuery, jsonArray(ids))
+	if err != nil {
+		return nil, fmt.Errorf("query aliases: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, alias string
+		if err := rows.Scan(&id, &alias); err != nil {
+			return nil, fmt.Errorf("scan alias: %w", err)
+		}
+		aliases[id] = append(aliases[id], alias)
+	}
+	return aliases, rows.Err()
+}
@@ -2,9 +2,12 @@ package storage
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	_ "github.com/ncruces/go-sqlite3/driver"
@@ -15,8 +18,12 @@ import (
 
 // MetaStore manages the central _meta.db database that tracks all projects.
 type MetaStore struct {
-	db      *sql.DB
-	dataDir string
+	db       *sql.DB
+	dataDir  string
+	embedder Embedder
+
+	idempotencyWindow time.Duration
+	limits            Limits
 }
 
 // OpenMeta opens (or creates) the _meta.db database and runs migrations.
@@ -42,7 +49,7 @@ func OpenMeta(dataDir string) (*MetaStore, error) {
 		return nil, fmt.Errorf("migrate meta db: %w", err)
 	}
 
-	return &MetaStore{db: db, dataDir: dataDir}, nil
+	return &MetaStore{db: db, dataDir: dataDir, idempotencyWindow: DefaultIdempotencyWindow, limits: DefaultLimits}, nil
 }
 
 // Close closes the database connection.
@@ -55,8 +62,51 @@ func (m *MetaStore) DataDir() string {
 	return m.dataDir
 }
 
+// SetEmbedder sets the embedder handed to project stores opened through this
+// meta store. When unset, project stores use the built-in HashEmbedder.
+func (m *MetaStore) SetEmbedder(e Embedder) {
+	m.embedder = e
+}
+
+// Embedder returns the configured embedder, or nil if none was set.
+func (m *MetaStore) Embedder() Embedder {
+	return m.embedder
+}
+
+// SetIdempotencyWindow sets how long project stores opened through this meta
+// store replay results stored under an idempotency key. Zero disables
+// idempotency keys.
+func (m *MetaStore) SetIdempotencyWindow(d time.Duration) {
+	m.idempotencyWindow = d
+}
+
+// IdempotencyWindow returns the configured idempotency window.
+func (m *MetaStore) IdempotencyWindow() time.Duration {
+	return m.idempotencyWindow
+}
+
+// SetLimits sets the limits checked on writes by project stores opened
+// through this meta store.
+func (m *MetaStore) SetLimits(l Limits) {
+	m.limits = l
+}
+
+// Limits returns the configured write limits.
+func (m *MetaStore) Limits() Limits {
+	return m.limits
+}
+
 // CreateProject creates a new project entry and its isolated database file.
 func (m *MetaStore) CreateProject(name, description string) (*models.Project, error) {
+	if err := checkProjectName(&name); err != nil {
+		return nil, err
+	}
+	if _, err := m.GetProjectByName(name); err == nil {
+		return nil, conflictf("project %q already exists", name)
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
 	id := uuid.New().String()
 	dbPath := filepath.Join("projects", id+".db")
 
@@ -139,7 +189,7 @@ func (m *MetaStore) UpdateProjectStatus(name, status string) (*models.Project, e
 	}
 	n, _ := result.RowsAffected()
 	if n == 0 {
-		return nil, fmt.Errorf("project %q not found", name)
+		return nil, notFoundf("project %q not found", name)
 	}
 	return m.GetProjectByName(name)
 }
@@ -152,7 +202,7 @@ func (m *MetaStore) ArchiveProject(name string) (*models.Project, error) {
 		return nil, err
 	}
 	if proj.Status == "archived" {
-		return nil, fmt.Errorf("project %q is already archived", name)
+		return nil, Errorf(ErrArchived, "project %q is already archived", name)
 	}
 
 	oldPath := filepath.Join(m.dataDir, proj.DBPath)
@@ -183,7 +233,7 @@ func (m *MetaStore) RestoreProject(name string) (*models.Project, error) {
 		return nil, err
 	}
 	if proj.Status != "archived" {
-		return nil, fmt.Errorf("project %q is not archived", name)
+		return nil, conflictf("project %q is not archived", name)
 	}
 
 	oldPath := filepath.Join(m.dataDir, proj.DBPath)
@@ -233,12 +283,27 @@ func (m *MetaStore) ProjectDBPath(proj *models.Project) string {
 	return filepath.Join(m.dataDir, proj.DBPath)
 }
 
+// ProjectDBSize returns the size in bytes of a project's database file plus
+// its write-ahead log, if any.
+func (m *MetaStore) ProjectDBSize(proj *models.Project) (int64, error) {
+	dbPath := m.ProjectDBPath(proj)
+	info, err := os.Stat(dbPath)
+	if err != nil {
+		return 0, fmt.Errorf("stat project db: %w", err)
+	}
+	size := info.Size()
+	if wal, err := os.Stat(dbPath + "-wal"); err == nil {
+		size += wal.Size()
+	}
+	return size, nil
+}
+
 // scanProject scans a single project row.
 func scanProject(row *sql.Row) (*models.Project, error) {
 	var p models.Project
 	err := row.Scan(&p.ID, &p.Name, &p.Description, &p.DBPath, &p.Status, &p.CreatedAt, &p.UpdatedAt)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("project not found")
+		return nil, notFoundf("project not found")
 	}
 	if err != nil {
 		return nil, fmt.Errorf("scan project: %w", err)
@@ -254,11 +319,82 @@ func initProjectDB(dbPath string) error {
 	}
 	defer db.Close()
 
+	return migrateProjectDB(db)
+}
+
+// addedColumns are the columns ProjectSchema gained after its tables were
+// first created, in order, with their definitions.
+var addedColumns = []struct{ table, column, definition string }{
+	{"entities", "version", "INTEGER NOT NULL DEFAULT 1"},
+	{"observations", "source", "TEXT NOT NULL DEFAULT ''"},
+	{"observations", "author", "TEXT NOT NULL DEFAULT ''"},
+	{"observations", "confidence", "REAL NULL"},
+	{"observations", "tags", "TEXT NOT NULL DEFAULT '[]'"},
+	{"observations", "valid_from", "TEXT NULL"},
+	{"observations", "valid_until", "TEXT NULL"},
+	{"relations", "valid_from", "TEXT NULL"},
+	{"relations", "valid_until", "TEXT NULL"},
+	{"entities", "name_fold", "TEXT NOT NULL DEFAULT ''"},
+	{"idempotency_keys", "claimed_at", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// migrateProjectDB applies the project schema and triggers. Every statement is
+// idempotent, so this is safe to run on both new and existing databases.
+func migrateProjectDB(db *sql.DB) error {
 	if _, err := db.Exec(ProjectSchema); err != nil {
 		return fmt.Errorf("create project schema: %w", err)
 	}
+	// Databases created by older versions lack the columns added since
+	for _, c := range addedColumns {
+		var exists int
+		if err := db.QueryRow(
+			`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, c.table, c.column,
+		).Scan(&exists); err != nil {
+			return fmt.Errorf("inspect %s: %w", c.table, err)
+		}
+		if exists > 0 {
+			continue
+		}
+		if _, err := db.Exec(`ALTER TABLE ` + c.table + ` ADD COLUMN ` + c.column + ` ` + c.definition); err != nil {
+			return fmt.Errorf("add %s.%s: %w", c.table, c.column, err)
+		}
+	}
+	if err := backfillNameFold(db); err != nil {
+		return err
+	}
+	if _, err := db.Exec(ProjectIndexes); err != nil {
+		return fmt.Errorf("create project indexes: %w", err)
+	}
 	if _, err := db.Exec(ProjectTriggers); err != nil {
 		return fmt.Errorf("create project triggers: %w", err)
 	}
 	return nil
 }
+
+// backfillNameFold sets the folded name of entities written before
+// entities.name_fold existed.
+func backfillNameFold(db *sql.DB) error {
+	rows, err := db.Query(`SELECT id, name FROM entities WHERE name_fold = ''`)
+	if err != nil {
+		return fmt.Errorf("load entity names: %w", err)
+	}
+	folds := make(map[string]string)
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan entity name: %w", err)
+		}
+		folds[id] = foldText(strings.TrimSpace(name))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("load entity names: %w", err)
+	}
+	for id, fold := range folds {
+		if _, err := db.Exec(`UPDATE entities SET name_fold = ? WHERE id = ?`, fold, id); err != nil {
+			return fmt.Errorf("fold entity name: %w", err)
+		}
+	}
+	return nil
+}
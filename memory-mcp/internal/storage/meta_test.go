@@ -1,9 +1,13 @@
 package storage
 
 import (
+	"database/sql"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
 )
 
 func tempDir(t *testing.T) string {
@@ -100,8 +104,8 @@ func TestCreateDuplicateProject(t *testing.T) {
 		t.Fatal(err)
 	}
 	_, err = meta.CreateProject("dup", "")
-	if err == nil {
-		t.Error("Expected error on duplicate project name")
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected a conflict on duplicate project name, got %v", err)
 	}
 }
 
@@ -163,6 +167,10 @@ func TestArchiveAndRestoreProject(t *testing.T) {
 		t.Errorf("Expected 1 archived project, got %d", len(projects))
 	}
 
+	if _, err := meta.ArchiveProject("archivable"); !errors.Is(err, ErrArchived) {
+		t.Errorf("Archiving twice should fail as archived, got %v", err)
+	}
+
 	// Restore
 	restored, err := meta.RestoreProject("archivable")
 	if err != nil {
@@ -171,6 +179,9 @@ func TestArchiveAndRestoreProject(t *testing.T) {
 	if restored.Status != "active" {
 		t.Errorf("Status = %q, want %q", restored.Status, "active")
 	}
+	if _, err := meta.RestoreProject("archivable"); !errors.Is(err, ErrConflict) {
+		t.Errorf("Restoring an active project should conflict, got %v", err)
+	}
 
 	// DB file should be back in projects/
 	restoredPath := filepath.Join(dir, restored.DBPath)
@@ -216,7 +227,71 @@ func TestGetNonExistentProject(t *testing.T) {
 	defer meta.Close()
 
 	_, err = meta.GetProjectByName("nonexistent")
-	if err == nil {
-		t.Error("Expected error for nonexistent project")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found for nonexistent project, got %v", err)
+	}
+}
+
+func TestMigrateEntityVersion(t *testing.T) {
+	dbPath := filepath.Join(tempDir(t), "old.db")
+	db, err := sql.Open("sqlite3", "file:"+dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An entities table from before versions existed
+	if _, err := db.Exec(`CREATE TABLE entities (
+		id TEXT PRIMARY KEY, name TEXT NOT NULL, entity_type TEXT NOT NULL,
+		created_at TEXT NOT NULL DEFAULT (datetime('now')),
+		updated_at TEXT NOT NULL DEFAULT (datetime('now')),
+		deleted_at TEXT NULL);
+		INSERT INTO entities (id, name, entity_type) VALUES ('e1', 'Go', 'technology')`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	ps, err := OpenProject(dbPath)
+	if err != nil {
+		t.Fatalf("OpenProject: %v", err)
+	}
+	defer ps.Close()
+	entities, err := ps.GetEntities([]string{"Go"})
+	if err != nil || len(entities) != 1 || entities[0].Version != 1 {
+		t.Fatalf("Existing entities should start at version 1, got %+v, %v", entities, err)
+	}
+	if id, err := ps.ResolveEntity("go"); err != nil || id != "e1" {
+		t.Errorf("Existing entities should resolve case-insensitively, got %q, %v", id, err)
+	}
+}
+
+func TestMigrateObservationMeta(t *testing.T) {
+	dbPath := filepath.Join(tempDir(t), "old.db")
+	db, err := sql.Open("sqlite3", "file:"+dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An observations table from before observation metadata existed
+	if _, err := db.Exec(`CREATE TABLE observations (
+		id TEXT PRIMARY KEY, entity_id TEXT NOT NULL, content TEXT NOT NULL,
+		created_at TEXT NOT NULL DEFAULT (datetime('now')),
+		deleted_at TEXT NULL);
+		INSERT INTO observations (id, entity_id, content) VALUES ('o1', 'e1', 'Compiled')`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	ps, err := OpenProject(dbPath)
+	if err != nil {
+		t.Fatalf("OpenProject: %v", err)
+	}
+	defer ps.Close()
+	obs, err := ps.loadObservations([]string{"e1"})
+	if err != nil || len(obs["e1"]) != 1 {
+		t.Fatalf("Existing observations should load, got %+v, %v", obs, err)
+	}
+	if o := obs["e1"][0]; o.Source != "" || o.Confidence != nil || o.Tags != nil {
+		t.Errorf("Existing observations should have no metadata, got %+v", o)
+	}
+	if o := obs["e1"][0]; o.Validity != (models.Validity{}) {
+		t.Errorf("Existing observations should always be valid, got %+v", o.Validity)
 	}
 }
@@ -0,0 +1,287 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+// Ontology enforcement modes.
+const (
+	OntologyOff     = "off"
+	OntologyLenient = "lenient"
+	OntologyStrict  = "strict"
+)
+
+const ontologyModeKey = "ontology_mode"
+
+// OntologyViolationError is returned when a write is rejected in strict mode.
+type OntologyViolationError struct {
+	Violations []string
+}
+
+func (e *OntologyViolationError) Error() string {
+	return "ontology violation: " + strings.Join(e.Violations, "; ")
+}
+
+// GetOntology returns the project's ontology. A project without one has
+// empty type lists and mode "lenient".
+func (p *ProjectStore) GetOntology() (*models.Ontology, error) {
+	return loadOntology(p.db)
+}
+
+// SetOntology replaces the project's ontology. An empty mode means lenient.
+// When entity types are listed, relation source and target types must be
+// among them. Existing entities and relations are not changed; use
+// ValidateGraph to find the ones that no longer fit.
+func (p *ProjectStore) SetOntology(o models.Ontology) (*models.Ontology, error) {
+	if err := normalizeOntology(&o); err != nil {
+		return nil, err
+	}
+
+	tx, err := p.begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM ontology_entity_types`); err != nil {
+		return nil, fmt.Errorf("clear entity types: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM ontology_relation_types`); err != nil {
+		return nil, fmt.Errorf("clear relation types: %w", err)
+	}
+	for _, et := range o.EntityTypes {
+		if _, err := tx.Exec(
+			`INSERT INTO ontology_entity_types (name, description) VALUES (?, ?)`,
+			et.Name, et.Description,
+		); err != nil {
+			return nil, fmt.Errorf("insert entity type %q: %w", et.Name, err)
+		}
+	}
+	for _, rt := range o.RelationTypes {
+		sources, _ := json.Marshal(rt.SourceTypes)
+		targets, _ := json.Marshal(rt.TargetTypes)
+		if _, err := tx.Exec(
+			`INSERT INTO ontology_relation_types (name, description, source_types, target_types, inverse)
+			 VALUES (?, ?, ?, ?, ?)`,
+			rt.Name, rt.Description, string(sources), string(targets), rt.Inverse,
+		); err != nil {
+			return nil, fmt.Errorf("insert relation type %q: %w", rt.Name, err)
+		}
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO project_settings (key, value) VALUES (?, ?)
+		 ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = datetime('now')`,
+		ontologyModeKey, o.Mode,
+	); err != nil {
+		return nil, fmt.Errorf("save ontology mode: %w", err)
+	}
+
+	saved, err := loadOntology(tx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.commit(tx); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return saved, nil
+}
+
+func loadOntology(q querier) (*models.Ontology, error) {
+	o := &models.Ontology{
+		Mode:          OntologyLenient,
+		EntityTypes:   []models.EntityTypeDef{},
+		RelationTypes: []models.RelationTypeDef{},
+	}
+
+	var mode string
+	err := q.QueryRow(`SELECT value FROM project_settings WHERE key = ?`, ontologyModeKey).Scan(&mode)
+	switch {
+	case err == nil:
+		o.Mode = mode
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("query ontology mode: %w", err)
+	}
+
+	rows, err := q.Query(`SELECT name, description FROM ontology_entity_types ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("query entity types: %w", err)
+	}
+	for rows.Next() {
+		var et models.EntityTypeDef
+		if err := rows.Scan(&et.Name, &et.Description); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan entity type: %w", err)
+		}
+		o.EntityTypes = append(o.EntityTypes, et)
+	}
+	rows.Close()
+
+	rows, err = q.Query(`SELECT name, description, source_types, target_types, inverse FROM ontology_relation_types ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("query relation types: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rt models.RelationTypeDef
+		var sources, targets string
+		if err := rows.Scan(&rt.Name, &rt.Description, &sources, &targets, &rt.Inverse); err != nil {
+			return nil, fmt.Errorf("scan relation type: %w", err)
+		}
+		if err := json.Unmarshal([]byte(sources), &rt.SourceTypes); err != nil {
+			return nil, fmt.Errorf("decode source types of relation type %q: %w", rt.Name, err)
+		}
+		if err := json.Unmarshal([]byte(targets), &rt.TargetTypes); err != nil {
+			return nil, fmt.Errorf("decode target types of relation type %q: %w", rt.Name, err)
+		}
+		o.RelationTypes = append(o.RelationTypes, rt)
+	}
+	return o, rows.Err()
+}
+
+// normalizeOntology trims names, fills the default mode and rejects
+// inconsistent definitions.
+func normalizeOntology(o *models.Ontology) error {
+	o.Mode = strings.ToLower(strings.TrimSpace(o.Mode))
+	switch o.Mode {
+	case "":
+		o.Mode = OntologyLenient
+	case OntologyOff, OntologyLenient, OntologyStrict:
+	default:
+		return invalidf("invalid ontology mode %q (use off, lenient or strict)", o.Mode)
+	}
+
+	entityTypes := make(map[string]bool)
+	for i := range o.EntityTypes {
+		et := &o.EntityTypes[i]
+		et.Name = strings.TrimSpace(et.Name)
+		if et.Name == "" {
+			return invalidf("entity type %d has no name", i+1)
+		}
+		if entityTypes[et.Name] {
+			return invalidf("entity type %q is listed twice", et.Name)
+		}
+		entityTypes[et.Name] = true
+	}
+
+	relationTypes := make(map[string]bool)
+	for i := range o.RelationTypes {
+		rt := &o.RelationTypes[i]
+		rt.Name = strings.TrimSpace(rt.Name)
+		rt.Inverse = strings.TrimSpace(rt.Inverse)
+		if rt.Name == "" {
+			return invalidf("relation type %d has no name", i+1)
+		}
+		if relationTypes[rt.Name] {
+			return invalidf("relation type %q is listed twice", rt.Name)
+		}
+		relationTypes[rt.Name] = true
+		if rt.Inverse == rt.Name {
+			return invalidf("relation type %q cannot be its own inverse", rt.Name)
+		}
+		if len(entityTypes) == 0 {
+			continue
+		}
+		for _, t := range append(append([]string{}, rt.SourceTypes...), rt.TargetTypes...) {
+			if !entityTypes[t] {
+				return invalidf("relation type %q refers to unknown entity type %q", rt.Name, t)
+			}
+		}
+	}
+	return nil
+}
+
+// ontologyChecker checks writes against a loaded ontology.
+type ontologyChecker struct {
+	mode          string
+	entityTypes   []string
+	relationTypes map[string]models.RelationTypeDef
+	// inverses maps an inverse name to the relation type it reverses.
+	inverses map[string]models.RelationTypeDef
+}
+
+func loadOntologyChecker(q querier) (*ontologyChecker, error) {
+	o, err := loadOntology(q)
+	if err != nil {
+		return nil, err
+	}
+	c := &ontologyChecker{
+		mode:          o.Mode,
+		relationTypes: make(map[string]models.RelationTypeDef),
+		inverses:      make(map[string]models.RelationTypeDef),
+	}
+	for _, et := range o.EntityTypes {
+		c.entityTypes = append(c.entityTypes, et.Name)
+	}
+	for _, rt := range o.RelationTypes {
+		c.relationTypes[rt.Name] = rt
+		if rt.Inverse != "" {
+			c.inverses[rt.Inverse] = rt
+		}
+	}
+	return c, nil
+}
+
+// strict reports whether violations must reject the write.
+func (c *ontologyChecker) strict() bool {
+	return c.mode == OntologyStrict
+}
+
+func (c *ontologyChecker) checkEntity(name, entityType string) []string {
+	if len(c.entityTypes) == 0 || containsString(c.entityTypes, entityType) {
+		return nil
+	}
+	msg := fmt.Sprintf("entity %q: type %q is not in the ontology (allowed: %s)", name, entityType, strings.Join(c.entityTypes, ", "))
+	for _, t := range c.entityTypes {
+		if foldText(strings.TrimSpace(entityType)) == foldText(t) {
+			msg += fmt.Sprintf("; did you mean %q?", t)
+			break
+		}
+	}
+	return []string{msg}
+}
+
+// checkRelation checks that the relation type is defined and that the
+// endpoint types fall within its source and target types.
+func (c *ontologyChecker) checkRelation(from, fromType, relationType, to, toType string) []string {
+	if len(c.relationTypes) == 0 {
+		return nil
+	}
+	prefix := fmt.Sprintf("relation %s —%s→ %s", from, relationType, to)
+	rt, ok := c.relationTypes[relationType]
+	if !ok {
+		if inv, ok := c.inverses[relationType]; ok {
+			return []string{fmt.Sprintf("%s: %q is the inverse of %q; create %s —%s→ %s instead", prefix, relationType, inv.Name, to, inv.Name, from)}
+		}
+		names := make([]string, 0, len(c.relationTypes))
+		for n := range c.relationTypes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return []string{fmt.Sprintf("%s: type %q is not in the ontology (allowed: %s)", prefix, relationType, strings.Join(names, ", "))}
+	}
+
+	var violations []string
+	if len(rt.SourceTypes) > 0 && !containsString(rt.SourceTypes, fromType) {
+		violations = append(violations, fmt.Sprintf("%s: source type %q is not one of %s", prefix, fromType, strings.Join(rt.SourceTypes, ", ")))
+	}
+	if len(rt.TargetTypes) > 0 && !containsString(rt.TargetTypes, toType) {
+		violations = append(violations, fmt.Sprintf("%s: target type %q is not one of %s", prefix, toType, strings.Join(rt.TargetTypes, ", ")))
+	}
+	return violations
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
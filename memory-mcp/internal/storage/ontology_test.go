@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+func testOntology(mode string) models.Ontology {
+	return models.Ontology{
+		Mode: mode,
+		EntityTypes: []models.EntityTypeDef{
+			{Name: "project"}, {Name: "technology", Description: "Languages, databases, libraries"}, {Name: "person"},
+		},
+		RelationTypes: []models.RelationTypeDef{
+			{Name: "uses", SourceTypes: []string{"project"}, TargetTypes: []string{"technology"}, Inverse: "used_by"},
+			{Name: "maintains", SourceTypes: []string{"person"}},
+		},
+	}
+}
+
+func TestSetGetOntology(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	empty, err := ps.GetOntology()
+	if err != nil {
+		t.Fatalf("GetOntology: %v", err)
+	}
+	if empty.Mode != OntologyLenient || len(empty.EntityTypes) != 0 || len(empty.RelationTypes) != 0 {
+		t.Errorf("A new project should have an empty lenient ontology, got %+v", empty)
+	}
+
+	saved, err := ps.SetOntology(testOntology(""))
+	if err != nil {
+		t.Fatalf("SetOntology: %v", err)
+	}
+	if saved.Mode != OntologyLenient || len(saved.EntityTypes) != 3 || len(saved.RelationTypes) != 2 {
+		t.Errorf("Unexpected saved ontology: %+v", saved)
+	}
+	uses := saved.RelationTypes[1]
+	if uses.Name != "uses" || uses.Inverse != "used_by" || len(uses.TargetTypes) != 1 || uses.TargetTypes[0] != "technology" {
+		t.Errorf("Relation type not round-tripped: %+v", uses)
+	}
+
+	// Setting again replaces the previous definition
+	saved, _ = ps.SetOntology(models.Ontology{Mode: "strict", EntityTypes: []models.EntityTypeDef{{Name: "concept"}}})
+	if saved.Mode != OntologyStrict || len(saved.EntityTypes) != 1 || len(saved.RelationTypes) != 0 {
+		t.Errorf("SetOntology should replace the ontology, got %+v", saved)
+	}
+}
+
+func TestGetOntologyCorruptRow(t *testing.T) {
+	ps := setupProjectStore(t)
+	ps.SetOntology(testOntology(OntologyStrict))
+	ps.db.Exec(`UPDATE ontology_relation_types SET target_types = 'not json' WHERE name = 'uses'`)
+
+	if _, err := ps.GetOntology(); err == nil || !strings.Contains(err.Error(), `"uses"`) {
+		t.Errorf("Expected a decode error naming uses, got %v", err)
+	}
+	// Writes checked against the ontology fail rather than allow any type
+	_, err := ps.ApplyBatch([]BatchOp{{Op: OpCreateEntity, Entity: "Go", EntityType: "technology"}})
+	if err == nil {
+		t.Error("A corrupt ontology should not be enforced as permissive")
+	}
+}
+
+func TestSetOntologyRejectsInconsistent(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	cases := map[string]models.Ontology{
+		"bad mode":       {Mode: "loose"},
+		"duplicate type": {EntityTypes: []models.EntityTypeDef{{Name: "a"}, {Name: "a"}}},
+		"unknown domain": {
+			EntityTypes:   []models.EntityTypeDef{{Name: "a"}},
+			RelationTypes: []models.RelationTypeDef{{Name: "r", SourceTypes: []string{"b"}}},
+		},
+		"self inverse": {RelationTypes: []models.RelationTypeDef{{Name: "r", Inverse: "r"}}},
+	}
+	for name, o := range cases {
+		if _, err := ps.SetOntology(o); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestOntologyLenient(t *testing.T) {
+	ps := setupProjectStore(t)
+	ps.SetOntology(testOntology(OntologyLenient))
+
+	// Lenient mode applies the batch and returns the violations as warnings
+	result, err := ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Memory Cloud", EntityType: "project"},
+		{Op: OpCreateEntity, Entity: "Go", EntityType: "Technology"},
+	})
+	if err != nil {
+		t.Fatalf("Lenient mode should not reject entities: %v", err)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], `did you mean "technology"`) {
+		t.Errorf("Expected one warning suggesting technology, got %v", result.Warnings)
+	}
+
+	result, err = ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateRelation, From: "Go", To: "Memory Cloud", RelationType: "used_by"},
+		{Op: OpCreateRelation, From: "Memory Cloud", To: "Go", RelationType: "uses"},
+	})
+	if err != nil {
+		t.Fatalf("Lenient mode should not reject relations: %v", err)
+	}
+	// used_by is the inverse of uses; uses targets technology, not Technology
+	if len(result.Warnings) != 2 || !strings.Contains(result.Warnings[0], "create Memory Cloud —uses→ Go instead") {
+		t.Errorf("Unexpected relation warnings: %v", result.Warnings)
+	}
+}
+
+func TestOntologyStrict(t *testing.T) {
+	ps := setupProjectStore(t)
+	ps.SetOntology(testOntology(OntologyStrict))
+
+	_, err := ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{{Name: "Go", EntityType: "tech"}})
+	var violation *OntologyViolationError
+	if !errors.As(err, &violation) {
+		t.Fatalf("Expected an OntologyViolationError, got %v", err)
+	}
+
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{
+		{Name: "Go", EntityType: "technology"},
+		{Name: "Ana", EntityType: "person"},
+		{Name: "Memory Cloud", EntityType: "project"},
+	})
+
+	_, err = ps.CreateRelations([]struct {
+		From         string
+		To           string
+		RelationType string
+	}{
+		{From: "Memory Cloud", To: "Go", RelationType: "uses"},
+		{From: "Ana", To: "Go", RelationType: "uses"},
+	})
+	if !errors.As(err, &violation) || len(violation.Violations) != 1 || !strings.Contains(violation.Violations[0], `source type "person"`) {
+		t.Fatalf("Expected a source type violation, got %v", err)
+	}
+	// The batch is rejected as a whole
+	graph, _ := ps.ReadGraph()
+	if len(graph.Relations) != 0 {
+		t.Errorf("No relation should be created, got %d", len(graph.Relations))
+	}
+
+	// Off disables enforcement
+	ps.SetOntology(testOntology(OntologyOff))
+	result, err := ps.ApplyBatch([]BatchOp{{Op: OpCreateEntity, Entity: "X", EntityType: "tech"}})
+	if err != nil || len(result.Warnings) != 0 {
+		t.Errorf("Ontology off should neither reject nor warn, got %v, %v", result, err)
+	}
+}
+
+func TestValidateGraphOntology(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{
+		{Name: "Go", EntityType: "technology", Observations: []string{"Linguagem"}},
+		{Name: "Memory Cloud", EntityType: "project", Observations: []string{"Servidor MCP"}},
+	})
+	ps.CreateRelations([]struct {
+		From         string
+		To           string
+		RelationType string
+	}{{From: "Go", To: "Memory Cloud", RelationType: "used_by"}})
+	ps.SetOntology(testOntology(OntologyStrict))
+
+	report, err := ps.ValidateGraph(ValidateOptions{Rules: []string{RuleOntologyRelation}, Fix: true})
+	if err != nil {
+		t.Fatalf("ValidateGraph: %v", err)
+	}
+	if len(report.Findings) != 1 || !report.Findings[0].Fixed {
+		t.Fatalf("Expected one fixed ontology finding, got %+v", report.Findings)
+	}
+
+	graph, _ := ps.ReadGraph()
+	rel := graph.Relations[0]
+	var memoryCloudID string
+	for _, e := range graph.Entities {
+		if e.Name == "Memory Cloud" {
+			memoryCloudID = e.ID
+		}
+	}
+	if rel.RelationType != "uses" || rel.FromEntity != memoryCloudID {
+		t.Errorf("Relation should be reversed to Memory Cloud —uses→ Go, got %+v", rel)
+	}
+}
@@ -2,10 +2,14 @@ package storage
 
 import (
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/google/uuid"
 	_ "github.com/ncruces/go-sqlite3/driver"
 	_ "github.com/ncruces/go-sqlite3/embed"
 
@@ -14,12 +18,36 @@ import (
 
 // ProjectStore manages a single project's knowledge graph database.
 type ProjectStore struct {
-	db *sql.DB
+	db       *sql.DB
+	embedder Embedder
+	indexer  *embedIndexer
+
+	// idempotencyWindow is how long results stored under an idempotency key
+	// are replayed; zero disables idempotency keys.
+	idempotencyWindow time.Duration
+
+	// limits bounds the size of writes (see Limits).
+	limits Limits
+
+	// stmts caches prepared statements for the hot read paths, keyed by query.
+	stmtMu sync.Mutex
+	stmts  map[string]*sql.Stmt
+
+	// base and changes are set on dry-run views (see DryRun).
+	base    *ProjectStore
+	changes *models.ChangeReport
+
+	// validAt and historical are set on views made by At.
+	validAt    string
+	historical bool
 }
 
 // OpenProject opens an existing project database and configures it.
 func OpenProject(dbPath string) (*ProjectStore, error) {
-	db, err := sql.Open("sqlite3", "file:"+dbPath+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)&_pragma=foreign_keys(ON)&_pragma=cache_size(-64000)")
+	// Transactions take the write lock when they begin, so one contending
+	// with the background embedding indexer waits out busy_timeout rather
+	// than failing when it first writes.
+	db, err := sql.Open("sqlite3", "file:"+dbPath+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)&_pragma=foreign_keys(ON)&_pragma=cache_size(-64000)&_txlock=immediate")
 	if err != nil {
 		return nil, fmt.Errorf("open project db: %w", err)
 	}
@@ -28,496 +56,370 @@ func OpenProject(dbPath string) (*ProjectStore, error) {
 		db.Close()
 		return nil, fmt.Errorf("ping project db: %w", err)
 	}
-	return &ProjectStore{db: db}, nil
+	// Bring databases created by older versions up to the current schema
+	if err := migrateProjectDB(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate project db: %w", err)
+	}
+	return &ProjectStore{db: db, embedder: NewHashEmbedder(), indexer: newEmbedIndexer(), idempotencyWindow: DefaultIdempotencyWindow, limits: DefaultLimits}, nil
+}
+
+// SetEmbedder replaces the embedder used to index observations for semantic search.
+func (p *ProjectStore) SetEmbedder(e Embedder) {
+	if e != nil {
+		p.embedder = e
+	}
 }
 
-// Close closes the project database connection.
+// Close waits for queued embeddings to be indexed, then closes the cached
+// statements and the project database connection. Closing a view does
+// nothing.
 func (p *ProjectStore) Close() error {
+	if p.base != nil {
+		return nil
+	}
+	p.waitIndexed(0)
+	p.stmtMu.Lock()
+	for _, stmt := range p.stmts {
+		stmt.Close()
+	}
+	p.stmts = nil
+	p.stmtMu.Unlock()
 	return p.db.Close()
 }
 
-// CreateEntities inserts entities with their optional initial observations.
-// Returns the created entities with their generated IDs.
+// CreateEntities creates entities with their optional initial observations,
+// as ApplyBatch does with a create_entity operation per entity. Returns the
+// created entities with their generated IDs.
 func (p *ProjectStore) CreateEntities(entities []struct {
 	Name         string
 	EntityType   string
 	Observations []string
 }) ([]models.Entity, error) {
-	tx, err := p.db.Begin()
-	if err != nil {
-		return nil, fmt.Errorf("begin tx: %w", err)
+	ops := make([]BatchOp, len(entities))
+	for i, e := range entities {
+		ops[i] = BatchOp{Op: OpCreateEntity, Entity: e.Name, EntityType: e.EntityType, Observations: e.Observations}
 	}
-	defer tx.Rollback()
-
-	var created []models.Entity
-
-	for _, e := range entities {
-		entityID := uuid.New().String()
-		_, err := tx.Exec(
-			`INSERT INTO entities (id, name, entity_type) VALUES (?, ?, ?)`,
-			entityID, e.Name, e.EntityType,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("insert entity %q: %w", e.Name, err)
-		}
-
-		entity := models.Entity{
-			ID:         entityID,
-			Name:       e.Name,
-			EntityType: e.EntityType,
-		}
-
-		for _, obsContent := range e.Observations {
-			obsID := uuid.New().String()
-			_, err := tx.Exec(
-				`INSERT INTO observations (id, entity_id, content) VALUES (?, ?, ?)`,
-				obsID, entityID, obsContent,
-			)
-			if err != nil {
-				return nil, fmt.Errorf("insert observation for %q: %w", e.Name, err)
-			}
-			entity.Observations = append(entity.Observations, models.Observation{
-				ID:       obsID,
-				EntityID: entityID,
-				Content:  obsContent,
-			})
-		}
-
-		// Re-read to get timestamps
-		row := tx.QueryRow(
-			`SELECT created_at, updated_at FROM entities WHERE id = ?`, entityID,
-		)
-		row.Scan(&entity.CreatedAt, &entity.UpdatedAt)
-
-		created = append(created, entity)
+	result, err := p.ApplyBatch(ops)
+	if err != nil {
+		return nil, err
 	}
-
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("commit: %w", err)
+	created := make([]models.Entity, len(result.Results))
+	for i, res := range result.Results {
+		created[i] = *res.Entity
 	}
 	return created, nil
 }
 
-// AddObservations adds observations to existing entities identified by name.
+// AddObservations adds observations to an existing entity identified by
+// name, as an add_observation operation of ApplyBatch.
 func (p *ProjectStore) AddObservations(entityName string, contents []string) ([]models.Observation, error) {
-	// Find the entity
-	var entityID string
-	err := p.db.QueryRow(
-		`SELECT id FROM entities WHERE name = ? AND deleted_at IS NULL`, entityName,
-	).Scan(&entityID)
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("entity %q not found", entityName)
-	}
+	result, err := p.ApplyBatch([]BatchOp{{Op: OpAddObservation, Entity: entityName, Observations: contents}})
 	if err != nil {
-		return nil, fmt.Errorf("lookup entity %q: %w", entityName, err)
-	}
-
-	tx, err := p.db.Begin()
-	if err != nil {
-		return nil, fmt.Errorf("begin tx: %w", err)
-	}
-	defer tx.Rollback()
-
-	var created []models.Observation
-	for _, content := range contents {
-		obsID := uuid.New().String()
-		_, err := tx.Exec(
-			`INSERT INTO observations (id, entity_id, content) VALUES (?, ?, ?)`,
-			obsID, entityID, content,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("insert observation: %w", err)
-		}
-		created = append(created, models.Observation{
-			ID:       obsID,
-			EntityID: entityID,
-			Content:  content,
-		})
-	}
-
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("commit: %w", err)
-	}
-
-	// Re-read to get timestamps
-	for i, obs := range created {
-		p.db.QueryRow(`SELECT created_at FROM observations WHERE id = ?`, obs.ID).Scan(&created[i].CreatedAt)
+		return nil, err
 	}
-
-	return created, nil
+	return result.Results[0].Observations, nil
 }
 
-// CreateRelations inserts directed relations between entities.
+// CreateRelations creates directed relations between entities, as ApplyBatch
+// does with a create_relation operation per relation.
 func (p *ProjectStore) CreateRelations(relations []struct {
 	From         string
 	To           string
 	RelationType string
 }) ([]models.Relation, error) {
-	tx, err := p.db.Begin()
-	if err != nil {
-		return nil, fmt.Errorf("begin tx: %w", err)
-	}
-	defer tx.Rollback()
-
-	var created []models.Relation
-
-	for _, r := range relations {
-		// Resolve entity names to IDs
-		var fromID, toID string
-		err := tx.QueryRow(`SELECT id FROM entities WHERE name = ? AND deleted_at IS NULL`, r.From).Scan(&fromID)
-		if err != nil {
-			return nil, fmt.Errorf("from entity %q not found: %w", r.From, err)
-		}
-		err = tx.QueryRow(`SELECT id FROM entities WHERE name = ? AND deleted_at IS NULL`, r.To).Scan(&toID)
-		if err != nil {
-			return nil, fmt.Errorf("to entity %q not found: %w", r.To, err)
-		}
-
-		relID := uuid.New().String()
-		_, err = tx.Exec(
-			`INSERT INTO relations (id, from_entity, to_entity, relation_type) VALUES (?, ?, ?, ?)`,
-			relID, fromID, toID, r.RelationType,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("insert relation: %w", err)
-		}
-		created = append(created, models.Relation{
-			ID:           relID,
-			FromEntity:   fromID,
-			ToEntity:     toID,
-			RelationType: r.RelationType,
-		})
+	ops := make([]BatchOp, len(relations))
+	for i, r := range relations {
+		ops[i] = BatchOp{Op: OpCreateRelation, From: r.From, To: r.To, RelationType: r.RelationType}
 	}
-
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("commit: %w", err)
+	result, err := p.ApplyBatch(ops)
+	if err != nil {
+		return nil, err
 	}
-
-	for i, rel := range created {
-		p.db.QueryRow(`SELECT created_at FROM relations WHERE id = ?`, rel.ID).Scan(&created[i].CreatedAt)
+	created := make([]models.Relation, len(result.Results))
+	for i, res := range result.Results {
+		created[i] = *res.Relation
 	}
-
 	return created, nil
 }
 
-// DeleteEntities soft-deletes entities and cascades to their observations and relations.
+// DeleteEntities soft-deletes entities and cascades to their observations,
+// properties and relations, as ApplyBatch does with a delete_entity
+// operation per name. Returns the number of entities deleted.
 func (p *ProjectStore) DeleteEntities(names []string) (int64, error) {
-	if len(names) == 0 {
-		return 0, nil
-	}
-
-	tx, err := p.db.Begin()
-	if err != nil {
-		return 0, fmt.Errorf("begin tx: %w", err)
-	}
-	defer tx.Rollback()
-
-	placeholders := make([]string, len(names))
-	args := make([]any, len(names))
+	ops := make([]BatchOp, len(names))
 	for i, name := range names {
-		placeholders[i] = "?"
-		args[i] = name
-	}
-	inClause := strings.Join(placeholders, ",")
-
-	// Get entity IDs for cascading
-	rows, err := tx.Query(
-		fmt.Sprintf(`SELECT id FROM entities WHERE name IN (%s) AND deleted_at IS NULL`, inClause),
-		args...,
-	)
-	if err != nil {
-		return 0, fmt.Errorf("query entities: %w", err)
-	}
-	var entityIDs []any
-	var idPlaceholders []string
-	for rows.Next() {
-		var id string
-		rows.Scan(&id)
-		entityIDs = append(entityIDs, id)
-		idPlaceholders = append(idPlaceholders, "?")
-	}
-	rows.Close()
-
-	if len(entityIDs) == 0 {
-		return 0, nil
-	}
-
-	idInClause := strings.Join(idPlaceholders, ",")
-
-	// Soft-delete observations
-	_, err = tx.Exec(
-		fmt.Sprintf(`UPDATE observations SET deleted_at = datetime('now') WHERE entity_id IN (%s) AND deleted_at IS NULL`, idInClause),
-		entityIDs...,
-	)
-	if err != nil {
-		return 0, fmt.Errorf("soft-delete observations: %w", err)
-	}
-
-	// Soft-delete relations (both from and to)
-	for _, eid := range entityIDs {
-		_, err = tx.Exec(
-			`UPDATE relations SET deleted_at = datetime('now') WHERE (from_entity = ? OR to_entity = ?) AND deleted_at IS NULL`,
-			eid, eid,
-		)
-		if err != nil {
-			return 0, fmt.Errorf("soft-delete relations: %w", err)
-		}
+		ops[i] = BatchOp{Op: OpDeleteEntity, Entity: name}
 	}
+	return p.applyDeletes(ops)
+}
 
-	// Soft-delete the entities
-	result, err := tx.Exec(
-		fmt.Sprintf(`UPDATE entities SET deleted_at = datetime('now'), updated_at = datetime('now') WHERE name IN (%s) AND deleted_at IS NULL`, inClause),
-		args...,
-	)
-	if err != nil {
-		return 0, fmt.Errorf("soft-delete entities: %w", err)
-	}
+// DeleteObservations soft-deletes the observations of an entity whose
+// content matches, as a delete_observation operation of ApplyBatch.
+func (p *ProjectStore) DeleteObservations(entityName string, contents []string) (int64, error) {
+	return p.applyDeletes([]BatchOp{{Op: OpDeleteObservation, Entity: entityName, Observations: contents}})
+}
 
-	count, _ := result.RowsAffected()
-	if err := tx.Commit(); err != nil {
-		return 0, fmt.Errorf("commit: %w", err)
+// DeleteRelations soft-deletes relations matching from/to entity names and
+// type, as ApplyBatch does with a delete_relation operation per relation.
+func (p *ProjectStore) DeleteRelations(relations []struct {
+	From         string
+	To           string
+	RelationType string
+}) (int64, error) {
+	ops := make([]BatchOp, len(relations))
+	for i, r := range relations {
+		ops[i] = BatchOp{Op: OpDeleteRelation, From: r.From, To: r.To, RelationType: r.RelationType}
 	}
-	return count, nil
+	return p.applyDeletes(ops)
 }
 
-// DeleteObservations soft-deletes observations matching entity name and content strings.
-func (p *ProjectStore) DeleteObservations(entityName string, contents []string) (int64, error) {
-	var entityID string
-	err := p.db.QueryRow(
-		`SELECT id FROM entities WHERE name = ? AND deleted_at IS NULL`, entityName,
-	).Scan(&entityID)
-	if err == sql.ErrNoRows {
-		return 0, fmt.Errorf("entity %q not found", entityName)
-	}
-	if err != nil {
-		return 0, fmt.Errorf("lookup entity: %w", err)
+// applyDeletes runs delete operations with ApplyBatch and returns the number
+// of rows they deleted.
+func (p *ProjectStore) applyDeletes(ops []BatchOp) (int64, error) {
+	if len(ops) == 0 {
+		return 0, nil
 	}
-
-	tx, err := p.db.Begin()
+	result, err := p.ApplyBatch(ops)
 	if err != nil {
-		return 0, fmt.Errorf("begin tx: %w", err)
+		return 0, err
 	}
-	defer tx.Rollback()
-
 	var total int64
-	for _, content := range contents {
-		result, err := tx.Exec(
-			`UPDATE observations SET deleted_at = datetime('now') WHERE entity_id = ? AND content = ? AND deleted_at IS NULL`,
-			entityID, content,
-		)
-		if err != nil {
-			return 0, fmt.Errorf("soft-delete observation: %w", err)
-		}
-		n, _ := result.RowsAffected()
-		total += n
-	}
-
-	if err := tx.Commit(); err != nil {
-		return 0, fmt.Errorf("commit: %w", err)
+	for _, res := range result.Results {
+		total += res.Deleted
 	}
 	return total, nil
 }
 
-// DeleteRelations soft-deletes relations matching from/to entity names and type.
-func (p *ProjectStore) DeleteRelations(relations []struct {
-	From         string
-	To           string
-	RelationType string
-}) (int64, error) {
-	tx, err := p.db.Begin()
-	if err != nil {
-		return 0, fmt.Errorf("begin tx: %w", err)
-	}
-	defer tx.Rollback()
+// GetEntities retrieves entities by name with their observations and relations.
+// Names are resolved like resolveEntity does (exact, alias, then
+// case/accent-insensitive); names that cannot be resolved are skipped.
+func (p *ProjectStore) GetEntities(names []string) ([]models.Entity, error) {
+	entities, _, err := p.LookupEntities(names)
+	return entities, err
+}
 
-	var total int64
-	for _, r := range relations {
-		// Resolve names to IDs
-		var fromID, toID string
-		err := tx.QueryRow(`SELECT id FROM entities WHERE name = ? AND deleted_at IS NULL`, r.From).Scan(&fromID)
-		if err != nil {
-			continue // skip if entity not found
-		}
-		err = tx.QueryRow(`SELECT id FROM entities WHERE name = ? AND deleted_at IS NULL`, r.To).Scan(&toID)
+// LookupEntities is GetEntities that also reports the names it could not
+// resolve, each with "did you mean" suggestions.
+func (p *ProjectStore) LookupEntities(names []string) ([]models.Entity, []*EntityNotFoundError, error) {
+	var ids []string
+	var missing []*EntityNotFoundError
+	seen := make(map[string]bool)
+	for _, name := range names {
+		id, err := resolveEntity(p.db, name)
 		if err != nil {
-			continue
+			var nf *EntityNotFoundError
+			if errors.As(err, &nf) {
+				missing = append(missing, nf)
+				continue
+			}
+			return nil, nil, err
 		}
-
-		result, err := tx.Exec(
-			`UPDATE relations SET deleted_at = datetime('now') WHERE from_entity = ? AND to_entity = ? AND relation_type = ? AND deleted_at IS NULL`,
-			fromID, toID, r.RelationType,
-		)
-		if err != nil {
-			return 0, fmt.Errorf("soft-delete relation: %w", err)
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
 		}
-		n, _ := result.RowsAffected()
-		total += n
 	}
 
-	if err := tx.Commit(); err != nil {
-		return 0, fmt.Errorf("commit: %w", err)
+	entities, err := p.getEntitiesByID(ids)
+	if err != nil {
+		return nil, nil, err
 	}
-	return total, nil
+	return entities, missing, nil
 }
 
-// GetEntities retrieves entities by exact name match with their observations and relations.
-func (p *ProjectStore) GetEntities(names []string) ([]models.Entity, error) {
-	if len(names) == 0 {
-		return nil, nil
-	}
+// ReadGraphOptions controls ReadGraphPage.
+type ReadGraphOptions struct {
+	// Limit caps the number of entities per page; 0 means no limit.
+	Limit int
+	// Cursor resumes after the last entity of a previous page.
+	Cursor string
+	// EntityTypes restricts entities, and the targets of their relations, to these types.
+	EntityTypes []string
+	// IncludeObservations and IncludeRelations select what is loaded besides
+	// the entities. A relation is returned on the page of its source entity.
+	IncludeObservations bool
+	IncludeRelations    bool
+	// MaxBytes ends the page early once its JSON size would exceed this many
+	// bytes; 0 means no limit. A page always holds at least one entity.
+	MaxBytes int
+	// Indent measures MaxBytes against the page marshaled with two-space
+	// indentation, as read_graph returns it by default, rather than compact.
+	Indent bool
+}
 
-	placeholders := make([]string, len(names))
-	args := make([]any, len(names))
-	for i, name := range names {
-		placeholders[i] = "?"
-		args[i] = name
+// graphElementSize returns the bytes v adds as an element of one of the
+// arrays of a marshaled KnowledgeGraph, separators and indentation included.
+func graphElementSize(v any, indent bool) int {
+	if !indent {
+		b, _ := json.Marshal(v)
+		return len(b) + 1
 	}
-	inClause := strings.Join(placeholders, ",")
+	// Elements sit two levels deep, each on a new line
+	b, _ := json.MarshalIndent(v, "    ", "  ")
+	return len(b) + len(",\n    ")
+}
 
-	rows, err := p.db.Query(
-		fmt.Sprintf(`SELECT id, name, entity_type, created_at, updated_at FROM entities WHERE name IN (%s) AND deleted_at IS NULL`, inClause),
-		args...,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("query entities: %w", err)
-	}
-	defer rows.Close()
+// ReadGraph returns the complete active knowledge graph.
+func (p *ProjectStore) ReadGraph() (*models.KnowledgeGraph, error) {
+	return p.ReadGraphPage(ReadGraphOptions{IncludeObservations: true, IncludeRelations: true})
+}
 
-	var entities []models.Entity
-	for rows.Next() {
-		var e models.Entity
-		if err := rows.Scan(&e.ID, &e.Name, &e.EntityType, &e.CreatedAt, &e.UpdatedAt); err != nil {
-			return nil, fmt.Errorf("scan entity: %w", err)
-		}
-		entities = append(entities, e)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+// ReadGraphPage returns one page of the active knowledge graph, ordered by
+// entity name. Observations and relations are loaded with one query per
+// chunk of entities rather than one per entity. NextCursor is set when more
+// entities follow.
+func (p *ProjectStore) ReadGraphPage(opts ReadGraphOptions) (*models.KnowledgeGraph, error) {
+	where := []string{"deleted_at IS NULL"}
+	var args []any
+	if len(opts.EntityTypes) > 0 {
+		where = append(where, "entity_type IN ("+inPlaceholders(len(opts.EntityTypes))+")")
+		args = append(args, stringArgs(opts.EntityTypes)...)
 	}
 
-	// Load observations and relations for each entity
-	for i := range entities {
-		obs, err := p.getObservations(entities[i].ID)
-		if err != nil {
-			return nil, err
-		}
-		entities[i].Observations = obs
+	graph := &models.KnowledgeGraph{Entities: []models.Entity{}, Relations: []models.Relation{}}
+	if err := p.db.QueryRow(
+		`SELECT COUNT(*) FROM entities WHERE `+strings.Join(where, " AND "), args...,
+	).Scan(&graph.TotalEntities); err != nil {
+		return nil, fmt.Errorf("count entities: %w", err)
+	}
 
-		rels, err := p.getRelations(entities[i].ID)
+	if opts.Cursor != "" {
+		keys, err := decodeCursor(opts.Cursor, 2)
 		if err != nil {
 			return nil, err
 		}
-		entities[i].Relations = rels
+		where = append(where, "(name > ? OR (name = ? AND id > ?))")
+		args = append(args, keys[0], keys[0], keys[1])
+	}
+	query := `SELECT id, name, entity_type, version, created_at, updated_at FROM entities WHERE ` +
+		strings.Join(where, " AND ") + ` ORDER BY name, id`
+	if opts.Limit > 0 {
+		// Fetch one extra row to know whether another page follows
+		query += ` LIMIT ?`
+		args = append(args, opts.Limit+1)
 	}
 
-	return entities, nil
-}
-
-// ReadGraph returns the complete active knowledge graph.
-func (p *ProjectStore) ReadGraph() (*models.KnowledgeGraph, error) {
-	// Load all active entities
-	rows, err := p.db.Query(
-		`SELECT id, name, entity_type, created_at, updated_at FROM entities WHERE deleted_at IS NULL ORDER BY name`,
-	)
+	rows, err := p.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query entities: %w", err)
 	}
-	defer rows.Close()
-
 	var entities []models.Entity
 	for rows.Next() {
 		var e models.Entity
-		if err := rows.Scan(&e.ID, &e.Name, &e.EntityType, &e.CreatedAt, &e.UpdatedAt); err != nil {
+		if err := rows.Scan(&e.ID, &e.Name, &e.EntityType, &e.Version, &e.CreatedAt, &e.UpdatedAt); err != nil {
+			rows.Close()
 			return nil, fmt.Errorf("scan entity: %w", err)
 		}
 		entities = append(entities, e)
 	}
+	rows.Close()
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	// Load observations for each entity
+	hasMore := opts.Limit > 0 && len(entities) > opts.Limit
+	if hasMore {
+		entities = entities[:opts.Limit]
+	}
+	ids := make([]string, len(entities))
+	for i, e := range entities {
+		ids[i] = e.ID
+	}
+
+	props, err := p.loadProperties(ids)
+	if err != nil {
+		return nil, err
+	}
 	for i := range entities {
-		obs, err := p.getObservations(entities[i].ID)
+		entities[i].Properties = propertyValues(props[entities[i].ID])
+	}
+	if opts.IncludeObservations {
+		obs, err := p.loadObservations(ids)
 		if err != nil {
 			return nil, err
 		}
-		entities[i].Observations = obs
+		for i := range entities {
+			entities[i].Observations = obs[entities[i].ID]
+		}
 	}
-
-	// Load all active relations
-	relRows, err := p.db.Query(
-		`SELECT r.id, r.from_entity, r.to_entity, r.relation_type, r.created_at
-		 FROM relations r
-		 WHERE r.deleted_at IS NULL
-		 ORDER BY r.created_at`,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("query relations: %w", err)
+	var relations []models.Relation
+	if opts.IncludeRelations {
+		relations, err = p.loadRelationsFrom(ids, opts.EntityTypes)
+		if err != nil {
+			return nil, err
+		}
 	}
-	defer relRows.Close()
 
-	var relations []models.Relation
-	for relRows.Next() {
-		var r models.Relation
-		if err := relRows.Scan(&r.ID, &r.FromEntity, &r.ToEntity, &r.RelationType, &r.CreatedAt); err != nil {
-			return nil, fmt.Errorf("scan relation: %w", err)
+	// Cut the page where it would outgrow MaxBytes
+	if opts.MaxBytes > 0 {
+		relSize := make(map[string]int)
+		for _, r := range relations {
+			relSize[r.FromEntity] += graphElementSize(r, opts.Indent)
+		}
+		size := 0
+		for i, e := range entities {
+			size += graphElementSize(e, opts.Indent) + relSize[e.ID]
+			if i > 0 && size > opts.MaxBytes {
+				entities = entities[:i]
+				hasMore = true
+				break
+			}
+		}
+		kept := make(map[string]bool, len(entities))
+		for _, e := range entities {
+			kept[e.ID] = true
+		}
+		var trimmed []models.Relation
+		for _, r := range relations {
+			if kept[r.FromEntity] {
+				trimmed = append(trimmed, r)
+			}
 		}
-		relations = append(relations, r)
+		relations = trimmed
 	}
-	if err := relRows.Err(); err != nil {
-		return nil, err
+
+	graph.Entities = append(graph.Entities, entities...)
+	graph.Relations = append(graph.Relations, relations...)
+	if hasMore && len(entities) > 0 {
+		last := entities[len(entities)-1]
+		graph.NextCursor = encodeCursor(last.Name, last.ID)
 	}
+	return graph, nil
+}
 
-	return &models.KnowledgeGraph{
-		Entities:  entities,
-		Relations: relations,
-	}, nil
+// encodeCursor builds an opaque page cursor from the sort keys of the last
+// row of a page.
+func encodeCursor(keys ...string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(keys, "\x00")))
 }
 
-// getObservations loads all active observations for an entity.
-func (p *ProjectStore) getObservations(entityID string) ([]models.Observation, error) {
-	rows, err := p.db.Query(
-		`SELECT id, entity_id, content, created_at FROM observations WHERE entity_id = ? AND deleted_at IS NULL ORDER BY created_at`,
-		entityID,
-	)
+// decodeCursor returns the n sort keys stored in a cursor.
+func decodeCursor(cursor string, n int) ([]string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
 	if err != nil {
-		return nil, fmt.Errorf("query observations: %w", err)
+		return nil, invalidf("invalid cursor %q", cursor)
 	}
-	defer rows.Close()
-
-	var obs []models.Observation
-	for rows.Next() {
-		var o models.Observation
-		if err := rows.Scan(&o.ID, &o.EntityID, &o.Content, &o.CreatedAt); err != nil {
-			return nil, fmt.Errorf("scan observation: %w", err)
-		}
-		obs = append(obs, o)
+	keys := strings.Split(string(raw), "\x00")
+	if len(keys) != n {
+		return nil, invalidf("invalid cursor %q", cursor)
 	}
-	return obs, rows.Err()
+	return keys, nil
 }
 
-// getRelations loads all active relations where the entity is either source or target.
-func (p *ProjectStore) getRelations(entityID string) ([]models.Relation, error) {
-	rows, err := p.db.Query(
-		`SELECT id, from_entity, to_entity, relation_type, created_at
-		 FROM relations
-		 WHERE (from_entity = ? OR to_entity = ?) AND deleted_at IS NULL
-		 ORDER BY created_at`,
-		entityID, entityID,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("query relations: %w", err)
+// inPlaceholders returns "?,?,..." with n placeholders for an IN clause.
+func inPlaceholders(n int) string {
+	if n == 0 {
+		return ""
 	}
-	defer rows.Close()
+	return strings.Repeat("?,", n-1) + "?"
+}
 
-	var rels []models.Relation
-	for rows.Next() {
-		var r models.Relation
-		if err := rows.Scan(&r.ID, &r.FromEntity, &r.ToEntity, &r.RelationType, &r.CreatedAt); err != nil {
-			return nil, fmt.Errorf("scan relation: %w", err)
-		}
-		rels = append(rels, r)
+// stringArgs converts strings to query arguments.
+func stringArgs(values []string) []any {
+	args := make([]any, len(values))
+	for i, v := range values {
+		args[i] = v
 	}
-	return rels, rows.Err()
+	return args
 }
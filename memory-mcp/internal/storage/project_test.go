@@ -1,8 +1,14 @@
 package storage
 
 import (
+	"encoding/json"
+	"errors"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
 )
 
 // setupProjectStore creates a fresh project DB in a temp directory and returns a ProjectStore.
@@ -116,6 +122,18 @@ func TestCreateRelations(t *testing.T) {
 	if rels[0].RelationType != "powers" {
 		t.Errorf("RelationType = %q, want %q", rels[0].RelationType, "powers")
 	}
+	if rels[0].FromName != "Go" || rels[0].ToName != "Memory Cloud" || rels[0].ToType != "project" {
+		t.Errorf("Relation endpoints not named: %+v", rels[0])
+	}
+
+	// Each endpoint sees the relation from its own side
+	entities, _ := ps.GetEntities([]string{"Go", "Memory Cloud"})
+	if len(entities[0].Outgoing) != 1 || len(entities[0].Incoming) != 0 {
+		t.Errorf("Go should have 1 outgoing relation, got %+v / %+v", entities[0].Outgoing, entities[0].Incoming)
+	}
+	if len(entities[1].Incoming) != 1 || entities[1].Incoming[0].FromName != "Go" || entities[1].Incoming[0].FromType != "technology" {
+		t.Errorf("Memory Cloud should have 1 incoming relation from Go, got %+v", entities[1].Incoming)
+	}
 }
 
 func TestDeleteEntities(t *testing.T) {
@@ -161,7 +179,7 @@ func TestDeleteEntities(t *testing.T) {
 	if len(entities) != 1 {
 		t.Fatal("Rust should still exist")
 	}
-	if len(entities[0].Relations) != 0 {
+	if len(entities[0].Outgoing)+len(entities[0].Incoming) != 0 {
 		t.Error("Relation involving deleted entity should be soft-deleted")
 	}
 }
@@ -230,7 +248,7 @@ func TestDeleteRelations(t *testing.T) {
 	if len(entities) != 1 {
 		t.Fatal("Go should still exist")
 	}
-	if len(entities[0].Relations) != 0 {
+	if len(entities[0].Outgoing)+len(entities[0].Incoming) != 0 {
 		t.Error("Deleted relation should not appear")
 	}
 }
@@ -286,6 +304,109 @@ func TestReadGraph(t *testing.T) {
 	}
 }
 
+func TestReadGraphPage(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{
+		{Name: "A", EntityType: "technology", Observations: []string{"a1", "a2"}},
+		{Name: "B", EntityType: "technology", Observations: []string{"b1"}},
+		{Name: "C", EntityType: "person"},
+		{Name: "D", EntityType: "technology"},
+		{Name: "E", EntityType: "person"},
+	})
+	ps.CreateRelations([]struct {
+		From         string
+		To           string
+		RelationType string
+	}{
+		{From: "A", To: "B", RelationType: "uses"},
+		{From: "C", To: "A", RelationType: "maintains"},
+		{From: "D", To: "C", RelationType: "credits"},
+	})
+
+	// Walk every page and check each entity and relation appears exactly once
+	var names []string
+	relations := 0
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("Pagination did not terminate")
+		}
+		page, err := ps.ReadGraphPage(ReadGraphOptions{Limit: 2, Cursor: cursor, IncludeObservations: true, IncludeRelations: true})
+		if err != nil {
+			t.Fatalf("ReadGraphPage: %v", err)
+		}
+		if page.TotalEntities != 5 {
+			t.Errorf("TotalEntities = %d, want 5", page.TotalEntities)
+		}
+		for _, e := range page.Entities {
+			names = append(names, e.Name)
+			if e.Name == "A" && len(e.Observations) != 2 {
+				t.Errorf("A should have 2 observations, got %d", len(e.Observations))
+			}
+		}
+		relations += len(page.Relations)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if strings.Join(names, ",") != "A,B,C,D,E" {
+		t.Errorf("Pages returned %v, want A..E in order", names)
+	}
+	if relations != 3 {
+		t.Errorf("Expected 3 relations across pages, got %d", relations)
+	}
+
+	// Type filter keeps only relations among matching entities
+	page, _ := ps.ReadGraphPage(ReadGraphOptions{EntityTypes: []string{"technology"}, IncludeRelations: true})
+	if len(page.Entities) != 3 || page.TotalEntities != 3 || len(page.Relations) != 1 {
+		t.Errorf("Expected 3 technologies and 1 relation, got %d / %d", len(page.Entities), len(page.Relations))
+	}
+	if page.Relations[0].FromName != "A" || page.Relations[0].ToName != "B" {
+		t.Errorf("Relation names not filled: %+v", page.Relations[0])
+	}
+
+	// Without includes only the entities are loaded
+	page, _ = ps.ReadGraphPage(ReadGraphOptions{})
+	if len(page.Entities) != 5 || len(page.Relations) != 0 || page.Entities[0].Observations != nil {
+		t.Errorf("Expected bare entities, got %+v", page)
+	}
+
+	// A byte budget cuts the page early but always returns one entity
+	page, _ = ps.ReadGraphPage(ReadGraphOptions{MaxBytes: 1, IncludeObservations: true})
+	if len(page.Entities) != 1 || page.NextCursor == "" {
+		t.Errorf("Expected a single entity and a cursor, got %d entities, cursor %q", len(page.Entities), page.NextCursor)
+	}
+
+	// The budget holds for the page as returned, indented or compact
+	full, _ := ps.ReadGraphPage(ReadGraphOptions{IncludeObservations: true, IncludeRelations: true})
+	compact, _ := json.Marshal(full)
+	for _, indent := range []bool{false, true} {
+		opts := ReadGraphOptions{MaxBytes: len(compact), Indent: indent, IncludeObservations: true, IncludeRelations: true}
+		page, _ = ps.ReadGraphPage(opts)
+		data, _ := json.Marshal(page)
+		if indent {
+			data, _ = json.MarshalIndent(page, "", "  ")
+		}
+		if indent == (page.NextCursor == "") {
+			t.Errorf("Indent %v: expected a cursor only for the indented page, got %q", indent, page.NextCursor)
+		}
+		// Allow for the counts and cursor around the arrays
+		if len(data) > opts.MaxBytes+200 {
+			t.Errorf("Indent %v: page of %d bytes exceeds the %d byte budget", indent, len(data), opts.MaxBytes)
+		}
+	}
+
+	if _, err := ps.ReadGraphPage(ReadGraphOptions{Cursor: "not a cursor!"}); err == nil {
+		t.Error("Expected an error for an invalid cursor")
+	}
+}
+
 func TestSearchFTS(t *testing.T) {
 	ps := setupProjectStore(t)
 
@@ -337,3 +458,54 @@ func TestSearchFTS(t *testing.T) {
 		t.Errorf("Expected 2 results for 'technology', got %d", len(results))
 	}
 }
+
+func TestSearchFiltered(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	high, low := 0.9, 0.3
+	if _, err := ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Go", EntityType: "technology", Observations: []string{"Fast compiled language"},
+			Meta: models.ObservationMeta{Source: "meeting-2026-03", Author: "ana", Confidence: &high, Tags: []string{"meeting", "decision"}}},
+		{Op: OpCreateEntity, Entity: "Python", EntityType: "technology", Observations: []string{"Dynamic scripting language"},
+			Meta: models.ObservationMeta{Author: "model", Confidence: &low, Tags: []string{"inferred"}}},
+		{Op: OpCreateEntity, Entity: "Rust", EntityType: "technology", Observations: []string{"Compiled language without GC"}},
+	}); err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		filter ObservationFilter
+		want   []string
+	}{
+		{"no filter", "language", ObservationFilter{}, []string{"Go", "Python", "Rust"}},
+		{"source", "language", ObservationFilter{Source: "meeting-2026-03"}, []string{"Go"}},
+		{"author", "", ObservationFilter{Author: "model"}, []string{"Python"}},
+		{"min confidence", "", ObservationFilter{MinConfidence: 0.5}, []string{"Go"}},
+		{"all tags", "", ObservationFilter{Tags: []string{"decision", "meeting"}}, []string{"Go"}},
+		{"missing tag", "", ObservationFilter{Tags: []string{"meeting", "inferred"}}, nil},
+		{"entity name match", "Python", ObservationFilter{Tags: []string{"inferred"}}, []string{"Python"}},
+		{"entity name without passing observation", "Rust", ObservationFilter{Author: "ana"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := ps.SearchFiltered(tt.query, tt.filter)
+			if err != nil {
+				t.Fatalf("SearchFiltered: %v", err)
+			}
+			var names []string
+			for _, e := range results {
+				names = append(names, e.Name)
+			}
+			sort.Strings(names)
+			if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Got %v, want %v", names, tt.want)
+			}
+		})
+	}
+
+	if _, err := ps.SearchFiltered(" ", ObservationFilter{}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("An empty search should be invalid, got %v", err)
+	}
+}
@@ -0,0 +1,435 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+// Property value types.
+const (
+	PropertyString = "string"
+	PropertyNumber = "number"
+	PropertyDate   = "date"
+	PropertyBool   = "bool"
+	PropertyJSON   = "json"
+)
+
+// Property filter operators. Numbers compare numerically, strings and dates
+// as text; = ignores ASCII case on strings and contains matches a substring
+// of string and json values.
+const (
+	PropertyEq       = "="
+	PropertyLt       = "<"
+	PropertyLte      = "<="
+	PropertyGt       = ">"
+	PropertyGte      = ">="
+	PropertyContains = "contains"
+)
+
+// jsonValue converts v to what decoding its JSON gives (float64 for every
+// number, []any and map[string]any for composites), so that values from Go
+// callers and from tool arguments are handled alike.
+func jsonValue(v any) (any, error) {
+	switch v.(type) {
+	case nil, string, float64, bool:
+		return v, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, invalidf("property value is not JSON: %v", err)
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, fmt.Errorf("decode property value: %w", err)
+	}
+	return out, nil
+}
+
+// encodeProperty checks v against typ and returns the type and stored text
+// of the value. An empty typ is inferred from v: strings, numbers and bools
+// map to their own types and anything else to json. Dates accept the formats
+// of valid_from.
+func (l Limits) encodeProperty(typ string, v any) (string, string, error) {
+	v, err := jsonValue(v)
+	if err != nil {
+		return "", "", err
+	}
+	if typ == "" {
+		switch v.(type) {
+		case string:
+			typ = PropertyString
+		case float64:
+			typ = PropertyNumber
+		case bool:
+			typ = PropertyBool
+		default:
+			typ = PropertyJSON
+		}
+	}
+	switch typ {
+	case PropertyString:
+		s, ok := v.(string)
+		if !ok {
+			return "", "", invalidf("property value %v is not a string", v)
+		}
+		if err := checkText("property value", &s, l.MaxObservationLength, true); err != nil {
+			return "", "", err
+		}
+		return typ, s, nil
+	case PropertyNumber:
+		var n float64
+		switch x := v.(type) {
+		case float64:
+			n = x
+		case string:
+			if n, err = strconv.ParseFloat(strings.TrimSpace(x), 64); err != nil {
+				return "", "", invalidf("property value %q is not a number", x)
+			}
+		default:
+			return "", "", invalidf("property value %v is not a number", v)
+		}
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			return "", "", invalidf("property value %v is not a finite number", n)
+		}
+		return typ, strconv.FormatFloat(n, 'f', -1, 64), nil
+	case PropertyDate:
+		s, ok := v.(string)
+		if !ok {
+			return "", "", invalidf("property value %v is not a date", v)
+		}
+		d, err := normalizeValidTime("property value", s)
+		if err != nil {
+			return "", "", err
+		}
+		if d == "" {
+			return "", "", invalidf("property value is required")
+		}
+		return typ, d, nil
+	case PropertyBool:
+		switch x := v.(type) {
+		case bool:
+			return typ, strconv.FormatBool(x), nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(x)); err == nil {
+				return typ, strconv.FormatBool(b), nil
+			}
+		}
+		return "", "", invalidf("property value %v is not a bool", v)
+	case PropertyJSON:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", "", invalidf("property value is not JSON: %v", err)
+		}
+		s := string(b)
+		if err := checkText("property value", &s, l.MaxObservationLength, true); err != nil {
+			return "", "", err
+		}
+		return typ, s, nil
+	}
+	return "", "", invalidf("unknown property type %q: use string, number, date, bool or json", typ)
+}
+
+// decodeProperty returns the value of a property stored as text.
+func decodeProperty(typ, text string) any {
+	switch typ {
+	case PropertyNumber:
+		if n, err := strconv.ParseFloat(text, 64); err == nil {
+			return n
+		}
+	case PropertyBool:
+		return text == "true"
+	case PropertyJSON:
+		var v any
+		if err := json.Unmarshal([]byte(text), &v); err == nil {
+			return v
+		}
+	}
+	return text
+}
+
+const propertiesQuery = `SELECT entity_id, key, value_type, value, updated_at FROM entity_properties
+	WHERE entity_id IN (SELECT value FROM json_each(?)) AND deleted_at IS NULL
+	ORDER BY key`
+
+// loadProperties loads the active properties of many entities, keyed by
+// entity ID.
+func (p *ProjectStore) loadProperties(ids []string) (map[string][]models.Property, error) {
+	props := make(map[string][]models.Property, len(ids))
+	if len(ids) == 0 {
+		return props, nil
+	}
+	rows, err := p.queryPrepared(propertiesQuery, jsonArray(ids))
+	if err != nil {
+		return nil, fmt.Errorf("query properties: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, text string
+		var prop models.Property
+		if err := rows.Scan(&id, &prop.Key, &prop.Type, &text, &prop.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan property: %w", err)
+		}
+		prop.Value = decodeProperty(prop.Type, text)
+		props[id] = append(props[id], prop)
+	}
+	return props, rows.Err()
+}
+
+// propertyValues returns properties as a map of values by key, or nil when
+// there are none.
+func propertyValues(props []models.Property) map[string]any {
+	if len(props) == 0 {
+		return nil
+	}
+	values := make(map[string]any, len(props))
+	for _, prop := range props {
+		values[prop.Key] = prop.Value
+	}
+	return values
+}
+
+// GetProperties returns the typed properties of the named entities, in the
+// order of names, restricted to keys when it is not empty.
+func (p *ProjectStore) GetProperties(names []string, keys []string) ([]models.EntityProperties, error) {
+	ids := make([]string, len(names))
+	for i, name := range names {
+		id, err := resolveEntity(p.db, normalizeText(name))
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	props, err := p.loadProperties(ids)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		wanted[normalizeText(key)] = true
+	}
+
+	result := make([]models.EntityProperties, len(ids))
+	for i, id := range ids {
+		name, _, err := entityNameType(p.db, id)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = models.EntityProperties{Entity: name, Properties: []models.Property{}}
+		for _, prop := range props[id] {
+			if len(wanted) == 0 || wanted[prop.Key] {
+				result[i].Properties = append(result[i].Properties, prop)
+			}
+		}
+	}
+	return result, nil
+}
+
+// PropertyFilter restricts a search to entities whose property Key compares
+// to Value with Op (= by default). A nil Value matches any entity that has
+// the property.
+type PropertyFilter struct {
+	Key   string
+	Op    string
+	Value any
+}
+
+var propertyOps = map[string]string{
+	PropertyEq: "=", PropertyLt: "<", PropertyLte: "<=", PropertyGt: ">", PropertyGte: ">=",
+}
+
+// condition returns the condition of f on entities aliased e, with its
+// arguments.
+func (f PropertyFilter) condition() (string, []any, error) {
+	key := normalizeText(f.Key)
+	if key == "" {
+		return "", nil, invalidf("property filter key is required")
+	}
+	op := f.Op
+	if op == "" {
+		op = PropertyEq
+	}
+	sqlOp, ordered := propertyOps[op]
+	if !ordered && op != PropertyContains {
+		return "", nil, invalidf("unknown property operator %q: use =, <, <=, >, >= or contains", f.Op)
+	}
+	v, err := jsonValue(f.Value)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var cond string
+	var args []any
+	switch x := v.(type) {
+	case nil:
+		if op != PropertyEq {
+			return "", nil, invalidf("property filter %q %s needs a value", key, op)
+		}
+		cond = "1"
+	case string:
+		s := normalizeText(x)
+		switch {
+		case op == PropertyContains:
+			cond = "p.value_type IN ('string', 'json') AND instr(lower(p.value), lower(?)) > 0"
+			args = []any{s}
+		default:
+			// Dates are compared in their stored form
+			date := s
+			if d, err := normalizeValidTime("value", s); err == nil {
+				date = d
+			}
+			collate := ""
+			if op == PropertyEq {
+				collate = " COLLATE NOCASE"
+			}
+			cond = fmt.Sprintf("(p.value_type = 'string' AND p.value %s ?%s) OR (p.value_type = 'date' AND p.value %s ?)", sqlOp, collate, sqlOp)
+			args = []any{s, date}
+		}
+	case float64:
+		if op == PropertyContains {
+			return "", nil, invalidf("contains needs a string value")
+		}
+		cond = fmt.Sprintf("p.value_type = 'number' AND CAST(p.value AS REAL) %s ?", sqlOp)
+		args = []any{x}
+	case bool:
+		if op != PropertyEq {
+			return "", nil, invalidf("bool properties only support =")
+		}
+		cond = "p.value_type = 'bool' AND p.value = ?"
+		args = []any{strconv.FormatBool(x)}
+	default:
+		if op != PropertyEq {
+			return "", nil, invalidf("json properties only support = and contains")
+		}
+		b, _ := json.Marshal(x)
+		cond = "p.value_type = 'json' AND p.value = ?"
+		args = []any{string(b)}
+	}
+	return `EXISTS (SELECT 1 FROM entity_properties p
+		WHERE p.entity_id = e.id AND p.deleted_at IS NULL AND p.key = ? AND (` + cond + `))`,
+		append([]any{key}, args...), nil
+}
+
+// propertyConditions joins the conditions of every filter.
+func propertyConditions(filters []PropertyFilter) (string, []any, error) {
+	var conds []string
+	var args []any
+	for _, f := range filters {
+		cond, condArgs, err := f.condition()
+		if err != nil {
+			return "", nil, err
+		}
+		conds = append(conds, cond)
+		args = append(args, condArgs...)
+	}
+	return strings.Join(conds, " AND "), args, nil
+}
+
+// propertyLinePattern matches observations written as "Key: value": a key of
+// up to 40 characters starting with a letter, a colon and a space, and a
+// value on the same line.
+var propertyLinePattern = regexp.MustCompile(`^(\p{L}[\p{L}\p{N} _-]{0,39}):\s+(\S.*)$`)
+
+// Inferred value types of property candidates.
+var (
+	numberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?$`)
+	datePattern   = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}(-[0-9]{2})?$`)
+)
+
+// maxPropertyKeyWords keeps sentences with a colon from being taken for
+// "Key: value" observations.
+const maxPropertyKeyWords = 4
+
+// propertyCandidate parses an observation written as "Key: value" into a
+// snake_case key and a typed value.
+func propertyCandidate(content string) (key, typ string, value any, ok bool) {
+	m := propertyLinePattern.FindStringSubmatch(content)
+	if m == nil {
+		return "", "", nil, false
+	}
+	words := strings.FieldsFunc(strings.ToLower(m[1]), func(r rune) bool { return r == ' ' || r == '_' || r == '-' })
+	if len(words) == 0 || len(words) > maxPropertyKeyWords {
+		return "", "", nil, false
+	}
+	key = strings.Join(words, "_")
+
+	text := strings.TrimSpace(m[2])
+	switch {
+	case text == "true" || text == "false":
+		return key, PropertyBool, text == "true", true
+	case numberPattern.MatchString(text):
+		n, _ := strconv.ParseFloat(text, 64)
+		return key, PropertyNumber, n, true
+	case datePattern.MatchString(text):
+		if d, err := normalizeValidTime("value", text); err == nil {
+			return key, PropertyDate, d, true
+		}
+	}
+	return key, PropertyString, text, true
+}
+
+// PropertyCandidates lists the active observations of the named entities
+// (every entity when names is empty) that are written as "Key: value" and
+// could become properties. Keys that appear in several observations of the
+// same entity are left out, as converting them would keep only one value.
+func (p *ProjectStore) PropertyCandidates(names []string) ([]models.PropertyCandidate, error) {
+	ids := make([]string, len(names))
+	for i, name := range names {
+		id, err := resolveEntity(p.db, normalizeText(name))
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	rows, err := p.db.Query(
+		`SELECT e.name, o.content FROM observations o
+		 JOIN entities e ON e.id = o.entity_id AND e.deleted_at IS NULL
+		 WHERE o.deleted_at IS NULL AND o.content LIKE '%: %'
+		   AND (?1 = '[]' OR o.entity_id IN (SELECT value FROM json_each(?1)))
+		 ORDER BY e.name, o.created_at, o.rowid`,
+		jsonArray(ids),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query observations: %w", err)
+	}
+	defer rows.Close()
+
+	type entityKey struct{ entity, key string }
+	count := make(map[entityKey]int)
+	var candidates []models.PropertyCandidate
+	for rows.Next() {
+		var c models.PropertyCandidate
+		if err := rows.Scan(&c.Entity, &c.Observation); err != nil {
+			return nil, fmt.Errorf("scan observation: %w", err)
+		}
+		var ok bool
+		if c.Key, c.Type, c.Value, ok = propertyCandidate(c.Observation); !ok {
+			continue
+		}
+		count[entityKey{c.Entity, c.Key}]++
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := []models.PropertyCandidate{}
+	for _, c := range candidates {
+		if count[entityKey{c.Entity, c.Key}] == 1 {
+			result = append(result, c)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Entity != result[j].Entity {
+			return result[i].Entity < result[j].Entity
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result, nil
+}
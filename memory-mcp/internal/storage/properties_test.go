@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSetProperties(t *testing.T) {
+	ps := setupProjectStore(t)
+	ps.ApplyBatch([]BatchOp{{Op: OpCreateEntity, Entity: "Acme", EntityType: "company"}})
+
+	result, err := ps.ApplyBatch([]BatchOp{
+		{Op: OpSetProperty, Entity: "Acme", Key: " sector ", Value: "logística"},
+		{Op: OpSetProperty, Entity: "Acme", Key: "employees", Value: 120},
+		{Op: OpSetProperty, Entity: "Acme", Key: "founded", Value: "1998-03", PropertyType: PropertyDate},
+		{Op: OpSetProperty, Entity: "Acme", Key: "public", Value: "true", PropertyType: PropertyBool},
+		{Op: OpSetProperty, Entity: "Acme", Key: "stack", Value: []string{"Java", "AWS"}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+	if res := result.Results[0]; res.Status != StatusCreated || res.Property.Key != "sector" {
+		t.Errorf("Expected the sector property created, got %+v", res)
+	}
+
+	entities, _ := ps.GetEntities([]string{"Acme"})
+	props := entities[0].Properties
+	if props["sector"] != "logística" || props["employees"] != 120.0 || props["founded"] != "1998-03-01" || props["public"] != true {
+		t.Errorf("Unexpected properties %v", props)
+	}
+	if stack, ok := props["stack"].([]any); !ok || len(stack) != 2 || stack[0] != "Java" {
+		t.Errorf("stack = %v, want the JSON array", props["stack"])
+	}
+
+	typed, err := ps.GetProperties([]string{"Acme"}, []string{"founded", "employees"})
+	if err != nil {
+		t.Fatalf("GetProperties: %v", err)
+	}
+	if got := typed[0].Properties; len(got) != 2 || got[0].Key != "employees" || got[0].Type != PropertyNumber || got[1].Type != PropertyDate {
+		t.Errorf("Expected the two typed properties ordered by key, got %+v", got)
+	}
+
+	// Setting again updates, a nil value removes
+	version := entities[0].Version
+	result, err = ps.ApplyBatch([]BatchOp{
+		{Op: OpSetProperty, Entity: "Acme", Key: "employees", Value: 150},
+		{Op: OpSetProperty, Entity: "Acme", Key: "public", Value: nil},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+	if result.Results[0].Status != StatusUpdated || result.Results[1].Status != StatusDeleted {
+		t.Errorf("Expected updated and deleted, got %+v", result.Results)
+	}
+	entities, _ = ps.GetEntities([]string{"Acme"})
+	if props := entities[0].Properties; props["employees"] != 150.0 || props["public"] != nil {
+		t.Errorf("Unexpected properties after update %v", props)
+	}
+	if entities[0].Version <= version {
+		t.Errorf("Property changes should bump the entity version, still %d", entities[0].Version)
+	}
+
+	tests := []struct {
+		name string
+		op   BatchOp
+		want string
+	}{
+		{"missing key", BatchOp{Op: OpSetProperty, Entity: "Acme", Value: "x"}, StatusInvalid},
+		{"not a number", BatchOp{Op: OpSetProperty, Entity: "Acme", Key: "n", Value: "many", PropertyType: PropertyNumber}, StatusInvalid},
+		{"not a date", BatchOp{Op: OpSetProperty, Entity: "Acme", Key: "d", Value: "soon", PropertyType: PropertyDate}, StatusInvalid},
+		{"unknown type", BatchOp{Op: OpSetProperty, Entity: "Acme", Key: "x", Value: "y", PropertyType: "money"}, StatusInvalid},
+		{"remove missing", BatchOp{Op: OpSetProperty, Entity: "Acme", Key: "public"}, StatusNotFound},
+		{"unknown entity", BatchOp{Op: OpSetProperty, Entity: "Nobody", Key: "x", Value: "y"}, StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ps.ApplyBatchItems([]BatchOp{tt.op}, ModeBestEffort)
+			if err != nil {
+				t.Fatalf("ApplyBatchItems: %v", err)
+			}
+			if res := result.Results[0]; res.Status != tt.want {
+				t.Errorf("Expected %s, got %+v", tt.want, res)
+			}
+		})
+	}
+
+	// Deleting the entity removes its properties from search
+	ps.ApplyBatch([]BatchOp{{Op: OpDeleteEntity, Entity: "Acme"}})
+	if found, _ := ps.SearchWithProperties("", ObservationFilter{}, []PropertyFilter{{Key: "sector"}}); len(found) != 0 {
+		t.Errorf("A deleted entity should not be found by its properties, got %d", len(found))
+	}
+}
+
+func TestSearchWithProperties(t *testing.T) {
+	ps := setupProjectStore(t)
+	ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Billing", EntityType: "service", Observations: []string{"Handles invoices"}},
+		{Op: OpSetProperty, Entity: "Billing", Key: "stack", Value: "Java"},
+		{Op: OpSetProperty, Entity: "Billing", Key: "replicas", Value: 3},
+		{Op: OpSetProperty, Entity: "Billing", Key: "launched", Value: "2024-05-10", PropertyType: PropertyDate},
+		{Op: OpCreateEntity, Entity: "Search", EntityType: "service", Observations: []string{"Indexes invoices"}},
+		{Op: OpSetProperty, Entity: "Search", Key: "stack", Value: "Go + AWS"},
+		{Op: OpSetProperty, Entity: "Search", Key: "replicas", Value: 8},
+		{Op: OpSetProperty, Entity: "Search", Key: "public", Value: true},
+	})
+
+	tests := []struct {
+		name    string
+		query   string
+		filters []PropertyFilter
+		want    []string
+	}{
+		{"equal ignores case", "", []PropertyFilter{{Key: "stack", Value: "java"}}, []string{"Billing"}},
+		{"contains", "", []PropertyFilter{{Key: "stack", Op: PropertyContains, Value: "aws"}}, []string{"Search"}},
+		{"number", "", []PropertyFilter{{Key: "replicas", Op: PropertyGt, Value: 4}}, []string{"Search"}},
+		{"date", "", []PropertyFilter{{Key: "launched", Op: PropertyGte, Value: "2024-05"}}, []string{"Billing"}},
+		{"bool", "", []PropertyFilter{{Key: "public", Value: true}}, []string{"Search"}},
+		{"has key", "", []PropertyFilter{{Key: "replicas"}}, []string{"Billing", "Search"}},
+		{"all filters", "", []PropertyFilter{{Key: "replicas", Op: PropertyLt, Value: 10}, {Key: "stack", Value: "Java"}}, []string{"Billing"}},
+		{"with query", "invoices", []PropertyFilter{{Key: "replicas", Op: PropertyLte, Value: 3}}, []string{"Billing"}},
+		{"number is not a string", "", []PropertyFilter{{Key: "replicas", Value: "3"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := ps.SearchWithProperties(tt.query, ObservationFilter{}, tt.filters)
+			if err != nil {
+				t.Fatalf("SearchWithProperties: %v", err)
+			}
+			var names []string
+			for _, e := range found {
+				names = append(names, e.Name)
+			}
+			if len(names) != len(tt.want) {
+				t.Fatalf("Got %v, want %v", names, tt.want)
+			}
+			for i := range names {
+				if names[i] != tt.want[i] {
+					t.Errorf("Got %v, want %v", names, tt.want)
+				}
+			}
+		})
+	}
+
+	for _, f := range []PropertyFilter{{Value: "x"}, {Key: "stack", Op: "like", Value: "J%"}, {Key: "public", Op: PropertyGt, Value: true}, {Key: "replicas", Op: PropertyGt}} {
+		if _, err := ps.SearchWithProperties("", ObservationFilter{}, []PropertyFilter{f}); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Filter %+v should be invalid, got %v", f, err)
+		}
+	}
+}
+
+func TestPropertyCandidates(t *testing.T) {
+	ps := setupProjectStore(t)
+	ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Acme", EntityType: "company", Observations: []string{
+			"Setor: logística",
+			"Stack: Java + AWS",
+			"Funcionários: 120",
+			"Data de fundação: 1998-03-02",
+			"Docs: https://acme.example.com",
+			"Meeting moved to 10:30",
+			"Note to self after the long meeting with the team: ask about budget",
+			"Contact: Ana",
+			"Contact: Bruno",
+		}},
+	})
+
+	candidates, err := ps.PropertyCandidates(nil)
+	if err != nil {
+		t.Fatalf("PropertyCandidates: %v", err)
+	}
+	got := make(map[string]any)
+	for _, c := range candidates {
+		got[c.Key+"/"+c.Type] = c.Value
+	}
+	want := map[string]any{
+		"data_de_fundação/date": "1998-03-02",
+		"docs/string":           "https://acme.example.com",
+		"funcionários/number":   120.0,
+		"setor/string":          "logística",
+		"stack/string":          "Java + AWS",
+	}
+	if len(got) != len(want) {
+		t.Errorf("Got candidates %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Candidate %s = %v, want %v", k, got[k], v)
+		}
+	}
+
+	if _, err := ps.PropertyCandidates([]string{"Nobody"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("An unknown entity should not be found, got %v", err)
+	}
+}
@@ -0,0 +1,301 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// suggestionThreshold is the minimum similarity for a name to be offered
+	// as a "did you mean" suggestion.
+	suggestionThreshold = 0.3
+	maxSuggestions      = 5
+	// maxSuggestionCandidates bounds the names scanned for suggestions; the
+	// most recently updated entities are scanned first.
+	maxSuggestionCandidates = 5000
+)
+
+// querier is the subset of *sql.DB and *sql.Tx used by lookups that must work
+// both inside and outside a transaction.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// EntityNotFoundError reports a name that could not be resolved, with the
+// closest existing entity names so the client can retry.
+type EntityNotFoundError struct {
+	Name        string   `json:"name"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+func (e *EntityNotFoundError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return fmt.Sprintf("entity %q not found", e.Name)
+	}
+	quoted := make([]string, len(e.Suggestions))
+	for i, s := range e.Suggestions {
+		quoted[i] = fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("entity %q not found; did you mean %s?", e.Name, strings.Join(quoted, ", "))
+}
+
+// resolveEntity maps a client-supplied name to an active entity ID. It tries,
+// in order: an exact match, an exact alias match, and a case- and
+// accent-insensitive match that is unambiguous. Names that are merely
+// similar are never used: the result is an *EntityNotFoundError suggesting
+// them, so that the client can retry with the intended name.
+func resolveEntity(q querier, name string) (string, error) {
+	id, err := resolveExact(q, name)
+	if err == nil {
+		return id, nil
+	}
+	var nf *EntityNotFoundError
+	if !errors.As(err, &nf) || len(nf.Suggestions) > 0 {
+		return "", err
+	}
+
+	rows, err := q.Query(
+		`SELECT id, name FROM entities WHERE name_fold = ? AND deleted_at IS NULL ORDER BY name`,
+		foldText(strings.TrimSpace(name)),
+	)
+	if err != nil {
+		return "", fmt.Errorf("lookup entity %q: %w", name, err)
+	}
+	defer rows.Close()
+	var foldMatches []entityName
+	for rows.Next() {
+		var c entityName
+		if err := rows.Scan(&c.id, &c.name); err != nil {
+			return "", fmt.Errorf("scan entity name: %w", err)
+		}
+		foldMatches = append(foldMatches, c)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("lookup entity %q: %w", name, err)
+	}
+	if len(foldMatches) == 1 {
+		return foldMatches[0].id, nil
+	}
+	if len(foldMatches) > 1 {
+		// Several entities differ only by case/accents; let the client pick
+		return "", ambiguousName(name, foldMatches)
+	}
+	return "", notFoundWithSuggestions(q, name)
+}
+
+// resolveExact maps a name to an active entity ID by exact name or alias
+// only. Otherwise it returns an *EntityNotFoundError, which suggests the
+// entities sharing the alias when there are several.
+func resolveExact(q querier, name string) (string, error) {
+	var id string
+	err := q.QueryRow(`SELECT id FROM entities WHERE name = ? AND deleted_at IS NULL`, name).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", fmt.Errorf("lookup entity %q: %w", name, err)
+	}
+
+	// Names of merged entities live on as aliases of the merge target
+	rows, err := q.Query(
+		`SELECT DISTINCT e.id, e.name FROM entity_aliases a
+		 JOIN entities e ON e.id = a.entity_id AND e.deleted_at IS NULL
+		 WHERE a.alias = ? ORDER BY e.name`, name,
+	)
+	if err != nil {
+		return "", fmt.Errorf("lookup alias %q: %w", name, err)
+	}
+	defer rows.Close()
+	var aliasMatches []entityName
+	for rows.Next() {
+		var c entityName
+		if err := rows.Scan(&c.id, &c.name); err != nil {
+			return "", fmt.Errorf("scan alias: %w", err)
+		}
+		aliasMatches = append(aliasMatches, c)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("lookup alias %q: %w", name, err)
+	}
+	if len(aliasMatches) == 1 {
+		return aliasMatches[0].id, nil
+	}
+	// An alias several entities share is ambiguous; let the client pick
+	return "", ambiguousName(name, aliasMatches)
+}
+
+// ambiguousName returns an *EntityNotFoundError for name suggesting the
+// entities it could mean. Without candidates the error has no suggestions.
+func ambiguousName(name string, candidates []entityName) *EntityNotFoundError {
+	nf := &EntityNotFoundError{Name: name}
+	for _, c := range candidates {
+		nf.Suggestions = append(nf.Suggestions, c.name)
+	}
+	return nf
+}
+
+// resolveTarget maps the name of an entity that a destructive write targets
+// (a deletion or a merge source) to an active entity ID by exact name or
+// alias only, so that a near miss never destroys another entity. Otherwise
+// it returns an *EntityNotFoundError with suggestions.
+func resolveTarget(q querier, name string) (string, error) {
+	id, err := resolveExact(q, name)
+	var nf *EntityNotFoundError
+	if errors.As(err, &nf) && len(nf.Suggestions) == 0 {
+		return "", notFoundWithSuggestions(q, name)
+	}
+	return id, err
+}
+
+// notFoundWithSuggestions returns an *EntityNotFoundError for name that
+// suggests the closest entity names.
+func notFoundWithSuggestions(q querier, name string) error {
+	nf := &EntityNotFoundError{Name: name}
+	candidates, err := loadEntityNames(q)
+	if err != nil {
+		return err
+	}
+	nf.Suggestions = topSuggestions(rankBySimilarity(name, candidates))
+	return nf
+}
+
+// SuggestEntityNames returns up to maxSuggestions active entity names that
+// resemble name, closest first.
+func (p *ProjectStore) SuggestEntityNames(name string) ([]string, error) {
+	candidates, err := loadEntityNames(p.db)
+	if err != nil {
+		return nil, err
+	}
+	return topSuggestions(rankBySimilarity(name, candidates)), nil
+}
+
+type entityName struct {
+	id    string
+	name  string
+	score float64
+}
+
+// loadEntityNames loads the names of up to maxSuggestionCandidates active
+// entities, most recently updated first.
+func loadEntityNames(q querier) ([]entityName, error) {
+	rows, err := q.Query(
+		`SELECT id, name FROM entities WHERE deleted_at IS NULL ORDER BY updated_at DESC, rowid DESC LIMIT ?`,
+		maxSuggestionCandidates,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load entity names: %w", err)
+	}
+	defer rows.Close()
+
+	var names []entityName
+	for rows.Next() {
+		var n entityName
+		if err := rows.Scan(&n.id, &n.name); err != nil {
+			return nil, fmt.Errorf("scan entity name: %w", err)
+		}
+		names = append(names, n)
+	}
+	return names, rows.Err()
+}
+
+// rankBySimilarity scores candidates against name and returns those above the
+// suggestion threshold, most similar first.
+func rankBySimilarity(name string, candidates []entityName) []entityName {
+	var ranked []entityName
+	for _, c := range candidates {
+		c.score = nameSimilarity(name, c.name)
+		if c.score >= suggestionThreshold {
+			ranked = append(ranked, c)
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].name < ranked[j].name
+	})
+	return ranked
+}
+
+func topSuggestions(ranked []entityName) []string {
+	var out []string
+	for i := 0; i < len(ranked) && i < maxSuggestions; i++ {
+		out = append(out, ranked[i].name)
+	}
+	return out
+}
+
+// nameSimilarity returns a score in [0,1] comparing two names after case and
+// accent folding: the better of normalized edit distance and trigram overlap.
+func nameSimilarity(a, b string) float64 {
+	fa, fb := foldText(strings.TrimSpace(a)), foldText(strings.TrimSpace(b))
+	if fa == fb {
+		return 1
+	}
+	return max(editSimilarity(fa, fb), trigramSimilarity(fa, fb))
+}
+
+// editSimilarity is 1 - levenshtein(a, b) / max(len(a), len(b)), over runes.
+func editSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// trigramSimilarity is the Jaccard index of the padded character trigram sets.
+func trigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+func trigrams(s string) map[string]bool {
+	r := []rune("  " + s + " ")
+	set := make(map[string]bool)
+	for i := 0; i+3 <= len(r); i++ {
+		set[string(r[i:i+3])] = true
+	}
+	return set
+}
+
+// ResolveEntity maps a name to an active entity ID using the same rules as
+// the write tools (exact, alias, case/accent-insensitive).
+func (p *ProjectStore) ResolveEntity(name string) (string, error) {
+	return resolveEntity(p.db, name)
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestResolveEntityTiers(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{
+		{Name: "João Silva", EntityType: "person"},
+		{Name: "Caddy", EntityType: "infrastructure"},
+		{Name: "Caddy Server", EntityType: "infrastructure"},
+	})
+
+	// Exact, then case/accent-insensitive
+	for _, name := range []string{"João Silva", "Joao Silva", "joão silva", "JOAO SILVA"} {
+		obs, err := ps.AddObservations(name, []string{"Sponsor do projeto"})
+		if err != nil {
+			t.Errorf("AddObservations(%q): %v", name, err)
+			continue
+		}
+		entities, _ := ps.GetEntities([]string{"João Silva"})
+		if len(entities) != 1 || obs[0].EntityID != entities[0].ID {
+			t.Errorf("%q should resolve to João Silva", name)
+		}
+	}
+
+	// A close typo is only suggested
+	_, err := ps.AddObservations("Joao Silvaa", []string{"Sponsor do projeto"})
+	var nf *EntityNotFoundError
+	if !errors.As(err, &nf) || len(nf.Suggestions) == 0 || nf.Suggestions[0] != "João Silva" {
+		t.Errorf("A typo should suggest João Silva, got %v", err)
+	}
+}
+
+func TestResolveEntityNearMiss(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{{Name: "ADR-001", EntityType: "decision"}})
+
+	// One character apart is another entity, not a typo to correct
+	_, err := ps.AddObservations("ADR-007", []string{"Accepted"})
+	var nf *EntityNotFoundError
+	if !errors.As(err, &nf) || len(nf.Suggestions) != 1 || nf.Suggestions[0] != "ADR-001" {
+		t.Fatalf("Expected not-found suggesting ADR-001, got %v", err)
+	}
+	entities, _ := ps.GetEntities([]string{"ADR-001"})
+	if len(entities) != 1 || len(entities[0].Observations) != 0 {
+		t.Errorf("ADR-001 should be left untouched, got %+v", entities)
+	}
+}
+
+func TestResolveEntitySuggestions(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{
+		{Name: "Caddy", EntityType: "infrastructure"},
+		{Name: "Caddy Server", EntityType: "infrastructure"},
+		{Name: "PostgreSQL", EntityType: "technology"},
+	})
+
+	_, err := ps.AddObservations("Cady Servr", []string{"test"})
+	var nf *EntityNotFoundError
+	if !errors.As(err, &nf) {
+		t.Fatalf("Expected *EntityNotFoundError, got %v", err)
+	}
+	if len(nf.Suggestions) == 0 || nf.Suggestions[0] != "Caddy Server" {
+		t.Errorf("First suggestion should be Caddy Server, got %v", nf.Suggestions)
+	}
+	if !strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("Error should offer suggestions, got %q", err.Error())
+	}
+
+	// Nothing similar: plain not-found without suggestions
+	_, err = ps.AddObservations("Kubernetes", []string{"test"})
+	if !errors.As(err, &nf) || len(nf.Suggestions) != 0 {
+		t.Errorf("Expected not-found without suggestions, got %v", err)
+	}
+
+	// Relations report which endpoint failed
+	_, err = ps.CreateRelations([]struct {
+		From         string
+		To           string
+		RelationType string
+	}{{From: "caddy", To: "Postgres", RelationType: "uses"}})
+	if err == nil || !strings.Contains(err.Error(), "PostgreSQL") {
+		t.Errorf("Expected suggestion for Postgres, got %v", err)
+	}
+}
+
+func TestResolveEntityAmbiguousFold(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{
+		{Name: "Go", EntityType: "technology"},
+		{Name: "GO", EntityType: "concept"},
+	})
+
+	// "go" matches both after folding, so it must not pick one silently
+	_, err := ps.AddObservations("go", []string{"test"})
+	var nf *EntityNotFoundError
+	if !errors.As(err, &nf) || len(nf.Suggestions) != 2 {
+		t.Errorf("Expected ambiguity error listing both names, got %v", err)
+	}
+}
+
+func TestLookupEntities(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{{Name: "Migração Microsserviços", EntityType: "project"}})
+
+	entities, missing, err := ps.LookupEntities([]string{"migracao microsservicos", "Migração Microsserviços", "Nada"})
+	if err != nil {
+		t.Fatalf("LookupEntities: %v", err)
+	}
+	if len(entities) != 1 {
+		t.Errorf("Both spellings should resolve to one entity, got %d", len(entities))
+	}
+	if len(missing) != 1 || missing[0].Name != "Nada" {
+		t.Errorf("Expected Nada to be reported missing, got %+v", missing)
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	if nameSimilarity("João Silva", "joao silva") != 1 {
+		t.Error("Folded names should be identical")
+	}
+	if nameSimilarity("Caddy", "Caddy Server") <= nameSimilarity("Caddy", "PostgreSQL") {
+		t.Error("Caddy should be closer to Caddy Server than to PostgreSQL")
+	}
+}
+
+func TestResolveEntityAmbiguousAlias(t *testing.T) {
+	ps := setupProjectStore(t)
+	ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Acme Corp", EntityType: "organization"},
+		{Op: OpCreateEntity, Entity: "Acme Labs", EntityType: "organization"},
+	})
+	// Renames and merges can leave one alias on several entities
+	ps.db.Exec(`INSERT INTO entity_aliases (alias, entity_id) SELECT 'Acme', id FROM entities`)
+
+	for name, resolve := range map[string]func(querier, string) (string, error){
+		"resolveEntity": resolveEntity,
+		"resolveTarget": resolveTarget,
+	} {
+		_, err := resolve(ps.db, "Acme")
+		var nf *EntityNotFoundError
+		if !errors.As(err, &nf) || strings.Join(nf.Suggestions, ",") != "Acme Corp,Acme Labs" {
+			t.Errorf("%s: expected not-found suggesting both entities, got %v", name, err)
+		}
+	}
+	if _, err := ps.ApplyBatch([]BatchOp{{Op: OpDeleteEntity, Entity: "Acme"}}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Deleting by an ambiguous alias should fail, got %v", err)
+	}
+}
@@ -22,7 +22,9 @@ const ProjectSchema = `
 CREATE TABLE IF NOT EXISTS entities (
     id          TEXT PRIMARY KEY,
     name        TEXT NOT NULL,
+    name_fold   TEXT NOT NULL DEFAULT '', -- name without case and accents (see foldText)
     entity_type TEXT NOT NULL,
+    version     INTEGER NOT NULL DEFAULT 1,
     created_at  TEXT NOT NULL DEFAULT (datetime('now')),
     updated_at  TEXT NOT NULL DEFAULT (datetime('now')),
     deleted_at  TEXT NULL
@@ -32,6 +34,12 @@ CREATE TABLE IF NOT EXISTS observations (
     id          TEXT PRIMARY KEY,
     entity_id   TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
     content     TEXT NOT NULL,
+    source      TEXT NOT NULL DEFAULT '',
+    author      TEXT NOT NULL DEFAULT '',
+    confidence  REAL NULL,
+    tags        TEXT NOT NULL DEFAULT '[]', -- JSON array of strings
+    valid_from  TEXT NULL,
+    valid_until TEXT NULL,
     created_at  TEXT NOT NULL DEFAULT (datetime('now')),
     deleted_at  TEXT NULL
 );
@@ -41,10 +49,83 @@ CREATE TABLE IF NOT EXISTS relations (
     from_entity     TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
     to_entity       TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
     relation_type   TEXT NOT NULL,
+    valid_from      TEXT NULL,
+    valid_until     TEXT NULL,
     created_at      TEXT NOT NULL DEFAULT (datetime('now')),
     deleted_at      TEXT NULL
 );
 
+-- Alternative names that resolve to an entity (e.g. names of entities merged into it).
+CREATE TABLE IF NOT EXISTS entity_aliases (
+    alias       TEXT NOT NULL,
+    entity_id   TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
+    created_at  TEXT NOT NULL DEFAULT (datetime('now')),
+    PRIMARY KEY (alias, entity_id)
+);
+
+-- Typed attributes of an entity, one row per key. value holds the value as
+-- text: numbers in decimal, dates as 2026-02-01 or UTC timestamps, bools as
+-- true/false and json as compact JSON.
+CREATE TABLE IF NOT EXISTS entity_properties (
+    id          TEXT PRIMARY KEY,
+    entity_id   TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
+    key         TEXT NOT NULL,
+    value_type  TEXT NOT NULL
+                CHECK(value_type IN ('string', 'number', 'date', 'bool', 'json')),
+    value       TEXT NOT NULL,
+    created_at  TEXT NOT NULL DEFAULT (datetime('now')),
+    updated_at  TEXT NOT NULL DEFAULT (datetime('now')),
+    deleted_at  TEXT NULL,
+    UNIQUE (entity_id, key)
+);
+
+-- Vectors for semantic search. observation_id is '' for the entity-level
+-- vector (name + type); model identifies the embedder that produced it.
+CREATE TABLE IF NOT EXISTS embeddings (
+    entity_id       TEXT NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
+    observation_id  TEXT NOT NULL DEFAULT '',
+    model           TEXT NOT NULL,
+    vector          BLOB NOT NULL,
+    created_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    PRIMARY KEY (entity_id, observation_id)
+);
+
+-- Per-project key/value settings (e.g. ontology_mode).
+CREATE TABLE IF NOT EXISTS project_settings (
+    key         TEXT PRIMARY KEY,
+    value       TEXT NOT NULL,
+    updated_at  TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+-- Results of writes made with an idempotency key, replayed when the same key
+-- comes back with the same request. request_hash covers the tool name and
+-- arguments; result is the JSON-encoded tool result, or empty while the
+-- request that claimed the key at claimed_at is running.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key          TEXT PRIMARY KEY,
+    request_hash TEXT NOT NULL,
+    result       TEXT NOT NULL,
+    created_at   TEXT NOT NULL DEFAULT (datetime('now')),
+    claimed_at   TEXT NOT NULL DEFAULT ''
+);
+
+-- Ontology: the allowed entity types and relation types. Empty tables mean
+-- no ontology, so nothing is enforced.
+CREATE TABLE IF NOT EXISTS ontology_entity_types (
+    name        TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+-- source_types and target_types are JSON arrays of entity types; an empty
+-- array allows any type.
+CREATE TABLE IF NOT EXISTS ontology_relation_types (
+    name            TEXT PRIMARY KEY,
+    description     TEXT NOT NULL DEFAULT '',
+    source_types    TEXT NOT NULL DEFAULT '[]',
+    target_types    TEXT NOT NULL DEFAULT '[]',
+    inverse         TEXT NOT NULL DEFAULT ''
+);
+
 CREATE VIRTUAL TABLE IF NOT EXISTS entities_fts USING fts5(
     name,
     entity_type,
@@ -65,6 +146,9 @@ CREATE INDEX IF NOT EXISTS idx_observations_entity ON observations(entity_id) WH
 CREATE INDEX IF NOT EXISTS idx_relations_from ON relations(from_entity) WHERE deleted_at IS NULL;
 CREATE INDEX IF NOT EXISTS idx_relations_to ON relations(to_entity) WHERE deleted_at IS NULL;
 CREATE INDEX IF NOT EXISTS idx_relations_type ON relations(relation_type) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_entity_aliases_entity ON entity_aliases(entity_id);
+CREATE INDEX IF NOT EXISTS idx_entity_properties_key ON entity_properties(key, value) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_embeddings_model ON embeddings(model);
 `
 
 // ProjectTriggers must be executed separately since CREATE TRIGGER doesn't
@@ -91,6 +175,43 @@ CREATE TRIGGER IF NOT EXISTS observations_au AFTER UPDATE ON observations BEGIN
     INSERT INTO observations_fts(observations_fts, rowid, content) VALUES('delete', old.rowid, old.content);
     INSERT INTO observations_fts(rowid, content) VALUES (new.rowid, new.content);
 END;
+
+-- Entity versions: every change to an entity, its observations or its
+-- relations bumps the entity's version (both endpoints for relations).
+CREATE TRIGGER IF NOT EXISTS entities_version_au AFTER UPDATE OF name, entity_type, deleted_at ON entities BEGIN
+    UPDATE entities SET version = version + 1 WHERE id = new.id;
+END;
+CREATE TRIGGER IF NOT EXISTS observations_version_ai AFTER INSERT ON observations BEGIN
+    UPDATE entities SET version = version + 1 WHERE id = new.entity_id;
+END;
+CREATE TRIGGER IF NOT EXISTS observations_version_au AFTER UPDATE OF entity_id, content, deleted_at ON observations BEGIN
+    UPDATE entities SET version = version + 1 WHERE id IN (old.entity_id, new.entity_id);
+END;
+CREATE TRIGGER IF NOT EXISTS relations_version_ai AFTER INSERT ON relations BEGIN
+    UPDATE entities SET version = version + 1 WHERE id IN (new.from_entity, new.to_entity);
+END;
+CREATE TRIGGER IF NOT EXISTS relations_version_au AFTER UPDATE OF from_entity, to_entity, relation_type, deleted_at ON relations BEGIN
+    UPDATE entities SET version = version + 1
+    WHERE id IN (old.from_entity, old.to_entity, new.from_entity, new.to_entity);
+END;
+CREATE TRIGGER IF NOT EXISTS observations_validity_au AFTER UPDATE OF valid_from, valid_until ON observations BEGIN
+    UPDATE entities SET version = version + 1 WHERE id = new.entity_id;
+END;
+CREATE TRIGGER IF NOT EXISTS relations_validity_au AFTER UPDATE OF valid_from, valid_until ON relations BEGIN
+    UPDATE entities SET version = version + 1 WHERE id IN (new.from_entity, new.to_entity);
+END;
+CREATE TRIGGER IF NOT EXISTS entity_properties_version_ai AFTER INSERT ON entity_properties BEGIN
+    UPDATE entities SET version = version + 1 WHERE id = new.entity_id;
+END;
+CREATE TRIGGER IF NOT EXISTS entity_properties_version_au AFTER UPDATE OF entity_id, value_type, value, deleted_at ON entity_properties BEGIN
+    UPDATE entities SET version = version + 1 WHERE id IN (old.entity_id, new.entity_id);
+END;
+`
+
+// ProjectIndexes are the indexes on columns that databases created by older
+// versions gain during migration, so they are created after it.
+const ProjectIndexes = `
+CREATE INDEX IF NOT EXISTS idx_entities_name_fold ON entities(name_fold) WHERE deleted_at IS NULL;
 `
 
 // Pragmas configures SQLite for optimal performance.
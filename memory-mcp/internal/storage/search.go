@@ -2,79 +2,192 @@ package storage
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
 )
 
+// ObservationFilter restricts a search to entities with an active
+// observation that has the given Source and Author, at least MinConfidence,
+// and every tag in Tags. Empty fields do not filter.
+type ObservationFilter struct {
+	Source        string
+	Author        string
+	MinConfidence float64
+	Tags          []string
+}
+
+func (f ObservationFilter) empty() bool {
+	return f.Source == "" && f.Author == "" && f.MinConfidence <= 0 && len(f.Tags) == 0
+}
+
+// where returns the conditions of f on observations aliased o, with their
+// arguments.
+func (f ObservationFilter) where() (string, []any) {
+	var conds []string
+	var args []any
+	if f.Source != "" {
+		conds = append(conds, "o.source = ?")
+		args = append(args, f.Source)
+	}
+	if f.Author != "" {
+		conds = append(conds, "o.author = ?")
+		args = append(args, f.Author)
+	}
+	if f.MinConfidence > 0 {
+		conds = append(conds, "o.confidence >= ?")
+		args = append(args, f.MinConfidence)
+	}
+	if len(f.Tags) > 0 {
+		conds = append(conds, `NOT EXISTS (SELECT 1 FROM json_each(?) wanted
+		     WHERE wanted.value NOT IN (SELECT value FROM json_each(o.tags)))`)
+		args = append(args, jsonArray(f.Tags))
+	}
+	return strings.Join(conds, " AND "), args
+}
+
 // Search performs FTS5 full-text search across entities and observations.
 // It returns fully-loaded entities (with observations and relations) that match.
 func (p *ProjectStore) Search(query string) ([]models.Entity, error) {
-	entityIDs := make(map[string]bool)
+	return p.SearchFiltered(query, ObservationFilter{})
+}
 
-	// Search entities_fts for matching entity names/types
-	rows, err := p.db.Query(
-		`SELECT e.id FROM entities e
-		 JOIN entities_fts ON entities_fts.rowid = e.rowid
-		 WHERE entities_fts MATCH ? AND e.deleted_at IS NULL`,
-		query,
-	)
+// SearchFiltered is Search restricted by an observation filter: an entity
+// matches when one of its observations passes the filter and either the
+// entity or that observation matches the query. The query may be empty when
+// the filter is not, to list every entity with a passing observation.
+func (p *ProjectStore) SearchFiltered(query string, filter ObservationFilter) ([]models.Entity, error) {
+	return p.SearchWithProperties(query, filter, nil)
+}
+
+// SearchWithProperties is SearchFiltered further restricted to entities
+// whose properties pass every property filter. With an empty query and
+// observation filter it lists every entity that passes the property filters,
+// ordered by name.
+func (p *ProjectStore) SearchWithProperties(query string, filter ObservationFilter, properties []PropertyFilter) ([]models.Entity, error) {
+	query = strings.TrimSpace(query)
+	propCond, propArgs, err := propertyConditions(properties)
 	if err != nil {
-		return nil, fmt.Errorf("search entities fts: %w", err)
-	}
-	for rows.Next() {
-		var id string
-		rows.Scan(&id)
-		entityIDs[id] = true
+		return nil, err
 	}
-	rows.Close()
 
-	// Search observations_fts for matching observation content
-	obsRows, err := p.db.Query(
-		`SELECT DISTINCT o.entity_id FROM observations o
-		 JOIN observations_fts ON observations_fts.rowid = o.rowid
-		 WHERE observations_fts MATCH ? AND o.deleted_at IS NULL`,
-		query,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("search observations fts: %w", err)
+	var ids []string
+	switch {
+	case query == "" && filter.empty() && len(properties) == 0:
+		return nil, invalidf("a query, an observation filter or a property filter is required")
+	case query == "" && filter.empty():
+		ids, err = p.queryIDs(`SELECT e.id FROM entities e WHERE e.deleted_at IS NULL AND `+propCond+` ORDER BY e.name, e.id`, propArgs...)
+	default:
+		ids, err = p.searchIDs(query, filter)
+		if err == nil && len(properties) > 0 && len(ids) > 0 {
+			var passing []string
+			passing, err = p.queryIDs(`SELECT e.id FROM entities e WHERE e.id IN (SELECT value FROM json_each(?)) AND `+propCond,
+				append([]any{jsonArray(ids)}, propArgs...)...)
+			ids = keepIDs(ids, passing)
+		}
 	}
-	for obsRows.Next() {
-		var entityID string
-		obsRows.Scan(&entityID)
-		entityIDs[entityID] = true
+	if err != nil {
+		return nil, err
 	}
-	obsRows.Close()
-
-	if len(entityIDs) == 0 {
+	if len(ids) == 0 {
 		return nil, nil
 	}
 
-	// Load full entity data for all matched IDs
-	var entities []models.Entity
-	for id := range entityIDs {
-		var e models.Entity
-		err := p.db.QueryRow(
-			`SELECT id, name, entity_type, created_at, updated_at FROM entities WHERE id = ? AND deleted_at IS NULL`,
-			id,
-		).Scan(&e.ID, &e.Name, &e.EntityType, &e.CreatedAt, &e.UpdatedAt)
-		if err != nil {
-			continue // entity may have been deleted between search and load
-		}
+	// Load full entity data for all matched IDs; entities deleted in the
+	// meantime are skipped
+	return p.getEntitiesByID(ids)
+}
 
-		obs, err := p.getObservations(e.ID)
-		if err != nil {
-			return nil, err
+// queryIDs runs a query that selects entity IDs.
+func (p *ProjectStore) queryIDs(query string, args ...any) ([]string, error) {
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search properties: %w", err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan search result: %w", err)
 		}
-		e.Observations = obs
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
 
-		rels, err := p.getRelations(e.ID)
-		if err != nil {
-			return nil, err
+// keepIDs returns the IDs of ids that are in keep, in the order of ids.
+func keepIDs(ids, keep []string) []string {
+	set := make(map[string]bool, len(keep))
+	for _, id := range keep {
+		set[id] = true
+	}
+	var kept []string
+	for _, id := range ids {
+		if set[id] {
+			kept = append(kept, id)
 		}
-		e.Relations = rels
-
-		entities = append(entities, e)
 	}
+	return kept
+}
 
-	return entities, nil
+// searchIDs returns the IDs of the entities matching the query and the
+// observation filter, at least one of which is set, in rank order.
+func (p *ProjectStore) searchIDs(query string, filter ObservationFilter) ([]string, error) {
+	// Entities matching by name/type come first, then those matching by
+	// observation content, each entity once
+	// Observations not valid at the store's read time neither match nor pass
+	var sqlText string
+	var args []any
+	valid, validArgs := p.validity().where("o")
+	if filter.empty() {
+		sqlText = `SELECT e.id FROM entities e
+		 JOIN entities_fts ON entities_fts.rowid = e.rowid
+		 WHERE entities_fts MATCH ?1 AND e.deleted_at IS NULL
+		 UNION ALL
+		 SELECT DISTINCT o.entity_id FROM observations o
+		 JOIN observations_fts ON observations_fts.rowid = o.rowid
+		 WHERE observations_fts MATCH ?1 AND o.deleted_at IS NULL` + valid
+		args = append([]any{query}, validArgs...)
+	} else {
+		cond, condArgs := filter.where()
+		condArgs = append(condArgs, validArgs...)
+		passing := `SELECT o.entity_id, o.rowid FROM observations o WHERE o.deleted_at IS NULL AND ` + cond + valid
+		if query == "" {
+			sqlText = `SELECT DISTINCT entity_id FROM (` + passing + `)`
+			args = condArgs
+		} else {
+			sqlText = `SELECT e.id FROM entities e
+			 JOIN entities_fts ON entities_fts.rowid = e.rowid
+			 WHERE entities_fts MATCH ? AND e.deleted_at IS NULL
+			   AND e.id IN (SELECT entity_id FROM (` + passing + `))
+			 UNION ALL
+			 SELECT DISTINCT ob.entity_id FROM (` + passing + `) ob
+			 JOIN observations_fts ON observations_fts.rowid = ob.rowid
+			 WHERE observations_fts MATCH ?`
+			args = append(append(append([]any{query}, condArgs...), condArgs...), query)
+		}
+	}
+	rows, err := p.db.Query(sqlText, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search fts: %w", err)
+	}
+	seen := make(map[string]bool)
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan search result: %w", err)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search fts: %w", err)
+	}
+	return ids, nil
 }
@@ -0,0 +1,403 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+// rrfK is the rank offset used by reciprocal rank fusion. 60 is the value from
+// the original RRF paper and works well without tuning.
+const rrfK = 60
+
+// embedBatchSize caps how many texts are sent to the embedder per call.
+const embedBatchSize = 64
+
+// embedItem is a piece of text to embed, keyed like the embeddings table.
+type embedItem struct {
+	entityID      string
+	observationID string
+	text          string
+}
+
+// entityEmbedItem builds the entity-level item (name + type) for an entity.
+func entityEmbedItem(entityID, name, entityType string) embedItem {
+	return embedItem{entityID: entityID, text: name + " (" + entityType + ")"}
+}
+
+// maxBackfillPerSearch caps how many missing vectors a semantic search
+// embeds before ranking, so a large unindexed project is caught up over
+// several searches rather than stalling one.
+const maxBackfillPerSearch = 4 * embedBatchSize
+
+// maxIndexWait bounds how long a semantic search waits for queued writes to
+// be indexed before ranking what is already stored.
+const maxIndexWait = 5 * time.Second
+
+// embedIndexer embeds written items in the background, so that writes do
+// not wait for the embedder, and tracks how far the backfill of items
+// without a vector has got. It is shared by a store and its views.
+type embedIndexer struct {
+	mu      sync.Mutex
+	queue   []embedItem
+	running bool
+	idle    chan struct{} // closed once the queue drains
+
+	// The backfill resumes after the last entity and observation rowids it
+	// scanned for model, and is complete once a scan finds nothing more.
+	model       string
+	entityRowid int64
+	obsRowid    int64
+	backfilled  bool
+	rewinds     int
+}
+
+func newEmbedIndexer() *embedIndexer {
+	idle := make(chan struct{})
+	close(idle)
+	return &embedIndexer{idle: idle}
+}
+
+// rewind restarts the backfill from the first row, for when vectors may be
+// missing behind its cursor. The caller holds mu.
+func (ix *embedIndexer) rewind() {
+	ix.entityRowid, ix.obsRowid, ix.backfilled = 0, 0, false
+	ix.rewinds++
+}
+
+// indexEmbeddings queues freshly written items to be embedded in the
+// background. Failures are logged rather than returned: the write itself has
+// already succeeded, and missing vectors are backfilled by later semantic
+// searches. Dry-run views index nothing.
+func (p *ProjectStore) indexEmbeddings(items []embedItem) {
+	if p.changes != nil || len(items) == 0 {
+		return
+	}
+	ix := p.indexer
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.queue = append(ix.queue, items...)
+	if !ix.running {
+		ix.running = true
+		ix.idle = make(chan struct{})
+		go p.drainEmbeddings()
+	}
+}
+
+// drainEmbeddings embeds queued items batch by batch until the queue is
+// empty.
+func (p *ProjectStore) drainEmbeddings() {
+	ix := p.indexer
+	for {
+		ix.mu.Lock()
+		if len(ix.queue) == 0 {
+			ix.running = false
+			close(ix.idle)
+			ix.mu.Unlock()
+			return
+		}
+		n := min(embedBatchSize, len(ix.queue))
+		batch := ix.queue[:n:n]
+		ix.queue = ix.queue[n:]
+		ix.mu.Unlock()
+
+		if err := p.storeEmbeddings(batch); err != nil {
+			log.Printf("index embeddings: %v", err)
+			ix.mu.Lock()
+			ix.rewind()
+			ix.mu.Unlock()
+		}
+	}
+}
+
+// waitIndexed waits up to timeout, or indefinitely when timeout is 0, for
+// the queued items to be indexed.
+func (p *ProjectStore) waitIndexed(timeout time.Duration) {
+	p.indexer.mu.Lock()
+	idle := p.indexer.idle
+	p.indexer.mu.Unlock()
+	if timeout == 0 {
+		<-idle
+		return
+	}
+	select {
+	case <-idle:
+	case <-time.After(timeout):
+	}
+}
+
+// storeEmbeddings embeds items in batches and upserts their vectors.
+func (p *ProjectStore) storeEmbeddings(items []embedItem) error {
+	model := p.embedder.Model()
+	for start := 0; start < len(items); start += embedBatchSize {
+		batch := items[start:min(start+embedBatchSize, len(items))]
+		texts := make([]string, len(batch))
+		for i, it := range batch {
+			texts[i] = it.text
+		}
+		vectors, err := p.embedder.Embed(texts)
+		if err != nil {
+			return fmt.Errorf("embed: %w", err)
+		}
+
+		tx, err := p.db.Begin()
+		if err != nil {
+			return fmt.Errorf("begin tx: %w", err)
+		}
+		for i, it := range batch {
+			_, err := tx.Exec(
+				`INSERT INTO embeddings (entity_id, observation_id, model, vector) VALUES (?, ?, ?, ?)
+				 ON CONFLICT(entity_id, observation_id) DO UPDATE SET model = excluded.model, vector = excluded.vector, created_at = datetime('now')`,
+				it.entityID, it.observationID, model, encodeVector(vectors[i]),
+			)
+			if err != nil {
+				tx.Rollback()
+				return fmt.Errorf("store embedding: %w", err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit: %w", err)
+		}
+	}
+	return nil
+}
+
+// backfillEmbeddings embeds up to maxBackfillPerSearch active entities and
+// observations that have no vector for the current model yet (pre-existing
+// data, a changed embedder, or an earlier embedding failure). It resumes
+// from where the previous call stopped, and does nothing once a scan has
+// found every vector in place; later writes are indexed as they happen.
+func (p *ProjectStore) backfillEmbeddings() error {
+	ix := p.indexer
+	model := p.embedder.Model()
+	ix.mu.Lock()
+	if ix.model != model {
+		ix.model = model
+		ix.rewind()
+	}
+	backfilled, entityRowid, obsRowid, rewinds := ix.backfilled, ix.entityRowid, ix.obsRowid, ix.rewinds
+	ix.mu.Unlock()
+	if backfilled {
+		return nil
+	}
+
+	var items []embedItem
+	rows, err := p.db.Query(
+		`SELECT e.rowid, e.id, e.name, e.entity_type FROM entities e
+		 LEFT JOIN embeddings em ON em.entity_id = e.id AND em.observation_id = '' AND em.model = ?
+		 WHERE e.rowid > ? AND e.deleted_at IS NULL AND em.entity_id IS NULL
+		 ORDER BY e.rowid LIMIT ?`,
+		model, entityRowid, maxBackfillPerSearch,
+	)
+	if err != nil {
+		return fmt.Errorf("query unindexed entities: %w", err)
+	}
+	for rows.Next() {
+		var id, name, entityType string
+		if err := rows.Scan(&entityRowid, &id, &name, &entityType); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan entity: %w", err)
+		}
+		items = append(items, entityEmbedItem(id, name, entityType))
+	}
+	rows.Close()
+	entitiesDone := len(items) < maxBackfillPerSearch
+
+	obsDone := false
+	if room := maxBackfillPerSearch - len(items); room > 0 {
+		obsRows, err := p.db.Query(
+			`SELECT o.rowid, o.entity_id, o.id, o.content FROM observations o
+			 JOIN entities e ON e.id = o.entity_id AND e.deleted_at IS NULL
+			 LEFT JOIN embeddings em ON em.observation_id = o.id AND em.model = ?
+			 WHERE o.rowid > ? AND o.deleted_at IS NULL AND em.entity_id IS NULL
+			 ORDER BY o.rowid LIMIT ?`,
+			model, obsRowid, room,
+		)
+		if err != nil {
+			return fmt.Errorf("query unindexed observations: %w", err)
+		}
+		found := 0
+		for obsRows.Next() {
+			var it embedItem
+			if err := obsRows.Scan(&obsRowid, &it.entityID, &it.observationID, &it.text); err != nil {
+				obsRows.Close()
+				return fmt.Errorf("scan observation: %w", err)
+			}
+			items = append(items, it)
+			found++
+		}
+		obsRows.Close()
+		obsDone = found < room
+	}
+
+	if err := p.storeEmbeddings(items); err != nil {
+		return err
+	}
+	// Keep a rewind made while this scan ran
+	ix.mu.Lock()
+	if ix.rewinds == rewinds {
+		ix.entityRowid, ix.obsRowid = entityRowid, obsRowid
+		ix.backfilled = entitiesDone && obsDone
+	}
+	ix.mu.Unlock()
+	return nil
+}
+
+// SemanticSearch ranks entities by cosine similarity between the query and
+// their name/observation vectors (an entity scores as its best-matching
+// vector). With hybrid set, the semantic ranking is fused with the FTS5
+// ranking using reciprocal rank fusion. At most limit entities are returned.
+func (p *ProjectStore) SemanticSearch(query string, limit int, hybrid bool) ([]models.ScoredEntity, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	p.waitIndexed(maxIndexWait)
+	if err := p.backfillEmbeddings(); err != nil {
+		return nil, fmt.Errorf("backfill embeddings: %w", err)
+	}
+
+	vectors, err := p.embedder.Embed([]string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+	qv := vectors[0]
+
+	// Observations not valid at the store's read time do not rank
+	valid, validArgs := p.validity().where("o")
+	rows, err := p.db.Query(
+		`SELECT em.entity_id, em.vector FROM embeddings em
+		 JOIN entities e ON e.id = em.entity_id AND e.deleted_at IS NULL
+		 LEFT JOIN observations o ON o.id = em.observation_id
+		 WHERE em.model = ? AND (em.observation_id = '' OR (o.id IS NOT NULL AND o.deleted_at IS NULL`+valid+`))`,
+		append([]any{p.embedder.Model()}, validArgs...)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("query embeddings: %w", err)
+	}
+	best := make(map[string]float64)
+	for rows.Next() {
+		var entityID string
+		var blob []byte
+		if err := rows.Scan(&entityID, &blob); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan embedding: %w", err)
+		}
+		score := cosine(qv, decodeVector(blob))
+		if cur, ok := best[entityID]; !ok || score > cur {
+			best[entityID] = score
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	semantic := make([]string, 0, len(best))
+	for id := range best {
+		semantic = append(semantic, id)
+	}
+	sort.Slice(semantic, func(i, j int) bool {
+		if best[semantic[i]] != best[semantic[j]] {
+			return best[semantic[i]] > best[semantic[j]]
+		}
+		return semantic[i] < semantic[j]
+	})
+
+	ranked, scores := semantic, best
+	if hybrid {
+		fts, err := p.rankFTS(ftsAnyTermQuery(query))
+		if err != nil {
+			return nil, err
+		}
+		ranked, scores = fuseRankings(semantic, fts)
+	}
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	entities, err := p.getEntitiesByID(ranked)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]models.ScoredEntity, len(entities))
+	for i, e := range entities {
+		results[i] = models.ScoredEntity{Entity: e, Score: scores[e.ID]}
+	}
+	return results, nil
+}
+
+// fuseRankings combines ranked ID lists with reciprocal rank fusion and
+// returns the fused order together with each ID's fused score.
+func fuseRankings(rankings ...[]string) ([]string, map[string]float64) {
+	scores := make(map[string]float64)
+	for _, ranking := range rankings {
+		for rank, id := range ranking {
+			scores[id] += 1.0 / float64(rrfK+rank+1)
+		}
+	}
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids, scores
+}
+
+// ftsAnyTermQuery turns free text into an FTS5 query matching any of its
+// words, quoting each one so punctuation cannot break the MATCH syntax.
+func ftsAnyTermQuery(text string) string {
+	tokens := tokenize(text)
+	quoted := make([]string, len(tokens))
+	for i, tok := range tokens {
+		quoted[i] = `"` + tok + `"`
+	}
+	return strings.Join(quoted, " OR ")
+}
+
+// rankFTS returns entity IDs matching an FTS5 query, best bm25 score first.
+// An entity ranks by the better of its name/type match and its best
+// observation match among those valid at the store's read time.
+func (p *ProjectStore) rankFTS(query string) ([]string, error) {
+	if query == "" {
+		return nil, nil
+	}
+	valid, validArgs := p.validity().where("o")
+	rows, err := p.db.Query(
+		`SELECT id, MIN(score) FROM (
+		     SELECT e.id AS id, bm25(entities_fts) AS score FROM entities e
+		     JOIN entities_fts ON entities_fts.rowid = e.rowid
+		     WHERE entities_fts MATCH ?1 AND e.deleted_at IS NULL
+		     UNION ALL
+		     SELECT o.entity_id AS id, bm25(observations_fts) AS score FROM observations o
+		     JOIN observations_fts ON observations_fts.rowid = o.rowid
+		     JOIN entities e ON e.id = o.entity_id AND e.deleted_at IS NULL
+		     WHERE observations_fts MATCH ?1 AND o.deleted_at IS NULL`+valid+`
+		 ) GROUP BY id ORDER BY MIN(score), id`,
+		append([]any{query}, validArgs...)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("rank fts: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		var score float64
+		if err := rows.Scan(&id, &score); err != nil {
+			return nil, fmt.Errorf("scan fts rank: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHashEmbedder(t *testing.T) {
+	h := NewHashEmbedder()
+
+	vecs, err := h.Embed([]string{"Mensageria com RabbitMQ", "mensageria com rabbitmq", "Banco de dados relacional"})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if len(vecs) != 3 || len(vecs[0]) != h.Dims {
+		t.Fatalf("Unexpected vector shape: %d vectors of %d dims", len(vecs), len(vecs[0]))
+	}
+
+	// Case and accents must not change the vector
+	if sim := cosine(vecs[0], vecs[1]); sim < 0.999 {
+		t.Errorf("Case-folded texts should be identical, cosine = %f", sim)
+	}
+	if cosine(vecs[0], vecs[2]) >= cosine(vecs[0], vecs[1]) {
+		t.Error("Unrelated text should be less similar than the same text")
+	}
+
+	// Round-trip through the blob encoding
+	decoded := decodeVector(encodeVector(vecs[0]))
+	if sim := cosine(vecs[0], decoded); sim < 0.999 {
+		t.Errorf("Encode/decode should preserve the vector, cosine = %f", sim)
+	}
+}
+
+func TestHTTPEmbedder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "test-model" {
+			t.Errorf("model = %q, want %q", req.Model, "test-model")
+		}
+		embeddings := make([][]float32, len(req.Input))
+		for i := range req.Input {
+			embeddings[i] = []float32{3, 4}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"embeddings": embeddings})
+	}))
+	defer srv.Close()
+
+	e := NewHTTPEmbedder(srv.URL+"/", "test-model")
+	if e.Model() != "http:test-model" {
+		t.Errorf("Model() = %q", e.Model())
+	}
+	vecs, err := e.Embed([]string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if len(vecs) != 2 {
+		t.Fatalf("Expected 2 vectors, got %d", len(vecs))
+	}
+	// Vectors are normalized on the way in
+	if vecs[0][0] != 0.6 || vecs[0][1] != 0.8 {
+		t.Errorf("Expected normalized [0.6 0.8], got %v", vecs[0])
+	}
+}
+
+func TestSemanticSearch(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{
+		{Name: "ADR: RabbitMQ", EntityType: "decision", Observations: []string{"Mensageria assíncrona entre microsserviços"}},
+		{Name: "PostgreSQL", EntityType: "technology", Observations: []string{"Banco relacional principal"}},
+	})
+
+	// Writes are indexed in the background
+	ps.waitIndexed(0)
+	var count int
+	ps.db.QueryRow(`SELECT COUNT(*) FROM embeddings`).Scan(&count)
+	if count != 4 {
+		t.Errorf("Expected 4 embeddings (2 entities + 2 observations), got %d", count)
+	}
+
+	results, err := ps.SemanticSearch("mensageria microsservicos", 10, false)
+	if err != nil {
+		t.Fatalf("SemanticSearch: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 ranked results, got %d", len(results))
+	}
+	if results[0].Name != "ADR: RabbitMQ" {
+		t.Errorf("Top result = %q, want %q", results[0].Name, "ADR: RabbitMQ")
+	}
+	if results[0].Score <= results[1].Score {
+		t.Error("Results should be ordered by descending score")
+	}
+
+	results, err = ps.SemanticSearch("banco relacional", 1, true)
+	if err != nil {
+		t.Fatalf("SemanticSearch hybrid: %v", err)
+	}
+	if len(results) != 1 || results[0].Name != "PostgreSQL" {
+		t.Errorf("Hybrid top result should be PostgreSQL, got %+v", results)
+	}
+}
+
+func TestSemanticSearchBackfill(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{{Name: "Caddy", EntityType: "infrastructure", Observations: []string{"Reverse proxy com TLS automático"}}})
+
+	// Simulate data written before embeddings existed
+	ps.waitIndexed(0)
+	ps.db.Exec(`DELETE FROM embeddings`)
+
+	results, err := ps.SemanticSearch("reverse proxy", 5, false)
+	if err != nil {
+		t.Fatalf("SemanticSearch: %v", err)
+	}
+	if len(results) != 1 || results[0].Name != "Caddy" {
+		t.Fatalf("Expected Caddy after backfill, got %+v", results)
+	}
+
+	var count int
+	ps.db.QueryRow(`SELECT COUNT(*) FROM embeddings`).Scan(&count)
+	if count != 2 {
+		t.Errorf("Expected 2 backfilled embeddings, got %d", count)
+	}
+}
+
+func TestSemanticSearchBackfillCursor(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	ops := make([]BatchOp, maxBackfillPerSearch+10)
+	for i := range ops {
+		ops[i] = BatchOp{Op: OpCreateEntity, Entity: fmt.Sprintf("Service %d", i), EntityType: "service"}
+	}
+	if _, err := ps.ApplyBatch(ops); err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+	ps.waitIndexed(0)
+	ps.db.Exec(`DELETE FROM embeddings`)
+
+	count := func() int {
+		var n int
+		ps.db.QueryRow(`SELECT COUNT(*) FROM embeddings`).Scan(&n)
+		return n
+	}
+	// Each search embeds a bounded share of what is missing
+	ps.SemanticSearch("service", 5, false)
+	if n := count(); n != maxBackfillPerSearch {
+		t.Errorf("First search embedded %d vectors, want %d", n, maxBackfillPerSearch)
+	}
+	ps.SemanticSearch("service", 5, false)
+	if n := count(); n != len(ops) {
+		t.Errorf("Second search left %d of %d vectors", n, len(ops))
+	}
+
+	// Once caught up, searches no longer scan for missing vectors
+	ps.db.Exec(`DELETE FROM embeddings WHERE rowid IN (SELECT rowid FROM embeddings LIMIT 1)`)
+	ps.SemanticSearch("service", 5, false)
+	if n := count(); n != len(ops)-1 {
+		t.Errorf("A caught-up search should not backfill, got %d vectors", n)
+	}
+}
+
+// blockingEmbedder embeds like HashEmbedder once release is closed.
+type blockingEmbedder struct {
+	HashEmbedder
+	release chan struct{}
+}
+
+func (b *blockingEmbedder) Embed(texts []string) ([][]float32, error) {
+	<-b.release
+	return b.HashEmbedder.Embed(texts)
+}
+
+func TestIndexEmbeddingsInBackground(t *testing.T) {
+	ps := setupProjectStore(t)
+	embedder := &blockingEmbedder{HashEmbedder: *NewHashEmbedder(), release: make(chan struct{})}
+	ps.SetEmbedder(embedder)
+
+	// The write returns while the embedder is still blocked
+	if _, err := ps.ApplyBatch([]BatchOp{{Op: OpCreateEntity, Entity: "Caddy", EntityType: "infrastructure"}}); err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+	close(embedder.release)
+	ps.waitIndexed(0)
+
+	var count int
+	ps.db.QueryRow(`SELECT COUNT(*) FROM embeddings WHERE model = ?`, embedder.Model()).Scan(&count)
+	if count != 1 {
+		t.Errorf("Expected the entity indexed in the background, got %d vectors", count)
+	}
+}
+
+func TestFuseRankings(t *testing.T) {
+	ids, scores := fuseRankings([]string{"a", "b", "c"}, []string{"b", "c"})
+	if ids[0] != "b" {
+		t.Errorf("b ranks well in both lists and should come first, got %v", ids)
+	}
+	if scores["a"] >= scores["c"] {
+		t.Error("c appears in both lists and should outscore a")
+	}
+}
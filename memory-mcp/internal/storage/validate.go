@@ -0,0 +1,624 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+// Validation rule identifiers, used as finding rules and in rule filters.
+const (
+	RuleUnknownEntityType  = "unknown_entity_type"
+	RuleBannedRelationType = "banned_relation_type"
+	RuleRelationTypeCase   = "relation_type_not_snake_case"
+	RuleDuplicateRelation  = "duplicate_relation"
+	RuleSelfLoop           = "self_loop"
+	RuleEmptyEntity        = "entity_without_observations"
+	RuleLongObservation    = "observation_too_long"
+	RuleMultiSentence      = "observation_not_atomic"
+	RuleDanglingRelation   = "relation_to_deleted_entity"
+	RuleOntologyRelation   = "relation_outside_ontology"
+)
+
+// Finding severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// DefaultEntityTypes are the entity types of the memory protocol in
+// MEMORY-USER-GUIDE.md.
+var DefaultEntityTypes = []string{
+	"person", "organization", "project", "component", "technology", "decision",
+	"lesson", "concept", "document", "milestone", "infrastructure", "protocol",
+}
+
+// BannedRelationTypes are vague relation types the protocol forbids.
+var BannedRelationTypes = []string{
+	"related_to", "relates_to", "is_related_to", "associated_with", "linked_to", "connected_to",
+}
+
+// DefaultMaxObservationLength is the default length limit, in characters,
+// above which an observation is reported as too long.
+const DefaultMaxObservationLength = 300
+
+var (
+	snakeCaseRe = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+	// sentenceBreakRe finds a sentence end followed by a new sentence. It is
+	// deliberately conservative: abbreviations and decimals do not match.
+	sentenceBreakRe = regexp.MustCompile(`[.!?;]\s+\p{Lu}`)
+)
+
+// ValidateOptions controls ValidateGraph.
+type ValidateOptions struct {
+	// Rules limits validation to these rule IDs; empty means all rules.
+	Rules []string
+	// AllowedEntityTypes defaults to the project ontology's entity types, or
+	// DefaultEntityTypes when the ontology lists none.
+	AllowedEntityTypes []string
+	// MaxObservationLength defaults to DefaultMaxObservationLength.
+	MaxObservationLength int
+	// Fix applies the safe auto-fixes in a single transaction.
+	Fix bool
+	// ExpectedVersions maps entity names to the version the client last
+	// read. With Fix, nothing is fixed and ValidateGraph fails with a
+	// *VersionConflictError if one of them is at another version.
+	ExpectedVersions map[string]int64
+}
+
+// ValidateGraph checks the project graph against the memory protocol rules
+// and returns one finding per violation. Safe fixes are: soft-deleting
+// duplicate relations, self-loops and relations to deleted entities,
+// normalizing relation types to snake_case, and normalizing entity types
+// that differ from an allowed type only by case or spacing.
+//
+// Relations are also checked against the ontology's relation types, whatever
+// its mode; relations written with an inverse name are fixed by reversing them.
+func (p *ProjectStore) ValidateGraph(opts ValidateOptions) (*models.ValidationReport, error) {
+	if opts.MaxObservationLength <= 0 {
+		opts.MaxObservationLength = DefaultMaxObservationLength
+	}
+	enabled := func(rule string) bool {
+		if len(opts.Rules) == 0 {
+			return true
+		}
+		for _, r := range opts.Rules {
+			if r == rule {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Only fixing writes; a plain lint reads in a deferred transaction so
+	// that writers are not held off while it scans
+	var tx *sql.Tx
+	var err error
+	if opts.Fix {
+		tx, err = p.begin()
+	} else {
+		tx, err = p.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if opts.Fix {
+		names := make([]string, 0, len(opts.ExpectedVersions))
+		for name := range opts.ExpectedVersions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			id, err := resolveEntity(tx, name)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkVersion(tx, id, name, opts.ExpectedVersions[name]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	ontology, err := loadOntologyChecker(tx)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.AllowedEntityTypes) == 0 {
+		opts.AllowedEntityTypes = ontology.entityTypes
+	}
+	if len(opts.AllowedEntityTypes) == 0 {
+		opts.AllowedEntityTypes = DefaultEntityTypes
+	}
+
+	v := &validator{tx: tx, opts: opts, fix: opts.Fix, ontology: ontology}
+	// Duplicates are looked for last, after the fixes that rewrite relation
+	// types and directions may have made some
+	checks := []struct {
+		rule string
+		run  func() error
+	}{
+		{RuleUnknownEntityType, v.checkEntityTypes},
+		{RuleBannedRelationType, v.checkBannedRelationTypes},
+		{RuleRelationTypeCase, v.checkRelationTypeCase},
+		{RuleSelfLoop, v.checkSelfLoops},
+		{RuleEmptyEntity, v.checkEmptyEntities},
+		{RuleLongObservation, v.checkLongObservations},
+		{RuleMultiSentence, v.checkMultiSentence},
+		{RuleDanglingRelation, v.checkDanglingRelations},
+		{RuleOntologyRelation, v.checkOntologyRelations},
+		{RuleDuplicateRelation, v.checkDuplicateRelations},
+	}
+	for _, c := range checks {
+		if !enabled(c.rule) {
+			continue
+		}
+		if err := c.run(); err != nil {
+			return nil, fmt.Errorf("%s: %w", c.rule, err)
+		}
+	}
+
+	if opts.Fix {
+		if err := p.commit(tx); err != nil {
+			return nil, fmt.Errorf("commit: %w", err)
+		}
+	}
+
+	report := &models.ValidationReport{
+		Findings: v.findings,
+		Counts:   map[string]int{SeverityError: 0, SeverityWarning: 0, SeverityInfo: 0},
+	}
+	if report.Findings == nil {
+		report.Findings = []models.Finding{}
+	}
+	for _, f := range report.Findings {
+		report.Counts[f.Severity]++
+		if f.Fixed {
+			report.Fixed++
+		}
+	}
+	return report, nil
+}
+
+// validator accumulates findings while running checks inside one transaction.
+type validator struct {
+	tx       *sql.Tx
+	opts     ValidateOptions
+	fix      bool
+	ontology *ontologyChecker
+	findings []models.Finding
+}
+
+func (v *validator) add(f models.Finding) {
+	v.findings = append(v.findings, f)
+}
+
+// applyFix runs a fix statement when fixing is enabled and reports whether
+// the finding was fixed.
+func (v *validator) applyFix(query string, args ...any) (bool, error) {
+	if !v.fix {
+		return false, nil
+	}
+	if _, err := v.tx.Exec(query, args...); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (v *validator) checkEntityTypes() error {
+	allowed := make(map[string]bool)
+	for _, t := range v.opts.AllowedEntityTypes {
+		allowed[t] = true
+	}
+
+	rows, err := v.tx.Query(`SELECT id, name, entity_type FROM entities WHERE deleted_at IS NULL ORDER BY name`)
+	if err != nil {
+		return err
+	}
+	type row struct{ id, name, entityType string }
+	var bad []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.name, &r.entityType); err != nil {
+			rows.Close()
+			return err
+		}
+		if !allowed[r.entityType] {
+			bad = append(bad, r)
+		}
+	}
+	rows.Close()
+
+	for _, r := range bad {
+		f := models.Finding{
+			ID:       RuleUnknownEntityType + ":" + r.id,
+			Rule:     RuleUnknownEntityType,
+			Severity: SeverityWarning,
+			Entity:   r.name,
+			Message:  fmt.Sprintf("entity type %q is not one of: %s", r.entityType, strings.Join(v.opts.AllowedEntityTypes, ", ")),
+		}
+		if normalized := strings.ToLower(strings.TrimSpace(r.entityType)); allowed[normalized] {
+			f.Fixable = true
+			f.Message += fmt.Sprintf(" (did you mean %q?)", normalized)
+			fixed, err := v.applyFix(`UPDATE entities SET entity_type = ?, updated_at = datetime('now') WHERE id = ?`, normalized, r.id)
+			if err != nil {
+				return err
+			}
+			f.Fixed = fixed
+		}
+		v.add(f)
+	}
+	return nil
+}
+
+func (v *validator) checkBannedRelationTypes() error {
+	rels, err := v.activeRelations(`AND r.relation_type IN (`+inPlaceholders(len(BannedRelationTypes))+`)`, stringArgs(BannedRelationTypes)...)
+	if err != nil {
+		return err
+	}
+	for _, r := range rels {
+		v.add(models.Finding{
+			ID:         RuleBannedRelationType + ":" + r.ID,
+			Rule:       RuleBannedRelationType,
+			Severity:   SeverityError,
+			Entity:     r.FromName,
+			RelationID: r.ID,
+			Message:    fmt.Sprintf("%s —%s→ %s uses a vague relation type; replace it with a specific active-voice verb", r.FromName, r.RelationType, r.ToName),
+		})
+	}
+	return nil
+}
+
+func (v *validator) checkRelationTypeCase() error {
+	rels, err := v.activeRelations("")
+	if err != nil {
+		return err
+	}
+	for _, r := range rels {
+		if snakeCaseRe.MatchString(r.RelationType) {
+			continue
+		}
+		f := models.Finding{
+			ID:         RuleRelationTypeCase + ":" + r.ID,
+			Rule:       RuleRelationTypeCase,
+			Severity:   SeverityWarning,
+			Entity:     r.FromName,
+			RelationID: r.ID,
+			Message:    fmt.Sprintf("relation type %q is not snake_case", r.RelationType),
+		}
+		if fixedType := toSnakeCase(r.RelationType); snakeCaseRe.MatchString(fixedType) {
+			f.Fixable = true
+			f.Message += fmt.Sprintf(" (use %q)", fixedType)
+			fixed, err := v.applyFix(`UPDATE relations SET relation_type = ? WHERE id = ?`, fixedType, r.ID)
+			if err != nil {
+				return err
+			}
+			f.Fixed = fixed
+		}
+		v.add(f)
+	}
+	return nil
+}
+
+func (v *validator) checkDuplicateRelations() error {
+	rows, err := v.tx.Query(`SELECT r.id FROM relations r WHERE r.deleted_at IS NULL AND ` + overlapsEarlierRelation)
+	if err != nil {
+		return err
+	}
+	dup := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		dup[id] = true
+	}
+	rows.Close()
+	if len(dup) == 0 {
+		return nil
+	}
+
+	rels, err := v.activeRelations("")
+	if err != nil {
+		return err
+	}
+	for _, r := range rels {
+		if !dup[r.ID] {
+			continue
+		}
+		fixed, err := v.applyFix(`UPDATE relations SET deleted_at = datetime('now') WHERE id = ?`, r.ID)
+		if err != nil {
+			return err
+		}
+		v.add(models.Finding{
+			ID:         RuleDuplicateRelation + ":" + r.ID,
+			Rule:       RuleDuplicateRelation,
+			Severity:   SeverityWarning,
+			Entity:     r.FromName,
+			RelationID: r.ID,
+			Message:    fmt.Sprintf("%s —%s→ %s is recorded more than once for overlapping periods", r.FromName, r.RelationType, r.ToName),
+			Fixable:    true,
+			Fixed:      fixed,
+		})
+	}
+	return nil
+}
+
+func (v *validator) checkSelfLoops() error {
+	rels, err := v.activeRelations(`AND r.from_entity = r.to_entity`)
+	if err != nil {
+		return err
+	}
+	for _, r := range rels {
+		fixed, err := v.applyFix(`UPDATE relations SET deleted_at = datetime('now') WHERE id = ?`, r.ID)
+		if err != nil {
+			return err
+		}
+		v.add(models.Finding{
+			ID:         RuleSelfLoop + ":" + r.ID,
+			Rule:       RuleSelfLoop,
+			Severity:   SeverityWarning,
+			Entity:     r.FromName,
+			RelationID: r.ID,
+			Message:    fmt.Sprintf("%s —%s→ itself", r.FromName, r.RelationType),
+			Fixable:    true,
+			Fixed:      fixed,
+		})
+	}
+	return nil
+}
+
+func (v *validator) checkEmptyEntities() error {
+	rows, err := v.tx.Query(
+		`SELECT e.id, e.name FROM entities e
+		 WHERE e.deleted_at IS NULL
+		   AND NOT EXISTS (SELECT 1 FROM observations o WHERE o.entity_id = e.id AND o.deleted_at IS NULL)
+		 ORDER BY e.name`,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		v.add(models.Finding{
+			ID:       RuleEmptyEntity + ":" + id,
+			Rule:     RuleEmptyEntity,
+			Severity: SeverityInfo,
+			Entity:   name,
+			Message:  fmt.Sprintf("%s has no observations", name),
+		})
+	}
+	return rows.Err()
+}
+
+// forEachObservation calls fn for every active observation of an active entity.
+func (v *validator) forEachObservation(fn func(id, entity, content string)) error {
+	rows, err := v.tx.Query(
+		`SELECT o.id, e.name, o.content FROM observations o
+		 JOIN entities e ON e.id = o.entity_id AND e.deleted_at IS NULL
+		 WHERE o.deleted_at IS NULL
+		 ORDER BY e.name, o.created_at`,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, entity, content string
+		if err := rows.Scan(&id, &entity, &content); err != nil {
+			return err
+		}
+		fn(id, entity, content)
+	}
+	return rows.Err()
+}
+
+func (v *validator) checkLongObservations() error {
+	return v.forEachObservation(func(id, entity, content string) {
+		if n := len([]rune(content)); n > v.opts.MaxObservationLength {
+			v.add(models.Finding{
+				ID:            RuleLongObservation + ":" + id,
+				Rule:          RuleLongObservation,
+				Severity:      SeverityWarning,
+				Entity:        entity,
+				ObservationID: id,
+				Message:       fmt.Sprintf("observation has %d characters (limit %d); split it into atomic facts", n, v.opts.MaxObservationLength),
+			})
+		}
+	})
+}
+
+func (v *validator) checkMultiSentence() error {
+	return v.forEachObservation(func(id, entity, content string) {
+		if n := len(sentenceBreakRe.FindAllStringIndex(content, -1)); n > 0 {
+			v.add(models.Finding{
+				ID:            RuleMultiSentence + ":" + id,
+				Rule:          RuleMultiSentence,
+				Severity:      SeverityInfo,
+				Entity:        entity,
+				ObservationID: id,
+				Message:       fmt.Sprintf("observation has %d sentences; one observation should hold one fact", n+1),
+			})
+		}
+	})
+}
+
+func (v *validator) checkDanglingRelations() error {
+	rows, err := v.tx.Query(
+		`SELECT r.id, r.relation_type, f.name, t.name, f.deleted_at IS NOT NULL, t.deleted_at IS NOT NULL
+		 FROM relations r
+		 JOIN entities f ON f.id = r.from_entity
+		 JOIN entities t ON t.id = r.to_entity
+		 WHERE r.deleted_at IS NULL AND (f.deleted_at IS NOT NULL OR t.deleted_at IS NOT NULL)
+		 ORDER BY f.name, r.relation_type, t.name`,
+	)
+	if err != nil {
+		return err
+	}
+	type row struct {
+		id, relType, from, to  string
+		fromDeleted, toDeleted bool
+	}
+	var dangling []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.relType, &r.from, &r.to, &r.fromDeleted, &r.toDeleted); err != nil {
+			rows.Close()
+			return err
+		}
+		dangling = append(dangling, r)
+	}
+	rows.Close()
+
+	for _, r := range dangling {
+		var deleted []string
+		if r.fromDeleted {
+			deleted = append(deleted, r.from)
+		}
+		if r.toDeleted {
+			deleted = append(deleted, r.to)
+		}
+		fixed, err := v.applyFix(`UPDATE relations SET deleted_at = datetime('now') WHERE id = ?`, r.id)
+		if err != nil {
+			return err
+		}
+		v.add(models.Finding{
+			ID:         RuleDanglingRelation + ":" + r.id,
+			Rule:       RuleDanglingRelation,
+			Severity:   SeverityError,
+			Entity:     r.from,
+			RelationID: r.id,
+			Message:    fmt.Sprintf("%s —%s→ %s points at deleted entity %s", r.from, r.relType, r.to, strings.Join(deleted, " and ")),
+			Fixable:    true,
+			Fixed:      fixed,
+		})
+	}
+	return nil
+}
+
+func (v *validator) checkOntologyRelations() error {
+	if len(v.ontology.relationTypes) == 0 {
+		return nil
+	}
+	rows, err := v.tx.Query(
+		`SELECT r.id, r.relation_type, f.name, f.entity_type, t.name, t.entity_type
+		 FROM relations r
+		 JOIN entities f ON f.id = r.from_entity AND f.deleted_at IS NULL
+		 JOIN entities t ON t.id = r.to_entity AND t.deleted_at IS NULL
+		 WHERE r.deleted_at IS NULL
+		 ORDER BY f.name, r.relation_type, t.name`,
+	)
+	if err != nil {
+		return err
+	}
+	type row struct{ id, relType, from, fromType, to, toType string }
+	var rels []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.relType, &r.from, &r.fromType, &r.to, &r.toType); err != nil {
+			rows.Close()
+			return err
+		}
+		rels = append(rels, r)
+	}
+	rows.Close()
+
+	for _, r := range rels {
+		violations := v.ontology.checkRelation(r.from, r.fromType, r.relType, r.to, r.toType)
+		if len(violations) == 0 {
+			continue
+		}
+		f := models.Finding{
+			ID:         RuleOntologyRelation + ":" + r.id,
+			Rule:       RuleOntologyRelation,
+			Severity:   SeverityWarning,
+			Entity:     r.from,
+			RelationID: r.id,
+			Message:    strings.Join(violations, "; "),
+		}
+		if inv, ok := v.ontology.inverses[r.relType]; ok {
+			f.Fixable = true
+			fixed, err := v.applyFix(
+				`UPDATE relations SET from_entity = to_entity, to_entity = from_entity, relation_type = ? WHERE id = ?`,
+				inv.Name, r.id,
+			)
+			if err != nil {
+				return err
+			}
+			f.Fixed = fixed
+		}
+		v.add(f)
+	}
+	return nil
+}
+
+// activeRelations loads active relations between active entities with names,
+// plus an optional extra WHERE fragment.
+func (v *validator) activeRelations(extra string, args ...any) ([]models.Relation, error) {
+	rows, err := v.tx.Query(
+		`SELECT `+relationColumns+`
+		 FROM relations r
+		 JOIN entities f ON f.id = r.from_entity AND f.deleted_at IS NULL
+		 JOIN entities t ON t.id = r.to_entity AND t.deleted_at IS NULL
+		 WHERE r.deleted_at IS NULL `+extra,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rels []models.Relation
+	for rows.Next() {
+		var r models.Relation
+		if err := rows.Scan(relationDest(&r)...); err != nil {
+			return nil, err
+		}
+		rels = append(rels, r)
+	}
+	sort.Slice(rels, func(i, j int) bool {
+		if rels[i].FromName != rels[j].FromName {
+			return rels[i].FromName < rels[j].FromName
+		}
+		return rels[i].CreatedAt < rels[j].CreatedAt
+	})
+	return rels, rows.Err()
+}
+
+// toSnakeCase converts "dependsOn", "Depends On" or "depends-on" to "depends_on".
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	prevLower := false
+	for _, r := range strings.TrimSpace(s) {
+		switch {
+		case unicode.IsUpper(r):
+			if prevLower {
+				b.WriteRune('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			prevLower = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+			prevLower = true
+		default:
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+				b.WriteRune('_')
+			}
+			prevLower = false
+		}
+	}
+	return strings.Trim(foldText(b.String()), "_")
+}
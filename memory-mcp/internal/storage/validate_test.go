@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+func TestValidateGraph(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{
+		{Name: "Go", EntityType: "Technology", Observations: []string{"Linguagem compilada. Criada no Google."}},
+		{Name: "SQLite", EntityType: "technology", Observations: []string{"Banco embarcado"}},
+		{Name: "Memory Cloud", EntityType: "tool"},
+		{Name: "Old", EntityType: "concept", Observations: []string{"Obsoleto"}},
+	})
+	ps.CreateRelations([]struct {
+		From         string
+		To           string
+		RelationType string
+	}{
+		{From: "Memory Cloud", To: "Go", RelationType: "related_to"},
+		{From: "Memory Cloud", To: "SQLite", RelationType: "dependsOn"},
+		{From: "Memory Cloud", To: "SQLite", RelationType: "uses"},
+		{From: "Go", To: "Go", RelationType: "extends"},
+		{From: "Old", To: "Go", RelationType: "replaces"},
+	})
+	// Record a duplicate directly, as older versions could
+	ps.db.Exec(`INSERT INTO relations (id, from_entity, to_entity, relation_type)
+		SELECT 'dup', from_entity, to_entity, relation_type FROM relations WHERE relation_type = 'uses'`)
+	// Soft-delete Old directly, leaving its relation behind as older versions could
+	ps.db.Exec(`UPDATE entities SET deleted_at = datetime('now') WHERE name = 'Old'`)
+
+	report, err := ps.ValidateGraph(ValidateOptions{})
+	if err != nil {
+		t.Fatalf("ValidateGraph: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for _, f := range report.Findings {
+		counts[f.Rule]++
+		if f.ID == "" || f.Severity == "" {
+			t.Errorf("Finding without ID or severity: %+v", f)
+		}
+	}
+	want := map[string]int{
+		RuleUnknownEntityType:  2, // Technology, tool
+		RuleBannedRelationType: 1,
+		RuleRelationTypeCase:   1,
+		RuleDuplicateRelation:  1,
+		RuleSelfLoop:           1,
+		RuleEmptyEntity:        1,
+		RuleMultiSentence:      1,
+		RuleDanglingRelation:   1,
+	}
+	for rule, n := range want {
+		if counts[rule] != n {
+			t.Errorf("%s: got %d findings, want %d", rule, counts[rule], n)
+		}
+	}
+	if report.Fixed != 0 {
+		t.Errorf("Nothing should be fixed without Fix, got %d", report.Fixed)
+	}
+
+	// Apply fixes, then only the unfixable findings remain
+	report, err = ps.ValidateGraph(ValidateOptions{Fix: true})
+	if err != nil {
+		t.Fatalf("ValidateGraph fix: %v", err)
+	}
+	if report.Fixed != 5 {
+		t.Errorf("Expected 5 fixes (type case, snake_case, duplicate, self-loop, dangling), got %d", report.Fixed)
+	}
+
+	report, _ = ps.ValidateGraph(ValidateOptions{})
+	for _, f := range report.Findings {
+		if f.Fixable {
+			t.Errorf("Fixable finding left after fixing: %+v", f)
+		}
+	}
+
+	entities, _ := ps.GetEntities([]string{"Go"})
+	if entities[0].EntityType != "technology" {
+		t.Errorf("Entity type should be normalized, got %q", entities[0].EntityType)
+	}
+}
+
+func TestValidateGraphDuplicatePeriods(t *testing.T) {
+	ps := setupProjectStore(t)
+	_, err := ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "João Silva", EntityType: "person"},
+		{Op: OpCreateEntity, Entity: "Memory Cloud", EntityType: "project"},
+		{Op: OpCreateRelation, From: "João Silva", To: "Memory Cloud", RelationType: "sponsors",
+			Validity: models.Validity{ValidFrom: "2024-01-01", ValidUntil: "2025-01-01"}},
+		{Op: OpCreateRelation, From: "João Silva", To: "Memory Cloud", RelationType: "sponsors",
+			Validity: models.Validity{ValidFrom: "2025-01-01"}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+	// Older versions could record the same relation for overlapping periods
+	ps.db.Exec(`INSERT INTO relations (id, from_entity, to_entity, relation_type, valid_from)
+		SELECT 'dup', from_entity, to_entity, relation_type, '2026-01-01' FROM relations LIMIT 1`)
+
+	// Fixing checks expected versions first
+	_, err = ps.ValidateGraph(ValidateOptions{Rules: []string{RuleDuplicateRelation}, Fix: true,
+		ExpectedVersions: map[string]int64{"João Silva": 1}})
+	var vc *VersionConflictError
+	if !errors.As(err, &vc) || vc.Entity != "João Silva" {
+		t.Fatalf("Expected a version conflict, got %v", err)
+	}
+
+	report, err := ps.ValidateGraph(ValidateOptions{Rules: []string{RuleDuplicateRelation}, Fix: true})
+	if err != nil {
+		t.Fatalf("ValidateGraph: %v", err)
+	}
+	if len(report.Findings) != 1 || report.Findings[0].RelationID != "dup" || !report.Findings[0].Fixed {
+		t.Fatalf("Only the overlapping relation should be a fixed duplicate, got %+v", report.Findings)
+	}
+	history, _ := ps.At("", true)
+	entities, _ := history.GetEntities([]string{"João Silva"})
+	if len(entities) != 1 || len(entities[0].Outgoing) != 2 {
+		t.Errorf("Both sponsorship periods should be kept, got %+v", entities)
+	}
+}
+
+func TestValidateGraphDuplicatesAfterFixes(t *testing.T) {
+	ps := setupProjectStore(t)
+	_, err := ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Go", EntityType: "technology", Observations: []string{"Linguagem"}},
+		{Op: OpCreateEntity, Entity: "Memory Cloud", EntityType: "project", Observations: []string{"Servidor MCP"}},
+		{Op: OpCreateRelation, From: "Memory Cloud", To: "Go", RelationType: "uses"},
+		{Op: OpCreateRelation, From: "Go", To: "Memory Cloud", RelationType: "used_by"},
+		{Op: OpCreateRelation, From: "Memory Cloud", To: "Go", RelationType: "depends_on"},
+		{Op: OpCreateRelation, From: "Memory Cloud", To: "Go", RelationType: "DependsOn"},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+	ps.SetOntology(testOntology(OntologyLenient))
+
+	// Reversing used_by and renaming DependsOn leave duplicates that are
+	// fixed in the same run
+	report, err := ps.ValidateGraph(ValidateOptions{Fix: true})
+	if err != nil {
+		t.Fatalf("ValidateGraph: %v", err)
+	}
+	duplicates := 0
+	for _, f := range report.Findings {
+		if f.Rule == RuleDuplicateRelation && f.Fixed {
+			duplicates++
+		}
+	}
+	if duplicates != 2 {
+		t.Errorf("Expected 2 fixed duplicates, got %+v", report.Findings)
+	}
+	graph, _ := ps.ReadGraph()
+	if len(graph.Relations) != 2 {
+		t.Errorf("Expected uses and depends_on once each, got %+v", graph.Relations)
+	}
+}
+
+func TestValidateGraphRuleFilter(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	ps.CreateEntities([]struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{{Name: "X", EntityType: "thing", Observations: []string{"abcdefghij"}}})
+
+	report, err := ps.ValidateGraph(ValidateOptions{Rules: []string{RuleLongObservation}, MaxObservationLength: 5})
+	if err != nil {
+		t.Fatalf("ValidateGraph: %v", err)
+	}
+	if len(report.Findings) != 1 || report.Findings[0].Rule != RuleLongObservation {
+		t.Errorf("Expected only the long-observation finding, got %+v", report.Findings)
+	}
+}
+
+func TestToSnakeCase(t *testing.T) {
+	for in, want := range map[string]string{
+		"dependsOn":  "depends_on",
+		"Depends On": "depends_on",
+		"depends-on": "depends_on",
+		"USES":       "uses",
+	} {
+		if got := toSnakeCase(in); got != want {
+			t.Errorf("toSnakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+// Limits bounds what a single write may store. Lengths count characters
+// after normalization; a limit of 0 or less disables the check.
+type Limits struct {
+	MaxNameLength        int // entity names
+	MaxTypeLength        int // entity and relation types
+	MaxObservationLength int // observation contents
+	MaxBatchItems        int // operations per batch
+}
+
+// DefaultLimits are the limits of stores that were not given others.
+var DefaultLimits = Limits{
+	MaxNameLength:        200,
+	MaxTypeLength:        64,
+	MaxObservationLength: 8000,
+	MaxBatchItems:        1000,
+}
+
+// Project names are slugs: lowercase letters and digits, separated by single
+// hyphens, underscores or dots, so they are safe in paths and URLs.
+const maxProjectNameLength = 64
+
+var projectNamePattern = regexp.MustCompile(`^[a-z0-9]+(?:[-_.][a-z0-9]+)*$`)
+
+// SetLimits replaces the limits checked on writes.
+func (p *ProjectStore) SetLimits(l Limits) {
+	p.limits = l
+}
+
+// normalizeText trims s and converts it to Unicode NFC, so that equal text
+// is stored and compared as the same bytes.
+func normalizeText(s string) string {
+	return norm.NFC.String(strings.TrimSpace(s))
+}
+
+// checkText normalizes *s and checks its length. An empty value fails when
+// required; otherwise it is left for the caller to treat as absent.
+func checkText(field string, s *string, max int, required bool) error {
+	*s = normalizeText(*s)
+	if *s == "" {
+		if required {
+			return invalidf("%s is required", field)
+		}
+		return nil
+	}
+	if n := utf8.RuneCountInString(*s); max > 0 && n > max {
+		return invalidf("%s is %d characters long; the limit is %d", field, n, max)
+	}
+	return nil
+}
+
+// checkObservations normalizes each observation and checks its length.
+func (l Limits) checkObservations(contents []string) error {
+	for i := range contents {
+		if err := checkText("observation", &contents[i], l.MaxObservationLength, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkMeta normalizes observation metadata and checks it: source up to the
+// observation length, author up to the name length, tags up to the type
+// length (blank and repeated tags are dropped) and confidence from 0 to 1.
+// Tags is replaced rather than modified in place.
+func (l Limits) checkMeta(meta *models.ObservationMeta) error {
+	if err := checkText("source", &meta.Source, l.MaxObservationLength, false); err != nil {
+		return err
+	}
+	if err := checkText("author", &meta.Author, l.MaxNameLength, false); err != nil {
+		return err
+	}
+	if c := meta.Confidence; c != nil && (*c < 0 || *c > 1 || math.IsNaN(*c)) {
+		return invalidf("confidence must be between 0 and 1, got %v", *c)
+	}
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range meta.Tags {
+		if err := checkText("tag", &tag, l.MaxTypeLength, false); err != nil {
+			return err
+		}
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	meta.Tags = tags
+	return nil
+}
+
+// checkEntity normalizes and checks a new entity's name, type and
+// observations.
+func (l Limits) checkEntity(name, entityType *string, observations []string) error {
+	if err := checkText("entity name", name, l.MaxNameLength, true); err != nil {
+		return err
+	}
+	if err := checkText("entity_type", entityType, l.MaxTypeLength, true); err != nil {
+		return err
+	}
+	return l.checkObservations(observations)
+}
+
+// checkBatchSize rejects batches with more than MaxBatchItems operations.
+func (l Limits) checkBatchSize(n int) error {
+	if l.MaxBatchItems > 0 && n > l.MaxBatchItems {
+		return invalidf("%d items exceed the limit of %d per batch", n, l.MaxBatchItems)
+	}
+	return nil
+}
+
+// checkOp normalizes the names op refers to and the values it writes, and
+// checks that those values are present and within the limits. Observation
+// contents that op only looks up are left as given, to match rows stored
+// before normalization. Observations is copied before it is normalized, as
+// it may share the caller's array.
+func (l Limits) checkOp(op *BatchOp) error {
+	for _, name := range []*string{&op.Entity, &op.From, &op.To} {
+		*name = normalizeText(*name)
+	}
+	if err := checkValidity(&op.Validity); err != nil {
+		return err
+	}
+	switch op.Op {
+	case OpCreateEntity:
+		op.Observations = append([]string(nil), op.Observations...)
+		if err := l.checkEntity(&op.Entity, &op.EntityType, op.Observations); err != nil {
+			return err
+		}
+		return l.checkMeta(&op.Meta)
+	case OpUpdateEntity:
+		if err := checkText("new_name", &op.NewName, l.MaxNameLength, false); err != nil {
+			return err
+		}
+		return checkText("new_type", &op.NewType, l.MaxTypeLength, false)
+	case OpAddObservation:
+		op.Observations = append([]string(nil), op.Observations...)
+		if err := l.checkObservations(op.Observations); err != nil {
+			return err
+		}
+		return l.checkMeta(&op.Meta)
+	case OpUpdateObservation:
+		return checkText("new_content", &op.NewContent, l.MaxObservationLength, op.Validity == models.Validity{})
+	case OpCreateRelation, OpUpdateRelation:
+		return checkText("relation_type", &op.RelationType, l.MaxTypeLength, true)
+	case OpSetProperty:
+		return checkText("property key", &op.Key, l.MaxTypeLength, true)
+	}
+	return nil
+}
+
+// checkProjectName normalizes a new project's name and checks that it is a
+// slug.
+func checkProjectName(name *string) error {
+	if err := checkText("project name", name, maxProjectNameLength, true); err != nil {
+		return err
+	}
+	if !projectNamePattern.MatchString(*name) {
+		return invalidf("project name %q must be lowercase letters and digits separated by -, _ or .", *name)
+	}
+	return nil
+}
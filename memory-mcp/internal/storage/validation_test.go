@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCreateEntitiesNormalizes(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	entities := []struct {
+		Name         string
+		EntityType   string
+		Observations []string
+	}{
+		// "José" spelled with a combining acute accent
+		{Name: "  Jose\u0301 ", EntityType: " person\n", Observations: []string{" Likes Go  "}},
+	}
+	created, err := ps.CreateEntities(entities)
+	if err != nil {
+		t.Fatalf("CreateEntities: %v", err)
+	}
+	if e := created[0]; e.Name != "José" || e.EntityType != "person" || e.Observations[0].Content != "Likes Go" {
+		t.Errorf("Expected trimmed NFC values, got %q %q %q", e.Name, e.EntityType, e.Observations[0].Content)
+	}
+	if entities[0].Name != "  Jose\u0301 " {
+		t.Error("CreateEntities should not modify its argument")
+	}
+	if got, _ := ps.GetEntities([]string{"José"}); len(got) != 1 {
+		t.Error("The precomposed name should find the entity")
+	}
+}
+
+func TestValidationLimits(t *testing.T) {
+	ps := setupProjectStore(t)
+	ps.SetLimits(Limits{MaxNameLength: 10, MaxTypeLength: 8, MaxObservationLength: 20, MaxBatchItems: 3})
+
+	tests := []struct {
+		name string
+		op   BatchOp
+		want string
+	}{
+		{"blank name", BatchOp{Op: OpCreateEntity, Entity: "   ", EntityType: "thing"}, "entity name is required"},
+		{"blank type", BatchOp{Op: OpCreateEntity, Entity: "Go", EntityType: ""}, "entity_type is required"},
+		{"long name", BatchOp{Op: OpCreateEntity, Entity: "Memory Cloud MCP", EntityType: "project"}, "entity name is 16 characters long; the limit is 10"},
+		{"long type", BatchOp{Op: OpCreateEntity, Entity: "Go", EntityType: "programming language"}, "entity_type is 20 characters long"},
+		{"blank observation", BatchOp{Op: OpCreateEntity, Entity: "Go", EntityType: "language", Observations: []string{" "}}, "observation is required"},
+		{"long observation", BatchOp{Op: OpCreateEntity, Entity: "Go", EntityType: "language", Observations: []string{strings.Repeat("é", 21)}}, "observation is 21 characters long"},
+		{"long relation type", BatchOp{Op: OpCreateRelation, From: "A", To: "B", RelationType: "depends_on_heavily"}, "relation_type is 18 characters long"},
+		{"long new name", BatchOp{Op: OpUpdateEntity, Entity: "A", NewName: "A much longer name"}, "new_name is 18 characters long"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ps.ApplyBatchItems([]BatchOp{tt.op}, ModeBestEffort)
+			if err != nil {
+				t.Fatalf("ApplyBatchItems: %v", err)
+			}
+			if res := result.Results[0]; res.Status != StatusInvalid || !strings.Contains(res.Reason, tt.want) {
+				t.Errorf("Expected invalid %q, got %+v", tt.want, res)
+			}
+		})
+	}
+
+	_, err := ps.ApplyBatchItems(make([]BatchOp, 4), ModeBestEffort)
+	if !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), "limit of 3 per batch") {
+		t.Errorf("Expected the batch size limit, got %v", err)
+	}
+
+	// A limit of 0 disables the check
+	ps.SetLimits(Limits{})
+	if _, err := ps.ApplyBatch([]BatchOp{{Op: OpCreateEntity, Entity: "Memory Cloud MCP", EntityType: "project"}}); err != nil {
+		t.Errorf("Disabled limits should accept the entity: %v", err)
+	}
+}
+
+func TestCreateProjectName(t *testing.T) {
+	meta, err := OpenMeta(tempDir(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer meta.Close()
+
+	for _, name := range []string{"", "My Project", "../escape", "a/b", "café", "proj--x", "-proj", "projeto🚀", strings.Repeat("a", 65)} {
+		if _, err := meta.CreateProject(name, ""); !errors.Is(err, ErrInvalid) {
+			t.Errorf("CreateProject(%q) should be invalid, got %v", name, err)
+		}
+	}
+	proj, err := meta.CreateProject(" cliente-acme_2.0 ", "")
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	if proj.Name != "cliente-acme_2.0" {
+		t.Errorf("Name = %q, want it trimmed", proj.Name)
+	}
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+// Validity times are stored as dates ("2026-02-01") or UTC timestamps
+// ("2026-02-01T09:30:00Z"), which order correctly as strings; a date stands
+// for its first instant.
+const (
+	validDateLayout = "2006-01-02"
+	validTimeLayout = "2006-01-02T15:04:05Z"
+)
+
+// normalizeValidTime parses a date, a month ("2026-02", its first day) or an
+// RFC 3339 timestamp into the stored form. An empty string stays empty.
+func normalizeValidTime(field, s string) (string, error) {
+	s = normalizeText(s)
+	if s == "" {
+		return "", nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC().Format(validTimeLayout), nil
+	}
+	if t, err := time.Parse(validDateLayout, s); err == nil {
+		return t.Format(validDateLayout), nil
+	}
+	if t, err := time.Parse("2006-01", s); err == nil {
+		return t.Format(validDateLayout), nil
+	}
+	return "", invalidf("%s %q is not a date (2026-02-01), month (2026-02) or RFC 3339 time", field, s)
+}
+
+// checkValidity normalizes the bounds of v and checks that ValidFrom comes
+// before ValidUntil.
+func checkValidity(v *models.Validity) error {
+	var err error
+	if v.ValidFrom, err = normalizeValidTime("valid_from", v.ValidFrom); err != nil {
+		return err
+	}
+	if v.ValidUntil, err = normalizeValidTime("valid_until", v.ValidUntil); err != nil {
+		return err
+	}
+	if v.ValidFrom != "" && v.ValidUntil != "" && v.ValidFrom >= v.ValidUntil {
+		return invalidf("valid_from %s must be before valid_until %s", v.ValidFrom, v.ValidUntil)
+	}
+	v.Historical = false
+	return nil
+}
+
+// At returns a view of the store whose reads show observations and relations
+// as of validAt (a date, month or RFC 3339 time; empty means now). Facts not
+// valid then are left out, or returned marked historical when
+// includeHistorical is set. Writes go through the view unchanged.
+func (p *ProjectStore) At(validAt string, includeHistorical bool) (*ProjectStore, error) {
+	at, err := normalizeValidTime("valid_at", validAt)
+	if err != nil {
+		return nil, err
+	}
+	return &ProjectStore{
+		db:                p.db,
+		embedder:          p.embedder,
+		indexer:           p.indexer,
+		idempotencyWindow: p.idempotencyWindow,
+		limits:            p.limits,
+		base:              p,
+		changes:           p.changes,
+		validAt:           at,
+		historical:        includeHistorical,
+	}, nil
+}
+
+// validityFilter decides which facts a read returns.
+type validityFilter struct {
+	at         string
+	historical bool
+}
+
+// validity returns the filter of the store's reads, as of now unless the
+// store is a view made by At.
+func (p *ProjectStore) validity() validityFilter {
+	at := p.validAt
+	if at == "" {
+		at = time.Now().UTC().Format(validTimeLayout)
+	}
+	return validityFilter{at: at, historical: p.historical}
+}
+
+// keep reports whether a fact with validity v is returned, marking it
+// historical when it is returned although not valid at f.at.
+func (f validityFilter) keep(v *models.Validity) bool {
+	valid := (v.ValidFrom == "" || v.ValidFrom <= f.at) && (v.ValidUntil == "" || f.at < v.ValidUntil)
+	v.Historical = !valid
+	return valid || f.historical
+}
+
+// where returns a condition on the validity columns of the table aliased
+// alias that keeps only the facts f returns, prefixed with AND, with its
+// arguments. It is empty when historical facts are included.
+func (f validityFilter) where(alias string) (string, []any) {
+	if f.historical {
+		return "", nil
+	}
+	return fmt.Sprintf(" AND (%[1]s.valid_from IS NULL OR %[1]s.valid_from <= ?) AND (%[1]s.valid_until IS NULL OR ? < %[1]s.valid_until)", alias),
+		[]any{f.at, f.at}
+}
+
+// overlapsEarlierRelation is the condition that the active relation r has
+// the same endpoints and type as an active relation recorded before it whose
+// validity period overlaps its own, which makes r a duplicate. The same
+// relation recorded for periods that do not overlap is not duplicated.
+const overlapsEarlierRelation = `EXISTS (
+    SELECT 1 FROM relations d
+    WHERE d.from_entity = r.from_entity AND d.to_entity = r.to_entity AND d.relation_type = r.relation_type
+      AND d.deleted_at IS NULL AND (d.created_at, d.rowid) < (r.created_at, r.rowid)
+      AND (d.valid_from IS NULL OR r.valid_until IS NULL OR d.valid_from < r.valid_until)
+      AND (r.valid_from IS NULL OR d.valid_until IS NULL OR r.valid_from < d.valid_until))`
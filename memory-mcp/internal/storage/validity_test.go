@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+func TestNormalizeValidTime(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"2026-02-01", "2026-02-01"},
+		{" 2026-02 ", "2026-02-01"},
+		{"2026-02-01T09:30:00Z", "2026-02-01T09:30:00Z"},
+		{"2026-02-01T09:30:00-03:00", "2026-02-01T12:30:00Z"},
+	}
+	for _, tt := range tests {
+		if got, err := normalizeValidTime("valid_from", tt.in); err != nil || got != tt.want {
+			t.Errorf("normalizeValidTime(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"yesterday", "01/02/2026", "2026-13-01"} {
+		if _, err := normalizeValidTime("valid_from", in); !errors.Is(err, ErrInvalid) {
+			t.Errorf("normalizeValidTime(%q) should be invalid, got %v", in, err)
+		}
+	}
+}
+
+func TestValidity(t *testing.T) {
+	ps := setupProjectStore(t)
+
+	_, err := ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Alice", EntityType: "person", Observations: []string{"Lives in Lisbon"},
+			Validity: models.Validity{ValidUntil: "2020-01-01"}},
+		{Op: OpAddObservation, Entity: "Alice", Observations: []string{"Lives in Porto"},
+			Validity: models.Validity{ValidFrom: "2020-01-01"}},
+		{Op: OpCreateEntity, Entity: "Acme", EntityType: "company"},
+		{Op: OpCreateEntity, Entity: "Globex", EntityType: "company"},
+		{Op: OpCreateRelation, From: "Alice", RelationType: "works_at", To: "Acme",
+			Validity: models.Validity{ValidFrom: "2018-03", ValidUntil: "2022-06"}},
+		{Op: OpCreateRelation, From: "Alice", RelationType: "works_at", To: "Globex",
+			Validity: models.Validity{ValidFrom: "2022-06"}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+
+	// Now: only the current facts
+	entities, err := ps.GetEntities([]string{"Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice := entities[0]
+	if len(alice.Observations) != 1 || alice.Observations[0].Content != "Lives in Porto" {
+		t.Errorf("Expected only the current observation, got %+v", alice.Observations)
+	}
+	if len(alice.Outgoing) != 1 || alice.Outgoing[0].ToName != "Globex" {
+		t.Errorf("Expected only the current relation, got %+v", alice.Outgoing)
+	}
+
+	// In 2019: the facts valid then
+	past, err := ps.At("2019-05-10", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entities, _ = past.GetEntities([]string{"Alice"})
+	alice = entities[0]
+	if len(alice.Observations) != 1 || alice.Observations[0].Content != "Lives in Lisbon" {
+		t.Errorf("Expected the 2019 observation, got %+v", alice.Observations)
+	}
+	if len(alice.Outgoing) != 1 || alice.Outgoing[0].ToName != "Acme" || alice.Outgoing[0].ValidFrom != "2018-03-01" {
+		t.Errorf("Expected the 2019 relation, got %+v", alice.Outgoing)
+	}
+	graph, err := past.ReadGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(graph.Relations) != 1 || graph.Relations[0].ToName != "Acme" {
+		t.Errorf("read_graph in 2019 should list the Acme relation only, got %+v", graph.Relations)
+	}
+
+	// Historical facts are kept and marked
+	all, _ := ps.At("", true)
+	entities, _ = all.GetEntities([]string{"Alice"})
+	alice = entities[0]
+	if len(alice.Observations) != 2 || !alice.Observations[0].Historical || alice.Observations[1].Historical {
+		t.Errorf("Expected the Lisbon observation marked historical, got %+v", alice.Observations)
+	}
+	if len(alice.Outgoing) != 2 {
+		t.Errorf("Expected both relations, got %+v", alice.Outgoing)
+	}
+
+	// Traversals only follow the relations valid at the time
+	sub, err := ps.Traverse([]string{"Alice"}, TraverseOptions{Direction: "outgoing"})
+	if err != nil {
+		t.Fatalf("Traverse: %v", err)
+	}
+	if len(sub.Entities) != 2 || sub.Entities[1].Name != "Globex" || len(sub.Relations) != 1 {
+		t.Errorf("Expected Alice and Globex only, got %+v", sub)
+	}
+	sub, _ = all.Traverse([]string{"Alice"}, TraverseOptions{Direction: "outgoing"})
+	if len(sub.Entities) != 3 || len(sub.Relations) != 2 {
+		t.Errorf("Expected every employer with historical relations, got %+v", sub)
+	}
+
+	if _, err := ps.At("last year", false); !errors.Is(err, ErrInvalid) {
+		t.Errorf("At should reject an unparseable time, got %v", err)
+	}
+}
+
+func TestValidityWrites(t *testing.T) {
+	ps := setupProjectStore(t)
+	ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateEntity, Entity: "Alice", EntityType: "person", Observations: []string{"Senior engineer"}},
+		{Op: OpCreateEntity, Entity: "Acme", EntityType: "company"},
+		{Op: OpCreateRelation, From: "Alice", RelationType: "works_at", To: "Acme"},
+	})
+
+	// Ending a relation and an observation hides them without deleting them
+	result, err := ps.ApplyBatch([]BatchOp{
+		{Op: OpUpdateRelation, From: "Alice", RelationType: "works_at", To: "Acme", Validity: models.Validity{ValidUntil: "2024-01-01"}},
+		{Op: OpUpdateObservation, Entity: "Alice", Observation: "Senior engineer", Validity: models.Validity{ValidUntil: "2024-01-01"}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyBatch: %v", err)
+	}
+	if rel := result.Results[0].Relation; rel == nil || rel.ValidUntil != "2024-01-01" {
+		t.Errorf("Expected the ended relation, got %+v", rel)
+	}
+	if obs := result.Results[1].Observations; len(obs) != 1 || obs[0].Content != "Senior engineer" {
+		t.Errorf("Updating the validity should keep the content, got %+v", obs)
+	}
+	entities, _ := ps.GetEntities([]string{"Alice"})
+	if len(entities[0].Observations) != 0 || len(entities[0].Outgoing) != 0 {
+		t.Errorf("Ended facts should be hidden, got %+v", entities[0])
+	}
+	if list, _ := ps.ListRelations(ListRelationsOptions{}); len(list.Relations) != 0 || list.TotalRelations != 0 {
+		t.Errorf("list_relations should hide the ended relation, got %+v", list)
+	}
+	history, _ := ps.At("", true)
+	if list, _ := history.ListRelations(ListRelationsOptions{}); len(list.Relations) != 1 || !list.Relations[0].Historical {
+		t.Errorf("list_relations should list the ended relation as historical on request, got %+v", list.Relations)
+	}
+	past, _ := ps.At("2023-06", false)
+	if list, _ := past.ListRelations(ListRelationsOptions{}); len(list.Relations) != 1 || list.Relations[0].Historical {
+		t.Errorf("list_relations should list the relation as of 2023, got %+v", list.Relations)
+	}
+
+	// Ended observations neither match searches nor rank
+	if found, _ := ps.Search("senior"); len(found) != 0 {
+		t.Errorf("An ended observation should not match, got %+v", found)
+	}
+	if found, _ := past.Search("senior"); len(found) != 1 {
+		t.Errorf("The observation should match as of 2023, got %+v", found)
+	}
+	if ranked, _ := ps.rankFTS(ftsAnyTermQuery("senior")); len(ranked) != 0 {
+		t.Errorf("An ended observation should not rank, got %v", ranked)
+	}
+	aliceScore := func(store *ProjectStore) float64 {
+		scored, _ := store.SemanticSearch("senior engineer", 5, false)
+		for _, e := range scored {
+			if e.Name == "Alice" {
+				return e.Score
+			}
+		}
+		return 0
+	}
+	if now, then := aliceScore(ps), aliceScore(past); now >= then {
+		t.Errorf("An ended observation should not rank Alice now (%v) as it did in 2023 (%v)", now, then)
+	}
+
+	// The relation can be recorded again for a later period, not an overlapping one
+	if _, err := ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateRelation, From: "Alice", RelationType: "works_at", To: "Acme", Validity: models.Validity{ValidFrom: "2023-06-01"}},
+	}); !errors.Is(err, ErrConflict) {
+		t.Errorf("An overlapping period should conflict, got %v", err)
+	}
+	if _, err := ps.ApplyBatch([]BatchOp{
+		{Op: OpCreateRelation, From: "Alice", RelationType: "works_at", To: "Acme", Validity: models.Validity{ValidFrom: "2025-01-01"}},
+	}); err != nil {
+		t.Errorf("A later period should be accepted: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		op   BatchOp
+	}{
+		{"bad time", BatchOp{Op: OpCreateEntity, Entity: "Bob", EntityType: "person", Validity: models.Validity{ValidFrom: "soon"}}},
+		{"reversed period", BatchOp{Op: OpCreateRelation, From: "Alice", RelationType: "knows", To: "Acme",
+			Validity: models.Validity{ValidFrom: "2024-01-01", ValidUntil: "2023-01-01"}}},
+		{"until before stored from", BatchOp{Op: OpUpdateRelation, From: "Alice", RelationType: "works_at", To: "Acme",
+			Validity: models.Validity{ValidUntil: "2024-06-01"}}},
+		{"nothing to update", BatchOp{Op: OpUpdateObservation, Entity: "Alice", Observation: "Senior engineer"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ps.ApplyBatchItems([]BatchOp{tt.op}, ModeBestEffort)
+			if err != nil {
+				t.Fatalf("ApplyBatchItems: %v", err)
+			}
+			if res := result.Results[0]; res.Status != StatusInvalid {
+				t.Errorf("Expected invalid, got %+v", res)
+			}
+		})
+	}
+}
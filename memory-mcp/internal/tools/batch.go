@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/storage"
+)
+
+// --- Input types ---
+
+type ApplyBatchInput struct {
+	Operations []BatchOpInput `json:"operations" jsonschema:"Operations to apply in order, in one transaction"`
+	BatchOptions
+	WriteOptions
+}
+
+type BatchOpInput struct {
+	Op              string   `json:"op" jsonschema:"create_entity, update_entity, delete_entity, add_observation, update_observation, delete_observation, create_relation, update_relation, delete_relation or set_property"`
+	Entity          string   `json:"entity,omitempty" jsonschema:"Entity name for entity, observation and property operations; may name an entity created earlier in the batch"`
+	EntityType      string   `json:"entity_type,omitempty" jsonschema:"Entity type (create_entity)"`
+	NewName         string   `json:"new_name,omitempty" jsonschema:"New entity name (update_entity); the old name stays resolvable as an alias"`
+	NewType         string   `json:"new_type,omitempty" jsonschema:"New entity type (update_entity)"`
+	Observations    []string `json:"observations,omitempty" jsonschema:"Observation contents (create_entity, add_observation, delete_observation)"`
+	Observation     string   `json:"observation,omitempty" jsonschema:"Current content of the observation to change (update_observation)"`
+	NewContent      string   `json:"new_content,omitempty" jsonschema:"Replacement content (update_observation); optional when valid_from or valid_until is set"`
+	From            string   `json:"from,omitempty" jsonschema:"Source entity name (relation operations)"`
+	To              string   `json:"to,omitempty" jsonschema:"Target entity name (relation operations)"`
+	RelationType    string   `json:"relation_type,omitempty" jsonschema:"Relation type (relation operations)"`
+	Key             string   `json:"key,omitempty" jsonschema:"Property key (set_property)"`
+	Value           any      `json:"value,omitempty" jsonschema:"Property value (set_property); null or omitted removes the property"`
+	PropertyType    string   `json:"property_type,omitempty" jsonschema:"string, number, date, bool or json (set_property; default inferred from the value)"`
+	ExpectedVersion int64    `json:"expected_version,omitempty" jsonschema:"Fail with a conflict unless the entity (the source entity, for relation operations) is at this version (from open_nodes, read_graph or a previous write)"`
+	ObservationMetaInput
+	ValidityInput
+}
+
+// BatchOptions are accepted by the tools that apply a list of items.
+type BatchOptions struct {
+	Mode string `json:"mode,omitempty" jsonschema:"all_or_nothing (default): apply nothing if any item fails; best_effort: apply the items that succeed"`
+}
+
+// --- Handlers ---
+
+func (t *KnowledgeTools) ApplyBatch(_ context.Context, _ *mcp.CallToolRequest, input ApplyBatchInput) (*mcp.CallToolResult, any, error) {
+	ps, errResult := t.requireProject()
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	if len(input.Operations) == 0 {
+		return toolError("At least one operation is required"), nil, nil
+	}
+
+	ops := make([]storage.BatchOp, len(input.Operations))
+	for i, op := range input.Operations {
+		ops[i] = storage.BatchOp{
+			Op:              op.Op,
+			Entity:          op.Entity,
+			EntityType:      op.EntityType,
+			NewName:         op.NewName,
+			NewType:         op.NewType,
+			Observations:    op.Observations,
+			Meta:            op.meta(),
+			Observation:     op.Observation,
+			NewContent:      op.NewContent,
+			From:            op.From,
+			To:              op.To,
+			RelationType:    op.RelationType,
+			Validity:        op.validity(),
+			Key:             op.Key,
+			Value:           op.Value,
+			PropertyType:    op.PropertyType,
+			ExpectedVersion: op.ExpectedVersion,
+		}
+	}
+
+	return applyItems(ps, ops, input.BatchOptions, input.WriteOptions, "apply batch")
+}
+
+// applyItems applies ops and returns the status of each, or the change report
+// of a dry run. A batch rolled back because an item failed is an error
+// result, whose content still lists every item's status and reason.
+func applyItems(ps *storage.ProjectStore, ops []storage.BatchOp, batch BatchOptions, write WriteOptions, what string) (*mcp.CallToolResult, any, error) {
+	store := write.writeStore(ps)
+	result, err := store.ApplyBatchItems(ops, batch.Mode)
+	if err != nil {
+		return toolError("Failed to %s: %v", what, err), nil, nil
+	}
+	if !result.Applied {
+		res, _, _ := toolJSON(result)
+		res.IsError = true
+		res.StructuredContent = batchFailure(result, what)
+		return res, nil, nil
+	}
+	if write.DryRun {
+		res, _, _ := toolJSON(store.Changes())
+		return appendOntologyWarnings(res, result.Warnings), nil, nil
+	}
+	return toolJSON(result)
+}
+
+// batchFailure describes a rolled-back batch by its first failed item, whose
+// status is the error code.
+func batchFailure(result *models.BatchResult, what string) ToolError {
+	te := ToolError{Code: CodeInternal, Message: "Failed to " + what}
+	for i, r := range result.Results {
+		switch r.Status {
+		case storage.StatusNotFound, storage.StatusConflict, storage.StatusInvalid:
+			te.Code = r.Status
+			te.Message = fmt.Sprintf("Failed to %s: operation %d (%s): %s", what, i, r.Op, r.Reason)
+			te.Details = map[string]any{"index": i, "op": r.Op, "results": result.Results}
+			return te
+		}
+	}
+	return te
+}
@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/storage"
+)
+
+// --- Input types ---
+
+type ListEntitiesInput struct {
+	EntityTypes []string `json:"entity_types,omitempty" jsonschema:"Only list entities of these types"`
+	Prefix      string   `json:"prefix,omitempty" jsonschema:"Only list entities whose name starts with this (case-sensitive)"`
+	Sort        string   `json:"sort,omitempty" jsonschema:"Order: name (default), updated (most recent first) or observations (most first)"`
+	Limit       int      `json:"limit,omitempty" jsonschema:"Maximum entities per page (default 100, max 1000)"`
+	Cursor      string   `json:"cursor,omitempty" jsonschema:"next_cursor from the previous page"`
+	OutputOptions
+}
+
+type ListRelationsInput struct {
+	RelationTypes []string `json:"relation_types,omitempty" jsonschema:"Only list relations of these types"`
+	From          string   `json:"from,omitempty" jsonschema:"Only list relations from this entity"`
+	To            string   `json:"to,omitempty" jsonschema:"Only list relations to this entity"`
+	FromTypes     []string `json:"from_types,omitempty" jsonschema:"Only list relations whose source has one of these entity types"`
+	ToTypes       []string `json:"to_types,omitempty" jsonschema:"Only list relations whose target has one of these entity types"`
+	Limit         int      `json:"limit,omitempty" jsonschema:"Maximum relations per page (default 100, max 1000)"`
+	Cursor        string   `json:"cursor,omitempty" jsonschema:"next_cursor from the previous page"`
+	ReadOptions
+	OutputOptions
+}
+
+type DescribeProjectInput struct {
+	Recent int `json:"recent,omitempty" jsonschema:"How many recently updated entities to list (default 10, max 100)"`
+}
+
+// --- Handlers ---
+
+func (t *KnowledgeTools) ListEntities(_ context.Context, _ *mcp.CallToolRequest, input ListEntitiesInput) (*mcp.CallToolResult, any, error) {
+	ps, errResult := t.requireProject()
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	list, err := ps.ListEntities(storage.ListEntitiesOptions{
+		EntityTypes: input.EntityTypes,
+		NamePrefix:  input.Prefix,
+		Sort:        input.Sort,
+		Limit:       pageLimit(input.Limit),
+		Cursor:      input.Cursor,
+	})
+	if err != nil {
+		return toolError("Failed to list entities: %v", err), nil, nil
+	}
+
+	return toolOutput(list, input.OutputOptions)
+}
+
+func (t *KnowledgeTools) ListRelations(_ context.Context, _ *mcp.CallToolRequest, input ListRelationsInput) (*mcp.CallToolResult, any, error) {
+	ps, errResult := t.requireProject()
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	store, err := input.readStore(ps)
+	if err != nil {
+		return toolError("Failed to list relations: %v", err), nil, nil
+	}
+	list, err := store.ListRelations(storage.ListRelationsOptions{
+		RelationTypes: input.RelationTypes,
+		From:          input.From,
+		To:            input.To,
+		FromTypes:     input.FromTypes,
+		ToTypes:       input.ToTypes,
+		Limit:         pageLimit(input.Limit),
+		Cursor:        input.Cursor,
+	})
+	if err != nil {
+		return toolError("Failed to list relations: %v", err), nil, nil
+	}
+
+	return toolOutput(list, input.OutputOptions)
+}
+
+func (t *KnowledgeTools) DescribeProject(_ context.Context, _ *mcp.CallToolRequest, input DescribeProjectInput) (*mcp.CallToolResult, any, error) {
+	ps, errResult := t.requireProject()
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	id, _, _ := t.Session.GetCurrent()
+	proj, err := t.Meta.GetProjectByID(id)
+	if err != nil {
+		return toolError("Failed to load project: %v", err), nil, nil
+	}
+
+	recent := input.Recent
+	if recent <= 0 {
+		recent = 10
+	}
+	if recent > 100 {
+		recent = 100
+	}
+	desc, err := ps.Describe(recent)
+	if err != nil {
+		return toolError("Failed to describe project: %v", err), nil, nil
+	}
+	desc.Project = *proj
+	if desc.DBSizeBytes, err = t.Meta.ProjectDBSize(proj); err != nil {
+		return toolError("Failed to describe project: %v", err), nil, nil
+	}
+
+	return toolJSON(desc)
+}
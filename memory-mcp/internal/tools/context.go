@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/storage"
+)
+
+// --- Input types ---
+
+type GetContextInput struct {
+	Query     string   `json:"query,omitempty" jsonschema:"What the conversation is about; matched semantically and by keywords"`
+	Focus     []string `json:"focus,omitempty" jsonschema:"Entity names that must lead the context"`
+	MaxTokens int      `json:"max_tokens,omitempty" jsonschema:"Token budget for the result (default 2000; estimated at 4 characters per token)"`
+	ValidAt   string   `json:"valid_at,omitempty" jsonschema:"Build the context from facts valid at this date (2026-02-01), month (2026-02) or RFC 3339 time (default now)"`
+	OutputOptions
+}
+
+// --- Handlers ---
+
+func (t *KnowledgeTools) GetContext(_ context.Context, _ *mcp.CallToolRequest, input GetContextInput) (*mcp.CallToolResult, any, error) {
+	ps, errResult := t.requireProject()
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	if input.Query == "" && len(input.Focus) == 0 {
+		return toolError("A query or focus entities are required"), nil, nil
+	}
+
+	store, err := ps.At(input.ValidAt, false)
+	if err != nil {
+		return toolError("Failed to build context: %v", err), nil, nil
+	}
+	pack, err := store.GetContext(storage.ContextOptions{
+		Query:     input.Query,
+		Focus:     input.Focus,
+		MaxTokens: input.MaxTokens,
+	})
+	if err != nil {
+		return toolError("Failed to build context: %v", err), nil, nil
+	}
+
+	// The budget is estimated on the markdown rendering, so default to it
+	if input.Format == "" {
+		input.Format = FormatMarkdown
+	}
+	return toolOutput(pack, input.OutputOptions)
+}
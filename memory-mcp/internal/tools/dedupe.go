@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// --- Input types ---
+
+type FindDuplicatesInput struct {
+	EntityType string  `json:"entity_type,omitempty" jsonschema:"Only compare entities of this type"`
+	MinScore   float64 `json:"min_score,omitempty" jsonschema:"Minimum combined score between 0 and 1 (default 0.5)"`
+	Limit      int     `json:"limit,omitempty" jsonschema:"Maximum number of pairs to return (default 20)"`
+}
+
+type MergeEntitiesInput struct {
+	Target           string           `json:"target" jsonschema:"Entity that survives the merge"`
+	Sources          []string         `json:"sources" jsonschema:"Exact names (or aliases) of the entities folded into the target, soft-deleted and kept as aliases"`
+	ExpectedVersions map[string]int64 `json:"expected_versions,omitempty" jsonschema:"Expected version per target or source name; nothing is merged (conflict) if a listed entity is at another version"`
+	WriteOptions
+}
+
+// --- Handlers ---
+
+func (t *KnowledgeTools) FindDuplicates(_ context.Context, _ *mcp.CallToolRequest, input FindDuplicatesInput) (*mcp.CallToolResult, any, error) {
+	ps, errResult := t.requireProject()
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	minScore := input.MinScore
+	if minScore <= 0 {
+		minScore = 0.5
+	}
+
+	candidates, err := ps.FindDuplicates(input.EntityType, minScore, input.Limit)
+	if err != nil {
+		return toolError("Failed to find duplicates: %v", err), nil, nil
+	}
+
+	return toolJSON(candidates)
+}
+
+func (t *KnowledgeTools) MergeEntities(_ context.Context, _ *mcp.CallToolRequest, input MergeEntitiesInput) (*mcp.CallToolResult, any, error) {
+	ps, errResult := t.requireProject()
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	if input.Target == "" || len(input.Sources) == 0 {
+		return toolError("Target and at least one source are required"), nil, nil
+	}
+
+	store := input.writeStore(ps)
+	result, err := store.MergeEntities(input.Target, input.Sources, input.ExpectedVersions)
+	if err != nil {
+		return toolError("Failed to merge entities: %v", err), nil, nil
+	}
+	if input.DryRun {
+		return toolJSON(store.Changes())
+	}
+
+	return toolJSON(result)
+}
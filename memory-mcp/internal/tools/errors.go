@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/storage"
+)
+
+// Error codes of error results. The failed-item statuses of batch results
+// use the same strings.
+const (
+	CodeNotFound        = "not_found"
+	CodeConflict        = "conflict"
+	CodeInvalid         = "invalid"
+	CodeArchived        = "archived"
+	CodeNoActiveProject = "no_active_project"
+	CodeInternal        = "internal"
+)
+
+// ToolError is the structured content of an error result: a stable code,
+// the message also sent as text, and details such as the failed item's
+// index or name suggestions for an unknown entity.
+type ToolError struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// toolError returns an error result with a formatted message. The code and
+// details come from the first error among args; a message without one
+// reports invalid arguments.
+func toolError(format string, args ...any) *mcp.CallToolResult {
+	te := ToolError{Code: CodeInvalid, Message: fmt.Sprintf(format, args...)}
+	for _, arg := range args {
+		if err, ok := arg.(error); ok {
+			te.Code = errorCode(err)
+			te.Details = errorDetails(err)
+			break
+		}
+	}
+	return errorResult(te)
+}
+
+// errorResult returns te as an error result, its message as text.
+func errorResult(te ToolError) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: te.Message}},
+		StructuredContent: te,
+		IsError:           true,
+	}
+}
+
+// errorCode classifies err by its storage error kind.
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, storage.ErrArchived):
+		return CodeArchived
+	case errors.Is(err, storage.ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, storage.ErrConflict):
+		return CodeConflict
+	case errors.Is(err, storage.ErrInvalid):
+		return CodeInvalid
+	}
+	return CodeInternal
+}
+
+// errorDetails collects the details carried by the typed errors in err's
+// tree, or nil when there are none.
+func errorDetails(err error) map[string]any {
+	details := make(map[string]any)
+	var notFound []*storage.EntityNotFoundError
+	walkErrors(err, func(err error) {
+		switch e := err.(type) {
+		case *storage.BatchError:
+			details["index"] = e.Index
+			details["op"] = e.Op
+		case *storage.EntityNotFoundError:
+			notFound = append(notFound, e)
+		case *storage.VersionConflictError:
+			details["entity"] = e.Entity
+			details["expected_version"] = e.ExpectedVersion
+			details["current_version"] = e.CurrentVersion
+		case *storage.OntologyViolationError:
+			details["violations"] = e.Violations
+		case *storage.IdempotencyConflictError:
+			details["idempotency_key"] = e.Key
+		case *storage.IdempotencyInProgressError:
+			details["idempotency_key"] = e.Key
+		}
+	})
+	if len(notFound) > 0 {
+		details["not_found"] = notFound
+	}
+	if len(details) == 0 {
+		return nil
+	}
+	return details
+}
+
+// walkErrors calls fn for err and every error it wraps, joined errors
+// included.
+func walkErrors(err error, fn func(error)) {
+	if err == nil {
+		return
+	}
+	fn(err)
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			walkErrors(inner, fn)
+		}
+	case interface{ Unwrap() error }:
+		walkErrors(e.Unwrap(), fn)
+	}
+}
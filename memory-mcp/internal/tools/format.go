@@ -0,0 +1,254 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/storage"
+)
+
+// Response formats accepted by the read tools.
+const (
+	FormatJSON        = "json"
+	FormatCompactJSON = "compact_json"
+	FormatMarkdown    = "markdown"
+)
+
+// metaFields are the fields dropped by omit: ["meta"].
+var metaFields = []string{
+	"id", "entity_id", "from_entity", "to_entity", "db_path", "created_at", "updated_at",
+}
+
+// OutputOptions selects how a read tool renders its result.
+type OutputOptions struct {
+	Format string   `json:"format,omitempty" jsonschema:"Response format: json (default), compact_json or markdown (most compact)"`
+	Omit   []string `json:"omit,omitempty" jsonschema:"JSON fields to drop at every level, e.g. created_at; \"meta\" drops all IDs and timestamps"`
+}
+
+// toolOutput renders v in the requested format. Markdown is supported for
+// entities, graphs, catalogs, projects and context packs; other values fall back to JSON.
+func toolOutput(v any, opts OutputOptions) (*mcp.CallToolResult, any, error) {
+	var text string
+	switch opts.Format {
+	case "", FormatJSON, FormatCompactJSON:
+		var payload any = v
+		if len(opts.Omit) > 0 {
+			projected, err := omitFields(v, opts.Omit)
+			if err != nil {
+				return toolError("Failed to marshal result: %v", err), nil, nil
+			}
+			payload = projected
+		}
+		var data []byte
+		var err error
+		if opts.Format == FormatCompactJSON {
+			data, err = json.Marshal(payload)
+		} else {
+			data, err = json.MarshalIndent(payload, "", "  ")
+		}
+		if err != nil {
+			return toolError("Failed to marshal result: %v", err), nil, nil
+		}
+		text = string(data)
+	case FormatMarkdown:
+		md, ok := renderMarkdown(v)
+		if !ok {
+			return toolJSON(v)
+		}
+		text = md
+	default:
+		return toolError("Unknown format %q (use json, compact_json or markdown)", opts.Format), nil, nil
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: text}},
+	}, nil, nil
+}
+
+// omitFields round-trips v through JSON and drops the named keys from every object.
+func omitFields(v any, omit []string) (any, error) {
+	drop := make(map[string]bool)
+	for _, f := range omit {
+		if f == "meta" {
+			for _, m := range metaFields {
+				drop[m] = true
+			}
+			continue
+		}
+		drop[f] = true
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var tree any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	var prune func(any)
+	prune = func(node any) {
+		switch n := node.(type) {
+		case map[string]any:
+			for k, child := range n {
+				if drop[k] {
+					delete(n, k)
+					continue
+				}
+				prune(child)
+			}
+		case []any:
+			for _, child := range n {
+				prune(child)
+			}
+		}
+	}
+	prune(tree)
+	return tree, nil
+}
+
+// renderMarkdown renders the result types of the read tools and reports
+// whether v was one of them.
+func renderMarkdown(v any) (string, bool) {
+	var b strings.Builder
+	switch r := v.(type) {
+	case []models.Entity:
+		if len(r) == 0 {
+			return "No entities.", true
+		}
+		for i, e := range r {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			writeEntityMarkdown(&b, e, e.Outgoing, e.Incoming)
+		}
+	case *models.KnowledgeGraph:
+		fmt.Fprintf(&b, "%d of %d entities, %d relations\n", len(r.Entities), r.TotalEntities, len(r.Relations))
+		outgoing := make(map[string][]models.Relation)
+		for _, rel := range r.Relations {
+			outgoing[rel.FromEntity] = append(outgoing[rel.FromEntity], rel)
+		}
+		for _, e := range r.Entities {
+			b.WriteString("\n")
+			writeEntityMarkdown(&b, e, outgoing[e.ID], nil)
+		}
+		writeCursorMarkdown(&b, r.NextCursor)
+	case *models.EntityList:
+		fmt.Fprintf(&b, "%d of %d entities\n", len(r.Entities), r.TotalEntities)
+		for _, e := range r.Entities {
+			fmt.Fprintf(&b, "- **%s** (%s, version %d), %d observations, updated %s\n", e.Name, e.EntityType, e.Version, e.ObservationCount, e.UpdatedAt)
+		}
+		writeCursorMarkdown(&b, r.NextCursor)
+	case *models.RelationList:
+		fmt.Fprintf(&b, "%d of %d relations\n", len(r.Relations), r.TotalRelations)
+		for _, rel := range r.Relations {
+			fmt.Fprintf(&b, "- %s → %s → %s%s\n", rel.FromName, rel.RelationType, rel.ToName, validitySuffix(rel.Validity))
+		}
+		writeCursorMarkdown(&b, r.NextCursor)
+	case []models.Project:
+		if len(r) == 0 {
+			return "No projects.", true
+		}
+		for _, p := range r {
+			fmt.Fprintf(&b, "- **%s** (%s)", p.Name, p.Status)
+			if p.Description != "" {
+				fmt.Fprintf(&b, ": %s", p.Description)
+			}
+			b.WriteString("\n")
+		}
+	case *models.ContextPack:
+		b.WriteString(storage.RenderContextMarkdown(r))
+	default:
+		return "", false
+	}
+	return b.String(), true
+}
+
+// writeCursorMarkdown notes that another page follows.
+func writeCursorMarkdown(b *strings.Builder, cursor string) {
+	if cursor != "" {
+		fmt.Fprintf(b, "\nMore results follow; pass cursor %q.\n", cursor)
+	}
+}
+
+// metaSuffix describes observation metadata as " (source: …; tags: …)", or
+// returns "" when there is none.
+func metaSuffix(m models.ObservationMeta) string {
+	var parts []string
+	if m.Source != "" {
+		parts = append(parts, "source: "+m.Source)
+	}
+	if m.Author != "" {
+		parts = append(parts, "author: "+m.Author)
+	}
+	if m.Confidence != nil {
+		parts = append(parts, fmt.Sprintf("confidence: %g", *m.Confidence))
+	}
+	if len(m.Tags) > 0 {
+		parts = append(parts, "tags: "+strings.Join(m.Tags, ", "))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, "; ") + ")"
+}
+
+// validitySuffix describes when a fact holds as " [from …; until …]",
+// noting facts that are historical, or returns "" when it always holds.
+func validitySuffix(v models.Validity) string {
+	var parts []string
+	if v.ValidFrom != "" {
+		parts = append(parts, "from "+v.ValidFrom)
+	}
+	if v.ValidUntil != "" {
+		parts = append(parts, "until "+v.ValidUntil)
+	}
+	if v.Historical {
+		parts = append(parts, "historical")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(parts, "; ") + "]"
+}
+
+// writeEntityMarkdown writes an entity as a heading with its type and
+// version, then its properties and bullet observations followed by
+// "→ relation → target" and "← relation ← source" lines.
+func writeEntityMarkdown(b *strings.Builder, e models.Entity, outgoing, incoming []models.Relation) {
+	fmt.Fprintf(b, "## %s (%s, version %d)\n", e.Name, e.EntityType, e.Version)
+	if len(e.Aliases) > 0 {
+		fmt.Fprintf(b, "Also known as: %s\n", strings.Join(e.Aliases, ", "))
+	}
+	if len(e.Properties) > 0 {
+		keys := make([]string, 0, len(e.Properties))
+		for key := range e.Properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		props := make([]string, len(keys))
+		for i, key := range keys {
+			v := e.Properties[key]
+			switch v.(type) {
+			case map[string]any, []any:
+				data, _ := json.Marshal(v)
+				v = string(data)
+			}
+			props[i] = fmt.Sprintf("%s = %v", key, v)
+		}
+		fmt.Fprintf(b, "Properties: %s\n", strings.Join(props, "; "))
+	}
+	for _, o := range e.Observations {
+		fmt.Fprintf(b, "- %s%s%s\n", o.Content, metaSuffix(o.ObservationMeta), validitySuffix(o.Validity))
+	}
+	for _, r := range outgoing {
+		fmt.Fprintf(b, "→ %s → %s%s\n", r.RelationType, r.ToName, validitySuffix(r.Validity))
+	}
+	for _, r := range incoming {
+		fmt.Fprintf(b, "← %s ← %s%s\n", r.RelationType, r.FromName, validitySuffix(r.Validity))
+	}
+}
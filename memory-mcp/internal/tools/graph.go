@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/graph"
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/storage"
+)
+
+// --- Input types ---
+
+type TraverseInput struct {
+	Start               []string `json:"start" jsonschema:"Entity names to start from"`
+	Direction           string   `json:"direction,omitempty" jsonschema:"Direction to follow relations: outgoing, incoming or both (default both)"`
+	MaxDepth            int      `json:"max_depth,omitempty" jsonschema:"Maximum number of hops (default 2, max 6)"`
+	RelationTypes       []string `json:"relation_types,omitempty" jsonschema:"Only follow these relation types"`
+	EntityTypes         []string `json:"entity_types,omitempty" jsonschema:"Only reach entities of these types"`
+	IncludeObservations bool     `json:"include_observations,omitempty" jsonschema:"Include observations of every reached entity"`
+	ReadOptions
+}
+
+type FindPathsInput struct {
+	From     string `json:"from" jsonschema:"Entity name where paths start"`
+	To       string `json:"to" jsonschema:"Entity name where paths end"`
+	K        int    `json:"k,omitempty" jsonschema:"Number of shortest paths to return (default 1, max 10)"`
+	MaxDepth int    `json:"max_depth,omitempty" jsonschema:"Maximum number of hops per path (default 4, max 6)"`
+	Directed bool   `json:"directed,omitempty" jsonschema:"Only follow relations in their own direction (default false)"`
+}
+
+type GraphStatsInput struct {
+	EntityTypes  []string `json:"entity_types,omitempty" jsonschema:"Only report entities of these types (measures still use the whole graph)"`
+	Top          int      `json:"top,omitempty" jsonschema:"Length of the degree and PageRank rankings (default 10)"`
+	HubMinDegree int      `json:"hub_min_degree,omitempty" jsonschema:"Total degree from which an entity counts as a hub (default 5)"`
+}
+
+type ValidateGraphInput struct {
+	Rules                []string         `json:"rules,omitempty" jsonschema:"Only run these rules (default all): unknown_entity_type, banned_relation_type, relation_type_not_snake_case, duplicate_relation, self_loop, entity_without_observations, observation_too_long, observation_not_atomic, relation_to_deleted_entity, relation_outside_ontology"`
+	MaxObservationLength int              `json:"max_observation_length,omitempty" jsonschema:"Observation length limit in characters (default 300)"`
+	Fix                  bool             `json:"fix,omitempty" jsonschema:"Apply safe auto-fixes (dedupe, drop self-loops and dangling relations, normalize type casing, reverse relations written with the inverse name of an ontology relation)"`
+	ExpectedVersions     map[string]int64 `json:"expected_versions,omitempty" jsonschema:"With fix: expected version per entity name; nothing is fixed (conflict) if a listed entity is at another version"`
+	WriteOptions
+}
+
+// --- Handlers ---
+
+func (t *KnowledgeTools) Traverse(_ context.Context, _ *mcp.CallToolRequest, input TraverseInput) (*mcp.CallToolResult, any, error) {
+	ps, errResult := t.requireProject()
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	if len(input.Start) == 0 {
+		return toolError("At least one start entity is required"), nil, nil
+	}
+
+	store, err := input.readStore(ps)
+	if err != nil {
+		return toolError("Traversal failed: %v", err), nil, nil
+	}
+	sub, err := store.Traverse(input.Start, storage.TraverseOptions{
+		Direction:           input.Direction,
+		MaxDepth:            input.MaxDepth,
+		RelationTypes:       input.RelationTypes,
+		EntityTypes:         input.EntityTypes,
+		IncludeObservations: input.IncludeObservations,
+	})
+	if err != nil {
+		return toolError("Traversal failed: %v", err), nil, nil
+	}
+
+	return toolJSON(sub)
+}
+
+func (t *KnowledgeTools) FindPaths(_ context.Context, _ *mcp.CallToolRequest, input FindPathsInput) (*mcp.CallToolResult, any, error) {
+	ps, errResult := t.requireProject()
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	if input.From == "" || input.To == "" {
+		return toolError("Both from and to are required"), nil, nil
+	}
+
+	k := min(max(input.K, 1), 10)
+	maxDepth := input.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = 4
+	}
+	maxDepth = min(maxDepth, storage.MaxTraverseDepth)
+
+	fromID, err := ps.ResolveEntity(input.From)
+	if err != nil {
+		return toolError("Failed to find paths: from: %v", err), nil, nil
+	}
+	toID, err := ps.ResolveEntity(input.To)
+	if err != nil {
+		return toolError("Failed to find paths: to: %v", err), nil, nil
+	}
+
+	// Paths only need the topology
+	kg, err := ps.ReadTopology()
+	if err != nil {
+		return toolError("Failed to read graph: %v", err), nil, nil
+	}
+
+	paths := graph.New(kg).KShortestPaths(fromID, toID, k, maxDepth, input.Directed)
+	return toolJSON(paths)
+}
+
+func (t *KnowledgeTools) GraphStats(_ context.Context, _ *mcp.CallToolRequest, input GraphStatsInput) (*mcp.CallToolResult, any, error) {
+	ps, errResult := t.requireProject()
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	// Stats only need the topology
+	kg, err := ps.ReadTopology()
+	if err != nil {
+		return toolError("Failed to read graph: %v", err), nil, nil
+	}
+
+	stats := graph.New(kg).Stats(graph.StatsOptions{
+		EntityTypes:  input.EntityTypes,
+		Top:          input.Top,
+		HubMinDegree: input.HubMinDegree,
+	})
+	return toolJSON(stats)
+}
+
+func (t *KnowledgeTools) ValidateGraph(_ context.Context, _ *mcp.CallToolRequest, input ValidateGraphInput) (*mcp.CallToolResult, any, error) {
+	ps, errResult := t.requireProject()
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	store := input.writeStore(ps)
+	report, err := store.ValidateGraph(storage.ValidateOptions{
+		Rules:                input.Rules,
+		MaxObservationLength: input.MaxObservationLength,
+		Fix:                  input.Fix,
+		ExpectedVersions:     input.ExpectedVersions,
+	})
+	if err != nil {
+		return toolError("Failed to validate graph: %v", err), nil, nil
+	}
+	if input.DryRun && input.Fix {
+		return toolJSON(store.Changes())
+	}
+
+	return toolJSON(report)
+}
@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// writeInput is implemented by the inputs that embed WriteOptions.
+type writeInput interface {
+	writeOptions() WriteOptions
+}
+
+// Idempotent wraps the handler of a write tool so that a call repeating the
+// idempotency_key and arguments of an earlier one returns the stored result
+// instead of writing again. The key is claimed before the write runs, so a
+// concurrent retry fails with a conflict instead of writing twice; a claim
+// abandoned by a crash lapses after a minute. Only
+// successful results are stored, so a failed call can be retried with the
+// same key; dry runs ignore the key.
+func Idempotent[In writeInput](t *KnowledgeTools, tool string, handler mcp.ToolHandlerFor[In, any]) mcp.ToolHandlerFor[In, any] {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input In) (*mcp.CallToolResult, any, error) {
+		opts := input.writeOptions()
+		if opts.IdempotencyKey == "" || opts.DryRun {
+			return handler(ctx, req, input)
+		}
+		ps, errResult := t.requireProject()
+		if errResult != nil {
+			return errResult, nil, nil
+		}
+
+		args, err := json.Marshal(input)
+		if err != nil {
+			return toolError("Failed to hash request: %v", err), nil, nil
+		}
+		sum := sha256.Sum256(append([]byte(tool+"\x00"), args...))
+		hash := hex.EncodeToString(sum[:])
+
+		stored, claimed, err := ps.ClaimIdempotencyKey(opts.IdempotencyKey, hash)
+		if err != nil {
+			return toolError("%v", err), nil, nil
+		}
+		if !claimed {
+			var result mcp.CallToolResult
+			if err := json.Unmarshal([]byte(stored), &result); err != nil {
+				return toolError("Failed to read stored result: %v", err), nil, nil
+			}
+			return &result, nil, nil
+		}
+
+		result, out, err := handler(ctx, req, input)
+		if err != nil || result == nil || result.IsError {
+			if relErr := ps.ReleaseIdempotencyKey(opts.IdempotencyKey); relErr != nil && err == nil {
+				return toolError("%v", relErr), nil, nil
+			}
+			return result, out, err
+		}
+		// Store the result as the client receives it, structured content
+		// included
+		if result.StructuredContent == nil && out != nil {
+			result.StructuredContent = out
+		}
+		data, err := json.Marshal(result)
+		if err == nil {
+			err = ps.CompleteIdempotencyKey(opts.IdempotencyKey, string(data))
+		}
+		if err != nil {
+			return toolError("Write applied, but %v", err), nil, nil
+		}
+		return result, nil, nil
+	}
+}
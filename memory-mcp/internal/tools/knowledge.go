@@ -2,10 +2,11 @@ package tools
 
 import (
 	"context"
-	"fmt"
+	"errors"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
 	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/session"
 	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/storage"
 )
@@ -20,56 +21,131 @@ type KnowledgeTools struct {
 
 type CreateEntitiesInput struct {
 	Entities []EntityInput `json:"entities" jsonschema:"Array of entities to create"`
+	BatchOptions
+	WriteOptions
 }
 
 type EntityInput struct {
 	Name         string   `json:"name" jsonschema:"Entity name"`
 	EntityType   string   `json:"entity_type" jsonschema:"Entity type (e.g., person, technology, concept)"`
 	Observations []string `json:"observations,omitempty" jsonschema:"Initial observations about the entity"`
+	ObservationMetaInput
+	ValidityInput
+}
+
+// ObservationMetaInput is the provenance recorded on every observation an
+// item creates.
+type ObservationMetaInput struct {
+	Source     string   `json:"source,omitempty" jsonschema:"Where the observations come from: a URL or document reference"`
+	Author     string   `json:"author,omitempty" jsonschema:"Who recorded the observations (user or client ID)"`
+	Confidence *float64 `json:"confidence,omitempty" jsonschema:"How certain the observations are, from 0 to 1"`
+	Tags       []string `json:"tags,omitempty" jsonschema:"Free-form tags (e.g. meeting, inferred)"`
+}
+
+func (m ObservationMetaInput) meta() models.ObservationMeta {
+	return models.ObservationMeta{Source: m.Source, Author: m.Author, Confidence: m.Confidence, Tags: m.Tags}
+}
+
+// ValidityInput is the period in which the observations or relation an item
+// creates hold, as opposed to when they were recorded.
+type ValidityInput struct {
+	ValidFrom  string `json:"valid_from,omitempty" jsonschema:"When the fact became true: a date (2026-02-01), month (2026-02) or RFC 3339 time"`
+	ValidUntil string `json:"valid_until,omitempty" jsonschema:"When the fact stopped being true (exclusive); same formats as valid_from"`
+}
+
+func (v ValidityInput) validity() models.Validity {
+	return models.Validity{ValidFrom: v.ValidFrom, ValidUntil: v.ValidUntil}
 }
 
 type AddObservationsInput struct {
 	Observations []ObservationInput `json:"observations" jsonschema:"Array of observations to add"`
+	BatchOptions
+	WriteOptions
 }
 
 type ObservationInput struct {
-	EntityName string   `json:"entity_name" jsonschema:"Name of the entity"`
-	Contents   []string `json:"contents" jsonschema:"Observation texts to add"`
+	EntityName      string   `json:"entity_name" jsonschema:"Name of the entity"`
+	Contents        []string `json:"contents" jsonschema:"Observation texts to add"`
+	ExpectedVersion int64    `json:"expected_version,omitempty" jsonschema:"Fail with a conflict unless the entity is at this version (from open_nodes, read_graph or a previous write)"`
+	ObservationMetaInput
+	ValidityInput
 }
 
 type CreateRelationsInput struct {
-	Relations []RelationInput `json:"relations" jsonschema:"Array of relations to create"`
+	Relations []NewRelationInput `json:"relations" jsonschema:"Array of relations to create"`
+	BatchOptions
+	WriteOptions
 }
 
 type RelationInput struct {
-	From         string `json:"from" jsonschema:"Source entity name"`
-	To           string `json:"to" jsonschema:"Target entity name"`
-	RelationType string `json:"relation_type" jsonschema:"Relation type in active voice (e.g., uses, depends_on, manages)"`
+	From            string `json:"from" jsonschema:"Source entity name"`
+	To              string `json:"to" jsonschema:"Target entity name"`
+	RelationType    string `json:"relation_type" jsonschema:"Relation type in active voice (e.g., uses, depends_on, manages)"`
+	ExpectedVersion int64  `json:"expected_version,omitempty" jsonschema:"Fail with a conflict unless the source entity is at this version (from open_nodes, read_graph or a previous write)"`
+}
+
+// NewRelationInput is a relation to create, with the period it holds in.
+type NewRelationInput struct {
+	RelationInput
+	ValidityInput
 }
 
 type SearchNodesInput struct {
-	Query string `json:"query" jsonschema:"Search query (supports FTS5 syntax: AND, OR, NOT, prefix*)"`
+	Query         string                `json:"query,omitempty" jsonschema:"Search query (supports FTS5 syntax: AND, OR, NOT, prefix*); optional when filtering on observation metadata or properties"`
+	Source        string                `json:"source,omitempty" jsonschema:"Only entities with an observation from this source"`
+	Author        string                `json:"author,omitempty" jsonschema:"Only entities with an observation by this author"`
+	MinConfidence float64               `json:"min_confidence,omitempty" jsonschema:"Only entities with an observation of at least this confidence"`
+	Tags          []string              `json:"tags,omitempty" jsonschema:"Only entities with an observation carrying all these tags"`
+	Properties    []PropertyFilterInput `json:"properties,omitempty" jsonschema:"Only entities whose properties pass all these conditions, e.g. {key: stack, value: Java}"`
+	ReadOptions
+	OutputOptions
+}
+
+type SemanticSearchInput struct {
+	Query  string `json:"query" jsonschema:"Natural-language query"`
+	Limit  int    `json:"limit,omitempty" jsonschema:"Maximum number of entities to return (default 10)"`
+	Hybrid bool   `json:"hybrid,omitempty" jsonschema:"Fuse semantic ranking with FTS5 keyword ranking (reciprocal rank fusion)"`
+	ReadOptions
 }
 
 type OpenNodesInput struct {
-	Names []string `json:"names" jsonschema:"Exact entity names to retrieve"`
+	Names []string `json:"names" jsonschema:"Entity names to retrieve (case/accent-insensitive; close typos are suggested)"`
+	ReadOptions
+	OutputOptions
+}
+
+type ReadGraphInput struct {
+	Limit       int      `json:"limit,omitempty" jsonschema:"Maximum entities per page (default 100, max 1000)"`
+	Cursor      string   `json:"cursor,omitempty" jsonschema:"next_cursor from the previous page"`
+	EntityTypes []string `json:"entity_types,omitempty" jsonschema:"Only return entities of these types (and relations between them)"`
+	Include     []string `json:"include,omitempty" jsonschema:"What to load besides entities: observations, relations (default both; [] for entities only)"`
+	ReadOptions
+	OutputOptions
 }
 
 type DeleteEntitiesInput struct {
-	Names []string `json:"names" jsonschema:"Entity names to delete"`
+	Names            []string         `json:"names" jsonschema:"Exact entity names (or aliases) to delete; other names are reported not found with suggestions"`
+	ExpectedVersions map[string]int64 `json:"expected_versions,omitempty" jsonschema:"Expected version per entity name; a listed entity at another version is not deleted (conflict)"`
+	BatchOptions
+	WriteOptions
 }
 
 type DeleteObservationsInput struct {
 	Deletions []DeleteObservationItem `json:"deletions" jsonschema:"Array of observations to delete"`
+	BatchOptions
+	WriteOptions
 }
 
 type DeleteObservationItem struct {
-	EntityName   string   `json:"entity_name" jsonschema:"Name of the entity"`
-	Observations []string `json:"observations" jsonschema:"Observation content strings to match and delete"`
+	EntityName      string   `json:"entity_name" jsonschema:"Name of the entity"`
+	Observations    []string `json:"observations" jsonschema:"Observation content strings to match and delete"`
+	ExpectedVersion int64    `json:"expected_version,omitempty" jsonschema:"Fail with a conflict unless the entity is at this version (from open_nodes, read_graph or a previous write)"`
 }
 
 type DeleteRelationsInput struct {
 	Relations []RelationInput `json:"relations" jsonschema:"Array of relations to delete"`
+	BatchOptions
+	WriteOptions
 }
 
 // --- Handlers ---
@@ -77,7 +153,7 @@ type DeleteRelationsInput struct {
 func (t *KnowledgeTools) requireProject() (*storage.ProjectStore, *mcp.CallToolResult) {
 	ps := t.Session.ProjectStore()
 	if ps == nil {
-		return nil, toolError("No active project. Use switch_project to select one.")
+		return nil, errorResult(ToolError{Code: CodeNoActiveProject, Message: "No active project. Use switch_project to select one."})
 	}
 	return ps, nil
 }
@@ -88,23 +164,11 @@ func (t *KnowledgeTools) CreateEntities(_ context.Context, _ *mcp.CallToolReques
 		return errResult, nil, nil
 	}
 
-	entities := make([]struct {
-		Name         string
-		EntityType   string
-		Observations []string
-	}, len(input.Entities))
+	ops := make([]storage.BatchOp, len(input.Entities))
 	for i, e := range input.Entities {
-		entities[i].Name = e.Name
-		entities[i].EntityType = e.EntityType
-		entities[i].Observations = e.Observations
+		ops[i] = storage.BatchOp{Op: storage.OpCreateEntity, Entity: e.Name, EntityType: e.EntityType, Observations: e.Observations, Meta: e.meta(), Validity: e.validity()}
 	}
-
-	created, err := ps.CreateEntities(entities)
-	if err != nil {
-		return toolError("Failed to create entities: %v", err), nil, nil
-	}
-
-	return toolJSON(created)
+	return applyItems(ps, ops, input.BatchOptions, input.WriteOptions, "create entities")
 }
 
 func (t *KnowledgeTools) AddObservations(_ context.Context, _ *mcp.CallToolRequest, input AddObservationsInput) (*mcp.CallToolResult, any, error) {
@@ -113,16 +177,11 @@ func (t *KnowledgeTools) AddObservations(_ context.Context, _ *mcp.CallToolReque
 		return errResult, nil, nil
 	}
 
-	var allCreated []any
-	for _, obs := range input.Observations {
-		created, err := ps.AddObservations(obs.EntityName, obs.Contents)
-		if err != nil {
-			return toolError("Failed to add observations for %q: %v", obs.EntityName, err), nil, nil
-		}
-		allCreated = append(allCreated, created)
+	ops := make([]storage.BatchOp, len(input.Observations))
+	for i, obs := range input.Observations {
+		ops[i] = storage.BatchOp{Op: storage.OpAddObservation, Entity: obs.EntityName, Observations: obs.Contents, Meta: obs.meta(), Validity: obs.validity(), ExpectedVersion: obs.ExpectedVersion}
 	}
-
-	return toolJSON(allCreated)
+	return applyItems(ps, ops, input.BatchOptions, input.WriteOptions, "add observations")
 }
 
 func (t *KnowledgeTools) CreateRelations(_ context.Context, _ *mcp.CallToolRequest, input CreateRelationsInput) (*mcp.CallToolResult, any, error) {
@@ -131,37 +190,59 @@ func (t *KnowledgeTools) CreateRelations(_ context.Context, _ *mcp.CallToolReque
 		return errResult, nil, nil
 	}
 
-	relations := make([]struct {
-		From         string
-		To           string
-		RelationType string
-	}, len(input.Relations))
+	ops := make([]storage.BatchOp, len(input.Relations))
 	for i, r := range input.Relations {
-		relations[i].From = r.From
-		relations[i].To = r.To
-		relations[i].RelationType = r.RelationType
+		ops[i] = storage.BatchOp{Op: storage.OpCreateRelation, From: r.From, To: r.To, RelationType: r.RelationType, Validity: r.validity(), ExpectedVersion: r.ExpectedVersion}
 	}
+	return applyItems(ps, ops, input.BatchOptions, input.WriteOptions, "create relations")
+}
 
-	created, err := ps.CreateRelations(relations)
+func (t *KnowledgeTools) SearchNodes(_ context.Context, _ *mcp.CallToolRequest, input SearchNodesInput) (*mcp.CallToolResult, any, error) {
+	ps, errResult := t.requireProject()
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	store, err := input.readStore(ps)
+	if err != nil {
+		return toolError("Search failed: %v", err), nil, nil
+	}
+	properties := make([]storage.PropertyFilter, len(input.Properties))
+	for i, f := range input.Properties {
+		properties[i] = storage.PropertyFilter{Key: f.Key, Op: f.Op, Value: f.Value}
+	}
+	entities, err := store.SearchWithProperties(input.Query, storage.ObservationFilter{
+		Source:        input.Source,
+		Author:        input.Author,
+		MinConfidence: input.MinConfidence,
+		Tags:          input.Tags,
+	}, properties)
 	if err != nil {
-		return toolError("Failed to create relations: %v", err), nil, nil
+		return toolError("Search failed: %v", err), nil, nil
 	}
 
-	return toolJSON(created)
+	return toolOutput(entities, input.OutputOptions)
 }
 
-func (t *KnowledgeTools) SearchNodes(_ context.Context, _ *mcp.CallToolRequest, input SearchNodesInput) (*mcp.CallToolResult, any, error) {
+func (t *KnowledgeTools) SemanticSearch(_ context.Context, _ *mcp.CallToolRequest, input SemanticSearchInput) (*mcp.CallToolResult, any, error) {
 	ps, errResult := t.requireProject()
 	if errResult != nil {
 		return errResult, nil, nil
 	}
+	if input.Query == "" {
+		return toolError("Query is required"), nil, nil
+	}
 
-	entities, err := ps.Search(input.Query)
+	store, err := input.readStore(ps)
 	if err != nil {
-		return toolError("Search failed: %v", err), nil, nil
+		return toolError("Semantic search failed: %v", err), nil, nil
+	}
+	results, err := store.SemanticSearch(input.Query, input.Limit, input.Hybrid)
+	if err != nil {
+		return toolError("Semantic search failed: %v", err), nil, nil
 	}
 
-	return toolJSON(entities)
+	return toolJSON(results)
 }
 
 func (t *KnowledgeTools) OpenNodes(_ context.Context, _ *mcp.CallToolRequest, input OpenNodesInput) (*mcp.CallToolResult, any, error) {
@@ -170,26 +251,85 @@ func (t *KnowledgeTools) OpenNodes(_ context.Context, _ *mcp.CallToolRequest, in
 		return errResult, nil, nil
 	}
 
-	entities, err := ps.GetEntities(input.Names)
+	store, err := input.readStore(ps)
+	if err != nil {
+		return toolError("Failed to open nodes: %v", err), nil, nil
+	}
+	entities, missing, err := store.LookupEntities(input.Names)
 	if err != nil {
 		return toolError("Failed to open nodes: %v", err), nil, nil
 	}
+	if len(entities) == 0 && len(missing) > 0 {
+		return toolError("Failed to open nodes: %v", errors.Join(notFoundErrors(missing)...)), nil, nil
+	}
 
-	return toolJSON(entities)
+	result, _, _ := toolOutput(entities, input.OutputOptions)
+	if len(missing) > 0 && !result.IsError {
+		// Keep the payload first; report unresolved names alongside it
+		result.Content = append(result.Content, &mcp.TextContent{
+			Text: errors.Join(notFoundErrors(missing)...).Error(),
+		})
+	}
+	return result, nil, nil
 }
 
-func (t *KnowledgeTools) ReadGraph(_ context.Context, _ *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+// Page limits for read_graph and the catalog tools. maxReadGraphBytes keeps
+// a single read_graph result well below what clients can comfortably hold in
+// context.
+const (
+	defaultPageLimit  = 100
+	maxPageLimit      = 1000
+	maxReadGraphBytes = 256 * 1024
+)
+
+// pageLimit applies the default and maximum page size to a requested limit.
+func pageLimit(limit int) int {
+	if limit <= 0 {
+		return defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		return maxPageLimit
+	}
+	return limit
+}
+
+func (t *KnowledgeTools) ReadGraph(_ context.Context, _ *mcp.CallToolRequest, input ReadGraphInput) (*mcp.CallToolResult, any, error) {
 	ps, errResult := t.requireProject()
 	if errResult != nil {
 		return errResult, nil, nil
 	}
 
-	graph, err := ps.ReadGraph()
+	opts := storage.ReadGraphOptions{
+		Limit:       pageLimit(input.Limit),
+		Cursor:      input.Cursor,
+		EntityTypes: input.EntityTypes,
+		MaxBytes:    maxReadGraphBytes,
+		Indent:      input.Format == "" || input.Format == FormatJSON,
+	}
+	if input.Include == nil {
+		input.Include = []string{"observations", "relations"}
+	}
+	for _, inc := range input.Include {
+		switch inc {
+		case "observations":
+			opts.IncludeObservations = true
+		case "relations":
+			opts.IncludeRelations = true
+		default:
+			return toolError("Unknown include %q (use observations, relations)", inc), nil, nil
+		}
+	}
+
+	store, err := input.readStore(ps)
+	if err != nil {
+		return toolError("Failed to read graph: %v", err), nil, nil
+	}
+	graph, err := store.ReadGraphPage(opts)
 	if err != nil {
 		return toolError("Failed to read graph: %v", err), nil, nil
 	}
 
-	return toolJSON(graph)
+	return toolOutput(graph, input.OutputOptions)
 }
 
 func (t *KnowledgeTools) DeleteEntities(_ context.Context, _ *mcp.CallToolRequest, input DeleteEntitiesInput) (*mcp.CallToolResult, any, error) {
@@ -198,12 +338,11 @@ func (t *KnowledgeTools) DeleteEntities(_ context.Context, _ *mcp.CallToolReques
 		return errResult, nil, nil
 	}
 
-	count, err := ps.DeleteEntities(input.Names)
-	if err != nil {
-		return toolError("Failed to delete entities: %v", err), nil, nil
+	ops := make([]storage.BatchOp, len(input.Names))
+	for i, name := range input.Names {
+		ops[i] = storage.BatchOp{Op: storage.OpDeleteEntity, Entity: name, ExpectedVersion: input.ExpectedVersions[name]}
 	}
-
-	return toolText(fmt.Sprintf("Deleted %d entities.", count)), nil, nil
+	return applyItems(ps, ops, input.BatchOptions, input.WriteOptions, "delete entities")
 }
 
 func (t *KnowledgeTools) DeleteObservations(_ context.Context, _ *mcp.CallToolRequest, input DeleteObservationsInput) (*mcp.CallToolResult, any, error) {
@@ -212,16 +351,19 @@ func (t *KnowledgeTools) DeleteObservations(_ context.Context, _ *mcp.CallToolRe
 		return errResult, nil, nil
 	}
 
-	var total int64
+	// One item per content, so each unmatched content is reported. The
+	// version is checked before the first deletion, which bumps it.
+	var ops []storage.BatchOp
 	for _, d := range input.Deletions {
-		count, err := ps.DeleteObservations(d.EntityName, d.Observations)
-		if err != nil {
-			return toolError("Failed to delete observations for %q: %v", d.EntityName, err), nil, nil
+		for i, content := range d.Observations {
+			op := storage.BatchOp{Op: storage.OpDeleteObservation, Entity: d.EntityName, Observations: []string{content}}
+			if i == 0 {
+				op.ExpectedVersion = d.ExpectedVersion
+			}
+			ops = append(ops, op)
 		}
-		total += count
 	}
-
-	return toolText(fmt.Sprintf("Deleted %d observations.", total)), nil, nil
+	return applyItems(ps, ops, input.BatchOptions, input.WriteOptions, "delete observations")
 }
 
 func (t *KnowledgeTools) DeleteRelations(_ context.Context, _ *mcp.CallToolRequest, input DeleteRelationsInput) (*mcp.CallToolResult, any, error) {
@@ -230,21 +372,18 @@ func (t *KnowledgeTools) DeleteRelations(_ context.Context, _ *mcp.CallToolReque
 		return errResult, nil, nil
 	}
 
-	relations := make([]struct {
-		From         string
-		To           string
-		RelationType string
-	}, len(input.Relations))
+	ops := make([]storage.BatchOp, len(input.Relations))
 	for i, r := range input.Relations {
-		relations[i].From = r.From
-		relations[i].To = r.To
-		relations[i].RelationType = r.RelationType
+		ops[i] = storage.BatchOp{Op: storage.OpDeleteRelation, From: r.From, To: r.To, RelationType: r.RelationType, ExpectedVersion: r.ExpectedVersion}
 	}
+	return applyItems(ps, ops, input.BatchOptions, input.WriteOptions, "delete relations")
+}
 
-	count, err := ps.DeleteRelations(relations)
-	if err != nil {
-		return toolError("Failed to delete relations: %v", err), nil, nil
+// notFoundErrors converts unresolved-name errors for errors.Join.
+func notFoundErrors(missing []*storage.EntityNotFoundError) []error {
+	errs := make([]error, len(missing))
+	for i, nf := range missing {
+		errs[i] = nf
 	}
-
-	return toolText(fmt.Sprintf("Deleted %d relations.", count)), nil, nil
+	return errs
 }
@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"context"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/models"
+)
+
+// --- Input types ---
+
+type SetOntologyInput struct {
+	Mode          string              `json:"mode,omitempty" jsonschema:"Enforcement on writes: off, lenient (warn, default) or strict (reject)"`
+	EntityTypes   []EntityTypeInput   `json:"entity_types,omitempty" jsonschema:"Allowed entity types; empty allows any type"`
+	RelationTypes []RelationTypeInput `json:"relation_types,omitempty" jsonschema:"Allowed relation types; empty allows any type"`
+	WriteOptions
+}
+
+type EntityTypeInput struct {
+	Name        string `json:"name" jsonschema:"Entity type name (e.g., technology)"`
+	Description string `json:"description,omitempty" jsonschema:"What entities of this type represent"`
+}
+
+type RelationTypeInput struct {
+	Name        string   `json:"name" jsonschema:"Relation type in active voice (e.g., uses)"`
+	Description string   `json:"description,omitempty" jsonschema:"What the relation means"`
+	SourceTypes []string `json:"source_types,omitempty" jsonschema:"Entity types allowed as source; empty allows any"`
+	TargetTypes []string `json:"target_types,omitempty" jsonschema:"Entity types allowed as target; empty allows any"`
+	Inverse     string   `json:"inverse,omitempty" jsonschema:"Name of the reverse reading (e.g., used_by); writes using it are reported"`
+}
+
+// --- Handlers ---
+
+func (t *KnowledgeTools) GetOntology(_ context.Context, _ *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+	ps, errResult := t.requireProject()
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	ontology, err := ps.GetOntology()
+	if err != nil {
+		return toolError("Failed to get ontology: %v", err), nil, nil
+	}
+
+	return toolJSON(ontology)
+}
+
+func (t *KnowledgeTools) SetOntology(_ context.Context, _ *mcp.CallToolRequest, input SetOntologyInput) (*mcp.CallToolResult, any, error) {
+	ps, errResult := t.requireProject()
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	ontology := models.Ontology{Mode: input.Mode}
+	for _, et := range input.EntityTypes {
+		ontology.EntityTypes = append(ontology.EntityTypes, models.EntityTypeDef{
+			Name:        et.Name,
+			Description: et.Description,
+		})
+	}
+	for _, rt := range input.RelationTypes {
+		ontology.RelationTypes = append(ontology.RelationTypes, models.RelationTypeDef{
+			Name:        rt.Name,
+			Description: rt.Description,
+			SourceTypes: rt.SourceTypes,
+			TargetTypes: rt.TargetTypes,
+			Inverse:     rt.Inverse,
+		})
+	}
+
+	saved, err := input.writeStore(ps).SetOntology(ontology)
+	if err != nil {
+		return toolError("Failed to set ontology: %v", err), nil, nil
+	}
+
+	result, _, _ := toolJSON(saved)
+	if input.DryRun {
+		result.Content = append(result.Content, &mcp.TextContent{Text: "Dry run: the ontology was not saved."})
+	}
+	return result, nil, nil
+}
+
+// appendOntologyWarnings adds lenient-mode ontology violations to a write
+// result as a separate text block after the JSON payload.
+func appendOntologyWarnings(result *mcp.CallToolResult, warnings []string) *mcp.CallToolResult {
+	if len(warnings) > 0 && !result.IsError {
+		result.Content = append(result.Content, &mcp.TextContent{
+			Text: "Ontology warnings:\n- " + strings.Join(warnings, "\n- "),
+		})
+	}
+	return result
+}
@@ -22,10 +22,11 @@ type ProjectTools struct {
 
 type ListProjectsInput struct {
 	Status string `json:"status" jsonschema:"Filter projects by status: active, archived, or all"`
+	OutputOptions
 }
 
 type CreateProjectInput struct {
-	Name        string `json:"name" jsonschema:"Unique project name (slug-friendly)"`
+	Name        string `json:"name" jsonschema:"Unique project name: lowercase letters and digits separated by -, _ or . (e.g. client-acme)"`
 	Description string `json:"description,omitempty" jsonschema:"Optional project description"`
 }
 
@@ -61,7 +62,7 @@ func (t *ProjectTools) ListProjects(_ context.Context, _ *mcp.CallToolRequest, i
 		projects = []models.Project{}
 	}
 
-	return toolJSON(projects)
+	return toolOutput(projects, input.OutputOptions)
 }
 
 func (t *ProjectTools) CreateProject(_ context.Context, _ *mcp.CallToolRequest, input CreateProjectInput) (*mcp.CallToolResult, any, error) {
@@ -169,13 +170,6 @@ func toolText(text string) *mcp.CallToolResult {
 	}
 }
 
-func toolError(format string, args ...any) *mcp.CallToolResult {
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf(format, args...)}},
-		IsError: true,
-	}
-}
-
 func toolJSON(v any) (*mcp.CallToolResult, any, error) {
 	data, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/storage"
+)
+
+// --- Input types ---
+
+type SetPropertiesInput struct {
+	Properties []PropertyInput `json:"properties" jsonschema:"Properties to set, one per entity and key"`
+	BatchOptions
+	WriteOptions
+}
+
+type PropertyInput struct {
+	EntityName      string `json:"entity_name" jsonschema:"Name of the entity"`
+	Key             string `json:"key" jsonschema:"Property key (e.g. stack, sector, started_on)"`
+	Value           any    `json:"value" jsonschema:"Property value; null removes the property"`
+	Type            string `json:"type,omitempty" jsonschema:"string, number, date, bool or json (default inferred from the value); dates take 2026-02-01, 2026-02 or RFC 3339 times"`
+	ExpectedVersion int64  `json:"expected_version,omitempty" jsonschema:"Fail with a conflict unless the entity is at this version (from open_nodes, read_graph or a previous write)"`
+}
+
+type GetPropertiesInput struct {
+	Names []string `json:"names" jsonschema:"Entity names"`
+	Keys  []string `json:"keys,omitempty" jsonschema:"Only return these keys (default all)"`
+}
+
+// PropertyFilterInput is a search_nodes condition on a property.
+type PropertyFilterInput struct {
+	Key   string `json:"key" jsonschema:"Property key"`
+	Op    string `json:"op,omitempty" jsonschema:"Comparison: =, <, <=, >, >= or contains (default =); = ignores case on strings"`
+	Value any    `json:"value,omitempty" jsonschema:"Value to compare with; omit to match any entity that has the property"`
+}
+
+type ExtractPropertiesInput struct {
+	EntityNames []string `json:"entity_names,omitempty" jsonschema:"Only look at these entities (default all)"`
+	Keys        []string `json:"keys,omitempty" jsonschema:"Only convert these keys (as listed by a call without apply)"`
+	Apply       bool     `json:"apply,omitempty" jsonschema:"Convert the candidates: set each property and delete its observation. Without it the candidates are only listed"`
+	BatchOptions
+	WriteOptions
+}
+
+// --- Handlers ---
+
+func (t *KnowledgeTools) SetProperties(_ context.Context, _ *mcp.CallToolRequest, input SetPropertiesInput) (*mcp.CallToolResult, any, error) {
+	ps, errResult := t.requireProject()
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	ops := make([]storage.BatchOp, len(input.Properties))
+	for i, p := range input.Properties {
+		ops[i] = storage.BatchOp{Op: storage.OpSetProperty, Entity: p.EntityName, Key: p.Key, Value: p.Value, PropertyType: p.Type, ExpectedVersion: p.ExpectedVersion}
+	}
+	return applyItems(ps, ops, input.BatchOptions, input.WriteOptions, "set properties")
+}
+
+func (t *KnowledgeTools) GetProperties(_ context.Context, _ *mcp.CallToolRequest, input GetPropertiesInput) (*mcp.CallToolResult, any, error) {
+	ps, errResult := t.requireProject()
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+	if len(input.Names) == 0 {
+		return toolError("At least one entity name is required"), nil, nil
+	}
+
+	props, err := ps.GetProperties(input.Names, input.Keys)
+	if err != nil {
+		return toolError("Failed to get properties: %v", err), nil, nil
+	}
+	return toolJSON(props)
+}
+
+func (t *KnowledgeTools) ExtractProperties(_ context.Context, _ *mcp.CallToolRequest, input ExtractPropertiesInput) (*mcp.CallToolResult, any, error) {
+	ps, errResult := t.requireProject()
+	if errResult != nil {
+		return errResult, nil, nil
+	}
+
+	candidates, err := ps.PropertyCandidates(input.EntityNames)
+	if err != nil {
+		return toolError("Failed to find property candidates: %v", err), nil, nil
+	}
+	if len(input.Keys) > 0 {
+		wanted := make(map[string]bool, len(input.Keys))
+		for _, key := range input.Keys {
+			wanted[key] = true
+		}
+		kept := candidates[:0]
+		for _, c := range candidates {
+			if wanted[c.Key] {
+				kept = append(kept, c)
+			}
+		}
+		candidates = kept
+	}
+	if !input.Apply {
+		return toolJSON(candidates)
+	}
+	if len(candidates) == 0 {
+		return toolError("No observation to convert"), nil, nil
+	}
+
+	ops := make([]storage.BatchOp, 0, 2*len(candidates))
+	for _, c := range candidates {
+		ops = append(ops,
+			storage.BatchOp{Op: storage.OpSetProperty, Entity: c.Entity, Key: c.Key, Value: c.Value, PropertyType: c.Type},
+			storage.BatchOp{Op: storage.OpDeleteObservation, Entity: c.Entity, Observations: []string{c.Observation}},
+		)
+	}
+	return applyItems(ps, ops, input.BatchOptions, input.WriteOptions, "extract properties")
+}
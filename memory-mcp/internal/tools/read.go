@@ -0,0 +1,19 @@
+package tools
+
+import (
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/storage"
+)
+
+// ReadOptions are accepted by the tools that return observations and
+// relations. Embedding it in an input struct adds the valid_at and
+// include_historical parameters.
+type ReadOptions struct {
+	ValidAt           string `json:"valid_at,omitempty" jsonschema:"Show facts as of this date (2026-02-01), month (2026-02) or RFC 3339 time (default now); facts not valid then are hidden"`
+	IncludeHistorical bool   `json:"include_historical,omitempty" jsonschema:"Also return facts not valid at valid_at, marked historical"`
+}
+
+// readStore returns the store a read runs against: a view of ps as of
+// ValidAt.
+func (o ReadOptions) readStore(ps *storage.ProjectStore) (*storage.ProjectStore, error) {
+	return ps.At(o.ValidAt, o.IncludeHistorical)
+}
@@ -0,0 +1,25 @@
+package tools
+
+import (
+	"github.com/wagnerlima/memory-cloud/memory-mcp/internal/storage"
+)
+
+// WriteOptions are accepted by every tool that changes the graph. Embedding
+// it in an input struct adds the dry_run and idempotency_key parameters.
+type WriteOptions struct {
+	DryRun         bool   `json:"dry_run,omitempty" jsonschema:"Report which entities, observations and relations would be created, changed or soft-deleted (cascades included) without applying anything"`
+	IdempotencyKey string `json:"idempotency_key,omitempty" jsonschema:"Unique key for this write; retrying with the same key and arguments returns the first result instead of writing again"`
+}
+
+func (o WriteOptions) writeOptions() WriteOptions {
+	return o
+}
+
+// writeStore returns the store a write runs against: ps itself, or a
+// dry-run view of it whose Changes are returned instead of the usual result.
+func (o WriteOptions) writeStore(ps *storage.ProjectStore) *storage.ProjectStore {
+	if o.DryRun {
+		return ps.DryRun()
+	}
+	return ps
+}
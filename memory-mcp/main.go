@@ -19,6 +19,15 @@ func main() {
 	transport := flag.String("transport", "stdio", "Transport mode: stdio or http")
 	port := flag.String("port", "8081", "HTTP port (only used with --transport http)")
 	dataDir := flag.String("data-dir", "./data", "Directory for SQLite databases")
+	embedder := flag.String("embedder", "builtin", "Embedder for semantic search: builtin or http")
+	embedderURL := flag.String("embedder-url", "http://localhost:11434", "Base URL of the Ollama-compatible embedding endpoint (only used with --embedder http)")
+	embedderModel := flag.String("embedder-model", "nomic-embed-text", "Embedding model name (only used with --embedder http)")
+	idempotencyWindow := flag.Duration("idempotency-window", storage.DefaultIdempotencyWindow, "How long write results are replayed for a repeated idempotency_key (0 disables)")
+	var limits storage.Limits
+	flag.IntVar(&limits.MaxNameLength, "max-name-length", storage.DefaultLimits.MaxNameLength, "Maximum entity name length in characters (0 disables)")
+	flag.IntVar(&limits.MaxTypeLength, "max-type-length", storage.DefaultLimits.MaxTypeLength, "Maximum entity and relation type length in characters (0 disables)")
+	flag.IntVar(&limits.MaxObservationLength, "max-observation-length", storage.DefaultLimits.MaxObservationLength, "Maximum observation length in characters (0 disables)")
+	flag.IntVar(&limits.MaxBatchItems, "max-batch-items", storage.DefaultLimits.MaxBatchItems, "Maximum items per write call or batch (0 disables)")
 	flag.Parse()
 
 	// Open the meta store
@@ -28,6 +37,18 @@ func main() {
 	}
 	defer meta.Close()
 
+	// Configure the embedder used for semantic search
+	switch *embedder {
+	case "builtin":
+		meta.SetEmbedder(storage.NewHashEmbedder())
+	case "http":
+		meta.SetEmbedder(storage.NewHTTPEmbedder(*embedderURL, *embedderModel))
+	default:
+		log.Fatalf("Unknown embedder: %s (use builtin or http)", *embedder)
+	}
+	meta.SetIdempotencyWindow(*idempotencyWindow)
+	meta.SetLimits(limits)
+
 	// Build the MCP server with all tools registered
 	srv := server.New(meta)
 